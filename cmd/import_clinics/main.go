@@ -1,79 +1,89 @@
+// Command import_clinics maintains the clinics table of the scenario DB.
+//
+//	go run ./cmd/import_clinics                                # add vets found on Google Places
+//	go run ./cmd/import_clinics -from-csv assets/clinics.csv   # one-time import of the CSV
+//	go run ./cmd/import_clinics -export assets/clinics.csv     # write the table out as CSV
 package main
 
 import (
 	"context"
-	"encoding/csv"
+	"flag"
 	"fmt"
 	"log"
-	"os"
 	"strconv"
 	"strings"
 	"time"
 
-	"github.com/joho/godotenv"
+	"github.com/vf0429/Petwell_Backend/internal/config"
+	"github.com/vf0429/Petwell_Backend/internal/models"
+	"github.com/vf0429/Petwell_Backend/internal/services/seed"
 	"googlemaps.github.io/maps"
 )
 
-// Clinic matches the CSV structure
-type Clinic struct {
-	ClinicID       string
-	Name           string
-	Address        string
-	PhoneRegular   string
-	PhoneEmergency string
-	Whatsapp       string
-	OpeningHours   string
-	Emergency24h   string
-	WebsiteURL     string
-	ApplemapURL    string
-	Latitude       string
-	Longitude      string
-	Rating         string
-	GooglePlaceID  string
-	PhotoReference string
-}
-
 func main() {
-	// 1. Load Environment Variables
-	if err := godotenv.Load(); err != nil {
-		// If .env is in root, try loading from there
-		if err := godotenv.Load("../../.env"); err != nil {
-			log.Println("Warning: .env file not found")
+	cfg := config.LoadConfig()
+
+	fromCSV := flag.String("from-csv", "", "import the clinics of this CSV instead of searching Google Places")
+	export := flag.String("export", "", "write every clinic to this CSV instead of searching Google Places")
+	flag.Parse()
+
+	db, err := models.InitDB(cfg)
+	if err != nil {
+		log.Fatalf("Opening %s failed: %v", cfg.ScenarioDBPath, err)
+	}
+
+	switch {
+	case *fromCSV != "":
+		fmt.Printf("Importing clinics from %s...\n", *fromCSV)
+		summary, err := seed.ImportClinics(db, *fromCSV)
+		if err != nil {
+			log.Fatalf("Import failed, nothing was written: %v", err)
 		}
+		fmt.Println(summary)
+		return
+	case *export != "":
+		n, err := seed.ExportClinics(db, *export)
+		if err != nil {
+			log.Fatalf("Export failed: %v", err)
+		}
+		fmt.Printf("Exported %d clinics to %s\n", n, *export)
+		return
 	}
 
-	apiKey := os.Getenv("MAPS_API_KEY")
-	if apiKey == "" {
+	if cfg.MapsAPIKey == "" {
 		log.Fatal("MAPS_API_KEY is not set")
 	}
 
-	// 2. Initialize Maps Client
-	c, err := maps.NewClient(maps.WithAPIKey(apiKey))
+	// Initialize Maps Client
+	c, err := maps.NewClient(maps.WithAPIKey(cfg.MapsAPIKey))
 	if err != nil {
 		log.Fatalf("fatal error: %s", err)
 	}
 
-	// 3. Load Existing CSV to avoid duplicates
-	csvPath := "assets/clinics.csv"
-	// Adjust path if running from root vs cmd folder
-	if _, err := os.Stat(csvPath); os.IsNotExist(err) {
-		csvPath = "../../assets/clinics.csv"
+	// Load existing clinics to avoid duplicates
+	var existingClinics []models.Clinic
+	if err := db.Find(&existingClinics).Error; err != nil {
+		log.Fatalf("Reading clinics failed: %v", err)
+	}
+	maxID := 0
+	for _, cl := range existingClinics {
+		if id, err := strconv.Atoi(cl.ClinicID); err == nil && id > maxID {
+			maxID = id
+		}
 	}
-
-	existingClinics, maxID := loadExistingClinics(csvPath)
 	fmt.Printf("Loaded %d existing clinics. Max ID: %d\n", len(existingClinics), maxID)
 
-	// 4. Search for Veterinary Clinics in Hong Kong
+	// Search for Veterinary Clinics in Hong Kong
 	// We will try a few keywords to get good coverage
 	keywords := []string{"Veterinary Clinic Hong Kong", "Animal Hospital Hong Kong", "Vet Hong Kong"}
 
-	var newClinics []Clinic
+	added := 0
 	uniquePlaceIDs := make(map[string]bool)
 
 	// Mark existing Place IDs as visited
-	for _, row := range existingClinics {
-		if len(row) > 13 && row[13] != "" {
-			uniquePlaceIDs[row[13]] = true
+	for _, cl := range existingClinics {
+		if cl.GooglePlaceID != "" {
+			uniquePlaceIDs[cl.GooglePlaceID] = true
 		}
 	}
 
@@ -132,16 +142,10 @@ func main() {
 				lat := details.Geometry.Location.Lat
 				lng := details.Geometry.Location.Lng
 
+				// Emergency24h is derived from the hours when the clinic is saved
 				openingHoursStr := ""
-				is24h := "FALSE"
 				if details.OpeningHours != nil && len(details.OpeningHours.WeekdayText) > 0 {
 					openingHoursStr = strings.Join(details.OpeningHours.WeekdayText, "; ")
-					for _, t := range details.OpeningHours.WeekdayText {
-						if strings.Contains(strings.ToLower(t), "open 24 hours") || strings.Contains(strings.ToLower(t), "24-hour") {
-							is24h = "TRUE"
-							break
-						}
-					}
 				}
 
 				photoRef := ""
@@ -149,14 +153,14 @@ func main() {
 					photoRef = details.Photos[0].PhotoReference
 				}
 
-				newClinic := Clinic{
+				newClinic := models.Clinic{
 					ClinicID:       strconv.Itoa(maxID),
 					Name:           details.Name,
 					Address:        details.FormattedAddress,
 					PhoneRegular:   details.InternationalPhoneNumber,
 					WebsiteURL:     details.Website,
 					OpeningHours:   openingHoursStr,
-					Emergency24h:   is24h,
+					Emergency24h:   "FALSE",
 					Latitude:       fmt.Sprintf("%f", lat),
 					Longitude:      fmt.Sprintf("%f", lng),
 					Rating:         fmt.Sprintf("%.1f", details.Rating),
@@ -165,7 +169,13 @@ func main() {
 					ApplemapURL:    "https://maps.apple.com/?q=" + strings.ReplaceAll(details.Name, " ", "+"),
 				}
 
-				newClinics = append(newClinics, newClinic)
+				// Each clinic is its own insert, so an interrupted run
+				// keeps what it found so far.
+				if err := db.Create(&newClinic).Error; err != nil {
+					log.Printf("Error saving %s: %v", newClinic.Name, err)
+					continue
+				}
+				added++
 				fmt.Printf("Found: %s\n", newClinic.Name)
 			}
 
@@ -176,70 +186,9 @@ func main() {
 		}
 	}
 
-	// 5. Append to CSV
-	if len(newClinics) > 0 {
-		appendToCSV(csvPath, newClinics)
-		fmt.Printf("Successfully added %d new clinics to %s\n", len(newClinics), csvPath)
+	if added > 0 {
+		fmt.Printf("Successfully added %d new clinics to %s\n", added, cfg.ScenarioDBPath)
 	} else {
 		fmt.Println("No new clinics found.")
 	}
 }
-
-func loadExistingClinics(path string) ([][]string, int) {
-	file, err := os.Open(path)
-	if err != nil {
-		return [][]string{}, 0
-	}
-	defer file.Close()
-
-	reader := csv.NewReader(file)
-	records, err := reader.ReadAll()
-	if err != nil {
-		return [][]string{}, 0
-	}
-
-	maxID := 0
-	for i, row := range records {
-		if i == 0 || len(row) == 0 {
-			continue
-		}
-		if id, err := strconv.Atoi(row[0]); err == nil && id > maxID {
-			maxID = id
-		}
-	}
-	return records, maxID
-}
-
-func appendToCSV(path string, newClinics []Clinic) {
-	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
-	if err != nil {
-		log.Fatalf("failed to open file: %s", err)
-	}
-	defer file.Close()
-
-	writer := csv.NewWriter(file)
-	defer writer.Flush()
-
-	for _, c := range newClinics {
-		record := []string{
-			c.ClinicID,
-			c.Name,
-			c.Address,
-			c.PhoneRegular,
-			c.PhoneEmergency,
-			c.Whatsapp,
-			c.OpeningHours,
-			c.Emergency24h,
-			c.WebsiteURL,
-			c.ApplemapURL,
-			c.Latitude,
-			c.Longitude,
-			c.Rating,
-			c.GooglePlaceID,
-			c.PhotoReference,
-		}
-		if err := writer.Write(record); err != nil {
-			log.Printf("error writing record: %s", err)
-		}
-	}
-}
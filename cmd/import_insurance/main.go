@@ -0,0 +1,61 @@
+// Command import_insurance builds the insurance product DB from the
+// versioned sources in assets/insurance.
+//
+//	go run ./cmd/import_insurance                 # import and print the diff
+//	go run ./cmd/import_insurance -dry-run        # only print the diff
+//	go run ./cmd/import_insurance -export -version 2026-02-03
+//	                                              # write the DB back to the sources
+package main
+
+import (
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/vf0429/Petwell_Backend/internal/config"
+	"github.com/vf0429/Petwell_Backend/internal/migrations"
+	"github.com/vf0429/Petwell_Backend/internal/services/seed"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func main() {
+	cfg := config.LoadConfig()
+
+	dbPath := flag.String("db", cfg.InsuranceDBPath, "insurance SQLite database to build")
+	srcDir := flag.String("src", seed.DefaultInsuranceSourceDir, "directory with manifest.json and the CSV/JSON sources")
+	dryRun := flag.Bool("dry-run", false, "print the diff without writing anything")
+	export := flag.Bool("export", false, "write the database contents to -src instead of importing")
+	version := flag.String("version", time.Now().Format("2006-01-02"), "manifest version written by -export")
+	flag.Parse()
+
+	db, err := sql.Open("sqlite3", "file:"+*dbPath+"?_foreign_keys=on")
+	if err != nil {
+		log.Fatalf("Opening %s failed: %v", *dbPath, err)
+	}
+	defer db.Close()
+
+	if *export {
+		if err := seed.ExportInsurance(db, *srcDir, *version); err != nil {
+			log.Fatalf("Export failed: %v", err)
+		}
+		fmt.Printf("Exported %s to %s (version %s)\n", *dbPath, *srcDir, *version)
+		return
+	}
+
+	// A fresh file gets its tables here; -dry-run leaves the schema alone.
+	if !*dryRun {
+		if _, err := migrations.Insurance.Up(db); err != nil {
+			log.Fatalf("Migrating %s failed: %v", *dbPath, err)
+		}
+	}
+
+	fmt.Printf("Importing insurance data from %s into %s...\n", *srcDir, *dbPath)
+	summary, err := seed.Insurance(db, *srcDir, *dryRun)
+	if err != nil {
+		log.Fatalf("Import failed, nothing was written: %v", err)
+	}
+	fmt.Print(summary)
+}
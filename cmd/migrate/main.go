@@ -0,0 +1,104 @@
+// Command migrate applies, reverts and reports the schema migrations of the
+// scenario and insurance databases.
+//
+//	go run ./cmd/migrate up                         # both databases
+//	go run ./cmd/migrate status
+//	go run ./cmd/migrate -db insurance -steps 1 down
+package main
+
+import (
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/vf0429/Petwell_Backend/internal/config"
+	"github.com/vf0429/Petwell_Backend/internal/migrations"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func main() {
+	cfg := config.LoadConfig()
+
+	which := flag.String("db", "all", "database to migrate: scenarios, insurance or all")
+	steps := flag.Int("steps", 1, "number of migrations to revert with down")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: migrate [-db scenarios|insurance|all] [-steps n] up|down|status")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	command := flag.Arg(0)
+
+	paths := map[*migrations.Set]string{
+		migrations.Scenarios: cfg.ScenarioDBPath,
+		migrations.Insurance: cfg.InsuranceDBPath,
+	}
+	var sets []*migrations.Set
+	for _, set := range migrations.Sets {
+		if *which == "all" || *which == set.Name {
+			sets = append(sets, set)
+		}
+	}
+	if len(sets) == 0 {
+		log.Fatalf("Unknown database %q", *which)
+	}
+	if command == "down" && len(sets) > 1 {
+		log.Fatal("down needs -db scenarios or -db insurance")
+	}
+
+	for _, set := range sets {
+		path := paths[set]
+		db, err := sql.Open("sqlite3", "file:"+path+"?_foreign_keys=on")
+		if err != nil {
+			log.Fatalf("Opening %s failed: %v", path, err)
+		}
+
+		switch command {
+		case "up":
+			ran, err := set.Up(db)
+			report(set, path, "applied", ran)
+			if err != nil {
+				log.Fatalf("Migration failed: %v", err)
+			}
+		case "down":
+			ran, err := set.Down(db, *steps)
+			report(set, path, "reverted", ran)
+			if err != nil {
+				log.Fatalf("Migration failed: %v", err)
+			}
+		case "status":
+			status, err := set.Status(db)
+			if err != nil {
+				log.Fatalf("Reading %s failed: %v", path, err)
+			}
+			fmt.Printf("%s (%s):\n", set.Name, path)
+			for _, s := range status {
+				applied := "pending"
+				if s.AppliedAt != nil {
+					applied = "applied " + s.AppliedAt.Local().Format("2006-01-02 15:04:05")
+				}
+				fmt.Printf("  %04d_%-32s %s\n", s.Version, s.Name, applied)
+			}
+		default:
+			flag.Usage()
+			os.Exit(2)
+		}
+		db.Close()
+	}
+}
+
+func report(set *migrations.Set, path, verb string, ran []migrations.Migration) {
+	if len(ran) == 0 {
+		fmt.Printf("%s (%s): nothing to do\n", set.Name, path)
+		return
+	}
+	for _, m := range ran {
+		fmt.Printf("%s (%s): %s %04d_%s\n", set.Name, path, verb, m.Version, m.Name)
+	}
+}
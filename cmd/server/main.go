@@ -11,6 +11,7 @@ import (
 	"github.com/vf0429/Petwell_Backend/internal/handlers"
 	"github.com/vf0429/Petwell_Backend/internal/models"
 	"github.com/vf0429/Petwell_Backend/internal/services/chat"
+	"github.com/vf0429/Petwell_Backend/internal/services/holidays"
 	"github.com/vf0429/Petwell_Backend/internal/services/places"
 	"github.com/vf0429/Petwell_Backend/internal/services/rag"
 )
@@ -36,13 +37,32 @@ func main() {
 	}
 
 	if *seedDB {
-		SeedDatabase(db)
+		SeedDatabase(cfg, db)
 		fmt.Println("Seeding complete. Exiting...")
 		return
 	}
 
+	// Open the insurance product DB once; handlers share its pool.
+	insuranceRepo, err := models.OpenInsuranceRepository(cfg)
+	if err != nil {
+		log.Fatalf("Fatal error opening insurance db: %v", err)
+	}
+	defer insuranceRepo.Close()
+
+	// Public holidays decide which hours clinics keep on a given day.
+	holidayCalendar, err := holidays.Load(cfg.HolidaysPath)
+	if err != nil {
+		log.Fatalf("Fatal error loading holiday calendar: %v", err)
+	}
+	if year := time.Now().In(models.HongKong).Year(); !holidayCalendar.Covers(year) {
+		log.Printf("Holiday calendar %s has no holidays for %d; update %s", holidayCalendar.Version, year, cfg.HolidaysPath)
+	}
+
 	// Initialize new Gin router for scenarios API
-	insuranceV1Router := handlers.NewInsuranceV1Handler(db)
+	insuranceV1Router := handlers.NewInsuranceV1Handler(cfg, db, insuranceRepo)
+	if len(cfg.AdminTokens) == 0 {
+		log.Println("ADMIN_TOKENS is not set; /api/v1/admin endpoints will reject every request")
+	}
 
 	// Create a new mux
 	mux := http.NewServeMux()
@@ -51,19 +71,31 @@ func main() {
 	mux.HandleFunc("/vaccines", handlers.VaccinesHandler)
 	mux.HandleFunc("/register", handlers.RegisterHandler)
 	mux.HandleFunc("/posts", handlers.PostsHandler)
-	mux.HandleFunc("/clinics", handlers.NewClinicsHandler(cfg))
-	mux.HandleFunc("/emergency-clinics", handlers.NewEmergencyClinicsHandler(cfg))
-
-	// Insurance handlers
-	mux.HandleFunc("/insurance-companies", handlers.InsuranceCompaniesHandler)
-	mux.HandleFunc("/insurance-products", handlers.InsuranceProductsHandler)
-	mux.HandleFunc("/coverage-list", handlers.CoverageListHandler)
-	mux.HandleFunc("/coverage-limits", handlers.CoverageLimitsHandler)
-	mux.HandleFunc("/sub-coverage-limits", handlers.SubCoverageLimitsHandler)
-
-	// Legacy handlers
-	mux.HandleFunc("/insurance-providers", handlers.InsuranceProvidersHandler)
-	mux.HandleFunc("/service-subcategories", handlers.ServiceSubcategoriesHandler)
+	mux.HandleFunc("/clinics", handlers.NewClinicsHandler(cfg, db, holidayCalendar))
+	mux.HandleFunc("/clinics/holiday-hours", handlers.NewClinicHolidayHoursHandler(cfg, db, holidayCalendar))
+	mux.HandleFunc("/emergency-clinics", handlers.NewEmergencyClinicsHandler(cfg, db, holidayCalendar))
+	mux.HandleFunc("/public-holidays", handlers.NewPublicHolidaysHandler(holidayCalendar))
+
+	// Insurance handlers; ?lang= picks one language
+	localized := func(pattern string, h http.HandlerFunc) {
+		mux.Handle(pattern, handlers.Localize(h))
+	}
+	localized("/insurance-companies", handlers.NewInsuranceCompaniesHandler(insuranceRepo))
+	localized("/insurance-products", handlers.NewInsuranceProductsHandler(insuranceRepo))
+	localized("/insurance-products/compare", handlers.NewInsuranceProductsCompareHandler(insuranceRepo))
+	localized("/insurance-products/{id}", handlers.NewInsuranceProductHandler(insuranceRepo))
+	localized("/insurance-products/{id}/history", handlers.NewInsuranceProductHistoryHandler(insuranceRepo))
+	localized("/insurance-quotes", handlers.NewInsuranceQuotesHandler(insuranceRepo, db))
+	localized("/insurance-tags", handlers.NewInsuranceTagsHandler(insuranceRepo))
+	localized("/insurance-rules", handlers.NewInsuranceRulesHandler(insuranceRepo))
+	localized("/coverage-list", handlers.NewCoverageListHandler(insuranceRepo))
+	localized("/coverage-limits", handlers.NewCoverageLimitsHandler(insuranceRepo))
+	localized("/sub-coverage-limits", handlers.NewSubCoverageLimitsHandler(insuranceRepo))
+	mux.HandleFunc("/insurance/search", handlers.NewInsuranceSearchHandler(insuranceRepo))
+
+	// Legacy handlers, deprecated; old app builds still call them
+	mux.HandleFunc("/insurance-providers", handlers.NewInsuranceProvidersHandler(insuranceRepo))
+	mux.HandleFunc("/service-subcategories", handlers.NewServiceSubcategoriesHandler(insuranceRepo))
 
 	// Legacy RAG chat handler (now with session support)
 	mux.HandleFunc("/api/chat", handlers.NewRAGHandler(ragClient, sessionStore))
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/vf0429/Petwell_Backend/internal/config"
+	"github.com/vf0429/Petwell_Backend/internal/models"
+	"github.com/vf0429/Petwell_Backend/internal/services/seed"
+	"gorm.io/gorm"
+)
+
+// SeedDatabase upserts the scenario fixtures from assets/pet_insurance_scenarios.json,
+// replaces the cost benchmarks with assets/cost_benchmarks.csv and warns
+// about insurers that don't match an insurance product.
+func SeedDatabase(cfg *config.Config, db *gorm.DB) {
+	path := seed.ResolvePath(seed.DefaultScenariosPath)
+	fmt.Printf("Seeding scenarios from %s...\n", path)
+
+	summary, err := seed.Scenarios(db, path)
+	if err != nil {
+		log.Fatalf("Seeding failed: %v", err)
+	}
+	fmt.Println(summary)
+
+	benchmarks := seed.ResolvePath(seed.DefaultBenchmarksPath)
+	n, err := seed.Benchmarks(db, benchmarks)
+	if err != nil {
+		log.Fatalf("Seeding cost benchmarks failed: %v", err)
+	}
+	fmt.Printf("Cost benchmarks: %d loaded from %s\n", n, benchmarks)
+
+	repo, err := models.OpenInsuranceRepository(cfg)
+	if err != nil {
+		fmt.Printf("  warning: insurer products not checked: %v\n", err)
+		return
+	}
+	defer repo.Close()
+	issues, err := models.CheckInsurerProducts(db, repo)
+	if err != nil {
+		fmt.Printf("  warning: insurer products not checked: %v\n", err)
+		return
+	}
+	for _, issue := range issues {
+		fmt.Printf("  warning: insurer %s: %s\n", issue.InsurerID, issue.Message)
+	}
+}
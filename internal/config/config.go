@@ -2,6 +2,7 @@ package config
 
 import (
 	"os"
+	"strings"
 
 	"github.com/joho/godotenv"
 )
@@ -14,6 +15,21 @@ type Config struct {
 	DBUser        string
 	DBPassword    string
 	DBName        string
+
+	// ScenarioDBPath is the SQLite file behind the /api/v1 scenario API.
+	ScenarioDBPath string
+	// InsuranceDBPath is the SQLite file with the insurance product tables.
+	// Relative paths are looked up in the working directory first, then
+	// next to the executable.
+	InsuranceDBPath string
+	// HolidaysPath is the Hong Kong public holiday calendar clinic hours
+	// are read against.
+	HolidaysPath string
+
+	// AdminTokens maps each bearer token accepted by the /api/v1/admin
+	// endpoints to the name of the admin holding it. Read from
+	// ADMIN_TOKENS as "name:token,name:token"; empty disables the admin API.
+	AdminTokens map[string]string
 }
 
 func LoadConfig() *Config {
@@ -34,7 +50,27 @@ func LoadConfig() *Config {
 		DBUser:        getEnvOrDefault("DB_USER", "postgres"),
 		DBPassword:    getEnvOrDefault("DB_PASSWORD", "postgres"),
 		DBName:        getEnvOrDefault("DB_NAME", "petwell"),
+
+		ScenarioDBPath:  getEnvOrDefault("SCENARIO_DB_PATH", "pet_insurance.db"),
+		InsuranceDBPath: getEnvOrDefault("INSURANCE_DB_PATH", "assets/pet_insurance.db"),
+		HolidaysPath:    getEnvOrDefault("HOLIDAYS_PATH", "assets/hk_public_holidays.json"),
+
+		AdminTokens: parseAdminTokens(os.Getenv("ADMIN_TOKENS")),
+	}
+}
+
+// parseAdminTokens reads "name:token" pairs separated by commas. Entries
+// without a name or token are ignored.
+func parseAdminTokens(s string) map[string]string {
+	tokens := make(map[string]string)
+	for _, entry := range strings.Split(s, ",") {
+		name, token, ok := strings.Cut(strings.TrimSpace(entry), ":")
+		if !ok || name == "" || token == "" {
+			continue
+		}
+		tokens[token] = name
 	}
+	return tokens
 }
 
 func getEnvOrDefault(key, defaultValue string) string {
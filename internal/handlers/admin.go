@@ -0,0 +1,277 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vf0429/Petwell_Backend/internal/models"
+)
+
+// adminKey is the gin context key holding the name of the authenticated admin.
+const adminKey = "admin"
+
+// requireAdmin accepts requests carrying "Authorization: Bearer <token>"
+// for one of tokens and records who the token belongs to under adminKey.
+func requireAdmin(tokens map[string]string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if ok {
+			for known, name := range tokens {
+				if subtle.ConstantTimeCompare([]byte(token), []byte(known)) == 1 {
+					c.Set(adminKey, name)
+					c.Next()
+					return
+				}
+			}
+		}
+		c.Header("WWW-Authenticate", `Bearer realm="admin"`)
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Admin token required"})
+	}
+}
+
+// adminError maps repository write errors to a status code.
+func adminError(c *gin.Context, err error) {
+	var invalid *models.ValidationError
+	switch {
+	case errors.As(err, &invalid):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": invalid.Error(), "field": invalid.Field})
+	case errors.Is(err, models.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Record not found"})
+	case errors.Is(err, models.ErrConflict):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		log.Printf("insurance admin: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to write insurance data"})
+	}
+}
+
+// idParam parses the named path parameter as a positive id, answering 400
+// when it isn't one.
+func idParam(c *gin.Context, name string) (int, bool) {
+	id, err := strconv.Atoi(c.Param(name))
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + name})
+		return 0, false
+	}
+	return id, true
+}
+
+// bindBody decodes the JSON body into v, answering 400 when it can't.
+func bindBody(c *gin.Context, v interface{}) bool {
+	if err := c.ShouldBindJSON(v); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return false
+	}
+	return true
+}
+
+// registerAdminRoutes mounts the authenticated insurance editing endpoints
+// under /admin. Each PUT replaces the whole record; ids in the path win
+// over ids in the body. Product and limit changes are recorded in the
+// change history under the admin's name.
+func registerAdminRoutes(r *gin.Engine, tokens map[string]string, repo *models.InsuranceRepository) {
+	admin := r.Group("/admin", requireAdmin(tokens))
+
+	// Calls to the deprecated endpoints since the server started
+	admin.GET("/legacy-usage", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"sunset": legacySunset, "endpoints": legacyUsage.snapshot()})
+	})
+
+	// Providers
+	admin.POST("/providers", func(c *gin.Context) {
+		var company models.InsuranceCompany
+		if !bindBody(c, &company) {
+			return
+		}
+		if err := repo.CreateCompany(&company); err != nil {
+			adminError(c, err)
+			return
+		}
+		c.JSON(http.StatusCreated, company)
+	})
+	admin.PUT("/providers/:id", func(c *gin.Context) {
+		id, ok := idParam(c, "id")
+		var company models.InsuranceCompany
+		if !ok || !bindBody(c, &company) {
+			return
+		}
+		company.CompanyId = id
+		if err := repo.UpdateCompany(&company); err != nil {
+			adminError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, company)
+	})
+	admin.DELETE("/providers/:id", func(c *gin.Context) {
+		id, ok := idParam(c, "id")
+		if !ok {
+			return
+		}
+		if err := repo.DeleteCompany(id); err != nil {
+			adminError(c, err)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+
+	// Products
+	admin.POST("/products", func(c *gin.Context) {
+		var product models.InsuranceProduct
+		if !bindBody(c, &product) {
+			return
+		}
+		if err := repo.CreateProduct(c.GetString(adminKey), &product); err != nil {
+			adminError(c, err)
+			return
+		}
+		c.JSON(http.StatusCreated, product)
+	})
+	admin.PUT("/products/:id", func(c *gin.Context) {
+		id, ok := idParam(c, "id")
+		var product models.InsuranceProduct
+		if !ok || !bindBody(c, &product) {
+			return
+		}
+		product.InsuranceId = id
+		if err := repo.UpdateProduct(c.GetString(adminKey), &product); err != nil {
+			adminError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, product)
+	})
+	admin.DELETE("/products/:id", func(c *gin.Context) {
+		id, ok := idParam(c, "id")
+		if !ok {
+			return
+		}
+		if err := repo.DeleteProduct(c.GetString(adminKey), id); err != nil {
+			adminError(c, err)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+
+	// Tags
+	admin.POST("/tags", func(c *gin.Context) {
+		var tag models.Tag
+		if !bindBody(c, &tag) {
+			return
+		}
+		if err := repo.CreateTag(&tag); err != nil {
+			adminError(c, err)
+			return
+		}
+		c.JSON(http.StatusCreated, tag)
+	})
+	admin.PUT("/tags/:id", func(c *gin.Context) {
+		id, ok := idParam(c, "id")
+		var tag models.Tag
+		if !ok || !bindBody(c, &tag) {
+			return
+		}
+		tag.TagId = id
+		if err := repo.UpdateTag(&tag); err != nil {
+			adminError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, tag)
+	})
+	admin.DELETE("/tags/:id", func(c *gin.Context) {
+		id, ok := idParam(c, "id")
+		if !ok {
+			return
+		}
+		if err := repo.DeleteTag(id); err != nil {
+			adminError(c, err)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+
+	// Coverage limits, keyed by coverage type and product
+	admin.POST("/coverage-limits", func(c *gin.Context) {
+		var limit models.CoverageLimit
+		if !bindBody(c, &limit) {
+			return
+		}
+		if err := repo.CreateCoverageLimit(c.GetString(adminKey), &limit); err != nil {
+			adminError(c, err)
+			return
+		}
+		c.JSON(http.StatusCreated, limit)
+	})
+	admin.PUT("/coverage-limits/:coverage_id/:product_id", func(c *gin.Context) {
+		coverageID, ok := idParam(c, "coverage_id")
+		if !ok {
+			return
+		}
+		productID, ok := idParam(c, "product_id")
+		var limit models.CoverageLimit
+		if !ok || !bindBody(c, &limit) {
+			return
+		}
+		limit.CoverageId, limit.ProductId = coverageID, productID
+		if err := repo.UpdateCoverageLimit(c.GetString(adminKey), &limit); err != nil {
+			adminError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, limit)
+	})
+	admin.DELETE("/coverage-limits/:coverage_id/:product_id", func(c *gin.Context) {
+		coverageID, ok := idParam(c, "coverage_id")
+		if !ok {
+			return
+		}
+		productID, ok := idParam(c, "product_id")
+		if !ok {
+			return
+		}
+		if err := repo.DeleteCoverageLimit(c.GetString(adminKey), coverageID, productID); err != nil {
+			adminError(c, err)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+
+	// Sub-coverage limits
+	admin.POST("/sub-coverage-limits", func(c *gin.Context) {
+		var limit models.SubCoverageLimit
+		if !bindBody(c, &limit) {
+			return
+		}
+		if err := repo.CreateSubCoverageLimit(c.GetString(adminKey), &limit); err != nil {
+			adminError(c, err)
+			return
+		}
+		c.JSON(http.StatusCreated, limit)
+	})
+	admin.PUT("/sub-coverage-limits/:id", func(c *gin.Context) {
+		id, ok := idParam(c, "id")
+		var limit models.SubCoverageLimit
+		if !ok || !bindBody(c, &limit) {
+			return
+		}
+		limit.SubCoverageId = id
+		if err := repo.UpdateSubCoverageLimit(c.GetString(adminKey), &limit); err != nil {
+			adminError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, limit)
+	})
+	admin.DELETE("/sub-coverage-limits/:id", func(c *gin.Context) {
+		id, ok := idParam(c, "id")
+		if !ok {
+			return
+		}
+		if err := repo.DeleteSubCoverageLimit(c.GetString(adminKey), id); err != nil {
+			adminError(c, err)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+}
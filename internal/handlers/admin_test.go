@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vf0429/Petwell_Backend/internal/config"
+	"github.com/vf0429/Petwell_Backend/internal/migrations"
+	"github.com/vf0429/Petwell_Backend/internal/models"
+)
+
+// openInsuranceTestRepo builds an insurance DB in a temp dir, runs stmts
+// on it and opens a repository on it.
+func openInsuranceTestRepo(t *testing.T, stmts ...string) *models.InsuranceRepository {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "insurance.db")
+	db, err := sql.Open("sqlite3", "file:"+path+"?_foreign_keys=on")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := migrations.Insurance.Up(db); err != nil {
+		t.Fatal(err)
+	}
+	for _, stmt := range stmts {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+	repo, err := models.OpenInsuranceRepository(&config.Config{InsuranceDBPath: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { repo.Close() })
+	return repo
+}
+
+func TestAdminRoutes(t *testing.T) {
+	repo := openInsuranceTestRepo(t,
+		"INSERT INTO insurance_provider (company_id, company_name) VALUES (1, 'OneDegree')",
+		"INSERT INTO product (insurance_id, provider_id, insurance_name) VALUES (1, 1, 'Essential Plan')",
+		"INSERT INTO coverage_list (coverage_id, coverage_type) VALUES (1, 'Medical Expenses')",
+		"INSERT INTO coverage_limit (coverage_id, product_id, coverage_limit) VALUES (1, 1, '30000')",
+	)
+	r := gin.New()
+	registerAdminRoutes(r, map[string]string{"secret": "alice"}, repo)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		body   string
+		status int
+		want   string // substring of the response body
+	}{
+		{"no token", http.MethodDelete, "/admin/products/1", "", "", http.StatusUnauthorized, "Admin token required"},
+		{"wrong token", http.MethodDelete, "/admin/products/1", "guess", "", http.StatusUnauthorized, "Admin token required"},
+		{"bad id", http.MethodPut, "/admin/providers/abc", "secret", `{"company_name": "x"}`, http.StatusBadRequest, "Invalid id"},
+		{"bad body", http.MethodPost, "/admin/providers", "secret", `{"company_name": 1}`, http.StatusBadRequest, "Invalid request body"},
+		{"missing name", http.MethodPost, "/admin/providers", "secret", `{"company_name": " "}`, http.StatusUnprocessableEntity, `"field":"company_name"`},
+		{"unknown provider", http.MethodPost, "/admin/products", "secret", `{"provider_id": 9, "insurance_name": "Plus Plan"}`, http.StatusUnprocessableEntity, `"field":"provider_id"`},
+		{"unknown product", http.MethodDelete, "/admin/products/9", "secret", "", http.StatusNotFound, "Record not found"},
+		{"provider with products", http.MethodDelete, "/admin/providers/1", "secret", "", http.StatusConflict, "still has 1 products"},
+		{"duplicate limit", http.MethodPost, "/admin/coverage-limits", "secret", `{"coverage_id": 1, "product_id": 1, "coverage_limit": "5000"}`, http.StatusConflict, "already exists"},
+		{"update provider", http.MethodPut, "/admin/providers/1", "secret", `{"company_name": "OneDegree HK"}`, http.StatusOK, `"company_name":"OneDegree HK"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+			if !strings.Contains(w.Body.String(), tt.want) {
+				t.Errorf("body = %s, want %q", w.Body, tt.want)
+			}
+		})
+	}
+}
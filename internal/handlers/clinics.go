@@ -2,25 +2,26 @@ package handlers
 
 import (
 	"context"
-	"encoding/csv"
 	"encoding/json"
 	"fmt"
+	"math"
 	"net/http"
-	"os"
-	"path/filepath"
+	"net/url"
+	"strconv"
 	"strings"
 	"sync"
-
-	// "time" // Removed unused
+	"time"
 
 	"github.com/vf0429/Petwell_Backend/internal/config"
 	"github.com/vf0429/Petwell_Backend/internal/models"
+	"github.com/vf0429/Petwell_Backend/internal/services/holidays"
 	"googlemaps.github.io/maps"
+	"gorm.io/gorm"
 )
 
 type ClinicsService struct {
-	clinics []models.Clinic
-	mu      sync.RWMutex
+	db  *gorm.DB
+	cfg *config.Config
 }
 
 var (
@@ -28,181 +29,139 @@ var (
 	serviceOnce     sync.Once
 )
 
-func getClinicsService(cfg *config.Config) *ClinicsService {
+func getClinicsService(cfg *config.Config, db *gorm.DB) *ClinicsService {
 	serviceOnce.Do(func() {
-		serviceInstance = &ClinicsService{}
-		// Load in background or blocking? Blocking is safer for initial load if data is small.
-		serviceInstance.loadClinics(cfg)
+		serviceInstance = &ClinicsService{db: db, cfg: cfg}
+		serviceInstance.start()
 	})
 	return serviceInstance
 }
 
-func (s *ClinicsService) loadClinics(cfg *config.Config) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+// list reads the clinics matching query, in directory order, with their
+// photo URLs filled in.
+func (s *ClinicsService) list(query ...interface{}) ([]models.Clinic, error) {
+	clinics := []models.Clinic{}
+	tx := s.db.Order(models.ClinicOrder)
+	if len(query) > 0 {
+		tx = tx.Where(query[0], query[1:]...)
+	}
+	if err := tx.Find(&clinics).Error; err != nil {
+		return nil, err
+	}
+	for i := range clinics {
+		clinics[i].PhotoURL = s.photoURL(clinics[i].PhotoReference)
+	}
+	return clinics, nil
+}
 
-	// Adjust path if running from root
-	path := filepath.Join("assets", "clinics.csv")
-	f, err := os.Open(path)
-	if err != nil {
-		// Try looking in ../data if running from cmd/...
-		path = filepath.Join("..", "assets", "clinics.csv")
-		f, err = os.Open(path)
-		if err != nil {
-			fmt.Printf("Error opening clinics.csv: %v\n", err)
-			return
-		}
+func (s *ClinicsService) photoURL(ref string) string {
+	if ref == "" || s.cfg.MapsAPIKey == "" {
+		return ""
 	}
-	defer f.Close()
+	return fmt.Sprintf("https://maps.googleapis.com/maps/api/place/photo?maxwidth=800&photo_reference=%s&key=%s", ref, s.cfg.MapsAPIKey)
+}
 
-	reader := csv.NewReader(f)
-	records, err := reader.ReadAll()
-	if err != nil {
-		fmt.Printf("Error reading CSV: %v\n", err)
+func (s *ClinicsService) start() {
+	var count int64
+	if err := s.db.Model(&models.Clinic{}).Count(&count).Error; err != nil {
+		fmt.Printf("Error counting clinics: %v\n", err)
 		return
 	}
-
-	var mapsClient *maps.Client
-	if cfg.MapsAPIKey != "" {
-		c, err := maps.NewClient(maps.WithAPIKey(cfg.MapsAPIKey))
-		if err == nil {
-			mapsClient = c
-		} else {
-			fmt.Printf("Maps client error: %v\n", err)
-		}
+	fmt.Printf("Serving %d clinics from the database\n", count)
+	if count == 0 {
+		fmt.Println("The clinics table is empty; import the directory with `go run ./cmd/import_clinics -from-csv assets/clinics.csv`")
 	}
 
-	var clinics []models.Clinic
-	// Skip header
-	if len(records) > 0 {
-		records = records[1:]
+	if s.cfg.MapsAPIKey == "" {
+		return
 	}
-
-	for _, record := range records {
-		if len(record) < 13 {
-			continue
-		}
-
-		c := models.Clinic{
-			ClinicID:       record[0],
-			Name:           record[1],
-			Address:        record[2],
-			PhoneRegular:   record[3],
-			PhoneEmergency: record[4],
-			Whatsapp:       record[5],
-			OpeningHours:   record[6],
-			Emergency24h:   record[7],
-			WebsiteURL:     record[8],
-			ApplemapURL:    record[9],
-			Latitude:       record[10],
-			Longitude:      record[11],
-			Rating:         record[12],
-		}
-
-		if len(record) > 13 {
-			c.GooglePlaceID = record[13]
-		}
-		if len(record) > 14 {
-			c.PhotoReference = record[14]
-			if c.PhotoReference != "" && cfg.MapsAPIKey != "" {
-				c.PhotoURL = fmt.Sprintf("https://maps.googleapis.com/maps/api/place/photo?maxwidth=800&photo_reference=%s&key=%s", c.PhotoReference, cfg.MapsAPIKey)
-			}
-		}
-
-		clinics = append(clinics, c)
+	mapsClient, err := maps.NewClient(maps.WithAPIKey(s.cfg.MapsAPIKey))
+	if err != nil {
+		fmt.Printf("Maps client error: %v\n", err)
+		return
 	}
 
 	// Enrich with Google Maps
-	if mapsClient != nil {
-		fmt.Println("Enriching clinics with Google Maps data in background...")
-		// Launch background task to enrich
-		go func() {
-			s.mu.Lock()
-			// Create a copy of pointers or iterate over indices?
-			// Range over slice copies elements, range over index is safer.
-			// But modifying slice elements in place requires locking for race conditions if read concurrently.
-			// The API handler holds RLock. So we need Lock to write.
-			// To avoid blocking reads for too long, we can lock per clinic update.
-			count := len(s.clinics)
-			s.mu.Unlock()
-
-			// Create a rate limiter channel, e.g., 5 concurrent requests
-			sem := make(chan struct{}, 5)
-			var wg sync.WaitGroup
-
-			for i := 0; i < count; i++ {
-				// Acquire semaphore
-				wg.Add(1)
-				sem <- struct{}{}
-
-				go func(idx int) {
-					defer func() { <-sem; wg.Done() }()
-
-					// Read clinic safely (need read lock for name/address, but since these are strings and immutable, maybe OK without lock if no one writes?)
-					// Actually we are the only writer.
-					// Readers might read while we read. RLock needed.
-					s.mu.RLock()
-					cl := s.clinics[idx] // Copy the struct value
-					s.mu.RUnlock()
-
-					// Skip if already enriched
-					if cl.GooglePlaceID != "" && cl.Latitude != "" && cl.Longitude != "" && cl.PhotoReference != "" {
-						return
-					}
-
-					var candidate *maps.PlacesSearchResult
-					var err error
-
-					// If we only have name/address, search
-					if cl.GooglePlaceID == "" {
-						// Search for place
-						r := &maps.FindPlaceFromTextRequest{
-							Input:     fmt.Sprintf("%s %s", cl.Name, cl.Address),
-							InputType: maps.FindPlaceFromTextInputTypeTextQuery,
-							Fields: []maps.PlaceSearchFieldMask{
-								maps.PlaceSearchFieldMaskPlaceID,
-							},
-						}
+	fmt.Println("Enriching clinics with Google Maps data in background...")
+	go func() {
+		var pending []models.Clinic
+		err := s.db.Order(models.ClinicOrder).
+			Where("google_place_id = '' OR google_place_id IS NULL OR latitude = '' OR longitude = '' OR photo_reference = '' OR photo_reference IS NULL").
+			Find(&pending).Error
+		if err != nil {
+			fmt.Printf("Error reading clinics to enrich: %v\n", err)
+			return
+		}
 
-						resp, err := mapsClient.FindPlaceFromText(context.Background(), r)
-						if err == nil && len(resp.Candidates) > 0 {
-							candidate = &resp.Candidates[0]
-						} else {
-							// Try just name if name + address fails?
-							// fmt.Printf("Failed to find: %s (%v)\n", cl.Name, err)
-							return
-						}
-					} else {
-						// If we have ID but missing other info (e.g. photo), we might need to fetch details directly
-						// But for simplicity, let's just assume if ID is missing we start over.
+		// Create a rate limiter channel, e.g., 5 concurrent requests
+		sem := make(chan struct{}, 5)
+		var wg sync.WaitGroup
+
+		for _, cl := range pending {
+			// Acquire semaphore
+			wg.Add(1)
+			sem <- struct{}{}
+
+			go func(cl models.Clinic) {
+				defer func() { <-sem; wg.Done() }()
+
+				var candidate *maps.PlacesSearchResult
+				var err error
+
+				// If we only have name/address, search
+				if cl.GooglePlaceID == "" {
+					// Search for place
+					r := &maps.FindPlaceFromTextRequest{
+						Input:     fmt.Sprintf("%s %s", cl.Name, cl.Address),
+						InputType: maps.FindPlaceFromTextInputTypeTextQuery,
+						Fields: []maps.PlaceSearchFieldMask{
+							maps.PlaceSearchFieldMaskPlaceID,
+						},
 					}
 
-					placeID := ""
-					if candidate != nil {
-						placeID = candidate.PlaceID
+					resp, err := mapsClient.FindPlaceFromText(context.Background(), r)
+					if err == nil && len(resp.Candidates) > 0 {
+						candidate = &resp.Candidates[0]
 					} else {
-						placeID = cl.GooglePlaceID
-					}
-
-					if placeID == "" {
+						// Try just name if name + address fails?
+						// fmt.Printf("Failed to find: %s (%v)\n", cl.Name, err)
 						return
 					}
-
-					// Now get full details
-					details, err := mapsClient.PlaceDetails(context.Background(), &maps.PlaceDetailsRequest{
-						PlaceID: placeID,
-						// Fetch all fields by default to avoid undefined constant errors
-					})
-
-					if err != nil {
-						fmt.Printf("Failed to get details for %s: %v\n", cl.Name, err)
-						return
+				} else {
+					// If we have ID but missing other info (e.g. photo), we might need to fetch details directly
+					// But for simplicity, let's just assume if ID is missing we start over.
+				}
+
+				placeID := ""
+				if candidate != nil {
+					placeID = candidate.PlaceID
+				} else {
+					placeID = cl.GooglePlaceID
+				}
+
+				if placeID == "" {
+					return
+				}
+
+				// Now get full details
+				details, err := mapsClient.PlaceDetails(context.Background(), &maps.PlaceDetailsRequest{
+					PlaceID: placeID,
+					// Fetch all fields by default to avoid undefined constant errors
+				})
+
+				if err != nil {
+					fmt.Printf("Failed to get details for %s: %v\n", cl.Name, err)
+					return
+				}
+
+				// Each clinic is updated in its own transaction against the
+				// current row, so a crash only loses the clinic in flight.
+				err = s.db.Transaction(func(tx *gorm.DB) error {
+					var row models.Clinic
+					if err := tx.First(&row, "clinic_id = ?", cl.ClinicID).Error; err != nil {
+						return err
 					}
-
-					s.mu.Lock()
-					// Re-read or just update directly
-					// We update the struct in the slice
-					target := &s.clinics[idx]
+					target := &row
 
 					target.GooglePlaceID = placeID
 
@@ -223,31 +182,10 @@ func (s *ClinicsService) loadClinics(cfg *config.Config) {
 						target.WebsiteURL = details.Website
 					}
 
-					// Process Opening Hours
-					if details.OpeningHours != nil && len(details.OpeningHours.WeekdayText) > 0 {
-						// Fill opening hours if empty
-						if target.OpeningHours == "" {
-							target.OpeningHours = strings.Join(details.OpeningHours.WeekdayText, "; ")
-						}
-
-						is24h := false
-						for _, dayText := range details.OpeningHours.WeekdayText {
-							lowerText := strings.ToLower(dayText)
-							if strings.Contains(lowerText, "open 24 hours") || strings.Contains(lowerText, "24-hour") {
-								is24h = true
-								break
-							}
-						}
-						// Only update if true, otherwise keep existing value or set to FALSE?
-						// User asked to judge if true. If CSV has FALSE but google says TRUE, we update to TRUE.
-						// If CSV has TRUE but google says FALSE, maybe we should respect Google?
-						// For safety let's just mark TRUE if we find it.
-						if is24h {
-							target.Emergency24h = "TRUE"
-						} else {
-							// Optional: verified not 24h?
-							// target.Emergency24h = "FALSE"
-						}
+					// Fill opening hours if empty; Emergency24h is derived
+					// from them when the clinic is saved.
+					if target.OpeningHours == "" && details.OpeningHours != nil && len(details.OpeningHours.WeekdayText) > 0 {
+						target.OpeningHours = strings.Join(details.OpeningHours.WeekdayText, "; ")
 					}
 
 					// Process Photos
@@ -255,112 +193,182 @@ func (s *ClinicsService) loadClinics(cfg *config.Config) {
 						ref := details.Photos[0].PhotoReference
 						if ref != "" {
 							target.PhotoReference = ref
-							target.PhotoURL = fmt.Sprintf("https://maps.googleapis.com/maps/api/place/photo?maxwidth=800&photo_reference=%s&key=%s", ref, cfg.MapsAPIKey)
 						}
 					}
 
-					s.mu.Unlock()
-					fmt.Printf("Enriched: %s (Rating: %.1f)\n", cl.Name, details.Rating)
-				}(i)
+					return tx.Save(target).Error
+				})
+				if err != nil {
+					fmt.Printf("Failed to save %s: %v\n", cl.Name, err)
+					return
+				}
+				fmt.Printf("Enriched: %s (Rating: %.1f)\n", cl.Name, details.Rating)
+			}(cl)
+		}
+
+		wg.Wait()
+		fmt.Println("Finished enriching clinics")
+	}()
+}
+
+func NewClinicsHandler(cfg *config.Config, db *gorm.DB, cal *holidays.Calendar) http.HandlerFunc {
+	svc := getClinicsService(cfg, db)
+	return func(w http.ResponseWriter, r *http.Request) {
+		EnableCors(&w)
+		w.Header().Set("Content-Type", "application/json")
+
+		q := r.URL.Query()
+		keep, err := parseOpenQuery(q, cal)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if q.Has("lat") || q.Has("lng") {
+			lat, lng, radius, limit, err := parseNearbyQuery(q)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
 			}
+			nearby, err := models.NearbyClinics(svc.db, lat, lng, radius, limit, keep)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			for i := range nearby {
+				nearby[i].PhotoURL = svc.photoURL(nearby[i].PhotoReference)
+			}
+			if err := json.NewEncoder(w).Encode(nearby); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+			}
+			return
+		}
 
-			wg.Wait()
-			s.saveClinics()
-		}()
+		clinics, err := svc.list()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if keep != nil {
+			open := []models.Clinic{}
+			for i := range clinics {
+				if keep(&clinics[i]) {
+					open = append(open, clinics[i])
+				}
+			}
+			clinics = open
+		}
+		if err := json.NewEncoder(w).Encode(clinics); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
 	}
-
-	s.clinics = clinics
-	fmt.Printf("Loaded %d clinics from CSV\n", len(clinics))
 }
 
-func (s *ClinicsService) saveClinics() {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	// Adjust path if running from root
-	path := filepath.Join("assets", "clinics.csv")
-	// If running inside cmd/server, adjust
-	if _, err := os.Stat(path); os.IsNotExist(err) {
-		path = filepath.Join("..", "assets", "clinics.csv")
+// parseOpenQuery reads open_now=true or open_at, a Hong Kong local time
+// (2026-10-16T23:00) or an RFC 3339 one, into a filter for the clinics open
+// then, on their holiday hours when cal has a public holiday that day. It
+// returns nil when neither is given.
+func parseOpenQuery(v url.Values, cal *holidays.Calendar) (func(*models.Clinic) bool, error) {
+	var at time.Time
+	switch {
+	case v.Get("open_now") != "" && v.Get("open_at") != "":
+		return nil, fmt.Errorf("use either open_now or open_at")
+	case v.Get("open_now") != "":
+		now, err := strconv.ParseBool(v.Get("open_now"))
+		if err != nil {
+			return nil, fmt.Errorf("open_now must be true or false")
+		}
+		if !now {
+			return nil, nil
+		}
+		at = time.Now()
+	case v.Get("open_at") != "":
+		var err error
+		if at, err = parseOpenAt(v.Get("open_at")); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, nil
 	}
+	return func(c *models.Clinic) bool { return c.OpenAt(at, cal.IsHoliday) }, nil
+}
 
-	f, err := os.Create(path)
-	if err != nil {
-		fmt.Printf("Error creating CSV for save: %v\n", err)
-		return
+func parseOpenAt(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	for _, layout := range []string{"2006-01-02T15:04", "2006-01-02T15:04:05", "2006-01-02 15:04"} {
+		if t, err := time.ParseInLocation(layout, s, models.HongKong); err == nil {
+			return t, nil
+		}
 	}
-	defer f.Close()
+	return time.Time{}, fmt.Errorf("open_at must be a Hong Kong time (2006-01-02T15:04) or an RFC 3339 time")
+}
 
-	writer := csv.NewWriter(f)
-	defer writer.Flush()
+const (
+	defaultNearbyRadiusM = 5000
+	maxNearbyRadiusM     = 50000
+	defaultNearbyLimit   = 20
+	maxNearbyLimit       = 100
+)
 
-	// Header
-	header := []string{
-		"clinic_id", "name", "address", "phone_regular", "phone_emergency", "whatsapp",
-		"opening_hours", "emergency_24h", "website_url", "applemap_url", "latitude", "longitude",
-		"rating", "google_place_id", "photo_reference",
+// parseNearbyQuery reads the /clinics?lat=&lng= search: lat and lng in
+// degrees, radius_m (default 5 km, at most 50 km) and limit (default 20,
+// at most 100).
+func parseNearbyQuery(v url.Values) (lat, lng, radius float64, limit int, err error) {
+	lat, err = strconv.ParseFloat(v.Get("lat"), 64)
+	if err != nil || math.IsNaN(lat) || lat < -90 || lat > 90 {
+		return 0, 0, 0, 0, fmt.Errorf("lat must be a latitude between -90 and 90")
 	}
-	if err := writer.Write(header); err != nil {
-		fmt.Printf("Error writing header: %v\n", err)
-		return
+	lng, err = strconv.ParseFloat(v.Get("lng"), 64)
+	if err != nil || math.IsNaN(lng) || lng < -180 || lng > 180 {
+		return 0, 0, 0, 0, fmt.Errorf("lng must be a longitude between -180 and 180")
 	}
-
-	for _, c := range s.clinics {
-		record := []string{
-			c.ClinicID,
-			c.Name,
-			c.Address,
-			c.PhoneRegular,
-			c.PhoneEmergency,
-			c.Whatsapp,
-			c.OpeningHours,
-			c.Emergency24h,
-			c.WebsiteURL,
-			c.ApplemapURL,
-			c.Latitude,
-			c.Longitude,
-			c.Rating,
-			c.GooglePlaceID,
-			c.PhotoReference,
+	radius = defaultNearbyRadiusM
+	if s := v.Get("radius_m"); s != "" {
+		radius, err = strconv.ParseFloat(s, 64)
+		if err != nil || !(radius > 0 && radius <= maxNearbyRadiusM) {
+			return 0, 0, 0, 0, fmt.Errorf("radius_m must be between 0 and %d", maxNearbyRadiusM)
 		}
-		if err := writer.Write(record); err != nil {
-			fmt.Printf("Error writing record: %v\n", err)
+	}
+	limit = defaultNearbyLimit
+	if s := v.Get("limit"); s != "" {
+		limit, err = strconv.Atoi(s)
+		if err != nil || limit < 1 || limit > maxNearbyLimit {
+			return 0, 0, 0, 0, fmt.Errorf("limit must be between 1 and %d", maxNearbyLimit)
 		}
 	}
-	fmt.Printf("Saved updated clinics data to %s\n", path)
+	return lat, lng, radius, limit, nil
 }
 
-func NewClinicsHandler(cfg *config.Config) http.HandlerFunc {
-	svc := getClinicsService(cfg)
+// NewEmergencyClinicsHandler lists the clinics open around the clock on
+// ?date= (a Hong Kong date, today by default), on their holiday hours when
+// it is a public holiday.
+func NewEmergencyClinicsHandler(cfg *config.Config, db *gorm.DB, cal *holidays.Calendar) http.HandlerFunc {
+	svc := getClinicsService(cfg, db)
 	return func(w http.ResponseWriter, r *http.Request) {
 		EnableCors(&w)
 		w.Header().Set("Content-Type", "application/json")
 
-		svc.mu.RLock()
-		defer svc.mu.RUnlock()
+		day := time.Now()
+		if s := r.URL.Query().Get("date"); s != "" {
+			var err error
+			if day, err = time.ParseInLocation("2006-01-02", s, models.HongKong); err != nil {
+				http.Error(w, "date must be a date (2006-01-02)", http.StatusBadRequest)
+				return
+			}
+		}
 
-		if err := json.NewEncoder(w).Encode(svc.clinics); err != nil {
+		clinics, err := svc.list()
+		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
 		}
-	}
-}
-
-func NewEmergencyClinicsHandler(cfg *config.Config) http.HandlerFunc {
-	svc := getClinicsService(cfg)
-	return func(w http.ResponseWriter, r *http.Request) {
-		EnableCors(&w)
-		w.Header().Set("Content-Type", "application/json")
-
-		svc.mu.RLock()
-		defer svc.mu.RUnlock()
-
-		var filtered []models.Clinic
-		for _, c := range svc.clinics {
-			if strings.EqualFold(c.Emergency24h, "true") {
+		filtered := []models.Clinic{}
+		for _, c := range clinics {
+			if c.OpenAllDay(day, cal.IsHoliday) {
 				filtered = append(filtered, c)
 			}
 		}
-
 		if err := json.NewEncoder(w).Encode(filtered); err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 		}
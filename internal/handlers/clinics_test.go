@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/vf0429/Petwell_Backend/internal/config"
+	"github.com/vf0429/Petwell_Backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// openClinicsTestDB builds a scenario DB holding stmts and points the
+// clinics service, which is otherwise built once per process, at it.
+func openClinicsTestDB(t *testing.T, stmts ...string) (*config.Config, *gorm.DB) {
+	t.Helper()
+	db := openScenarioTestDB(t, stmts...)
+	serviceOnce = sync.Once{}
+	t.Cleanup(func() { serviceOnce = sync.Once{} })
+	return &config.Config{}, db
+}
+
+func TestNearbyClinics(t *testing.T) {
+	cfg, db := openClinicsTestDB(t,
+		`INSERT INTO clinics (clinic_id, name, latitude, longitude) VALUES
+			('central', 'Central Vet', '22.2820', '114.1580'),
+			('wan_chai', 'Wan Chai Vet', '22.2770', '114.1720'),
+			('sha_tin', 'Sha Tin Vet', '22.3830', '114.1880'),
+			('no_location', 'Mobile Vet', '', '')`,
+	)
+	handler := NewClinicsHandler(cfg, db, nil)
+
+	tests := []struct {
+		name   string
+		query  string
+		status int
+		want   string // substring of the response body
+	}{
+		{"lat only", "lat=22.28", http.StatusBadRequest, "lng must be a longitude between -180 and 180"},
+		{"lng only", "lng=114.16", http.StatusBadRequest, "lat must be a latitude between -90 and 90"},
+		{"lat not a number", "lat=north&lng=114.16", http.StatusBadRequest, "lat must be a latitude"},
+		{"lat out of range", "lat=91&lng=114.16", http.StatusBadRequest, "lat must be a latitude"},
+		{"lng out of range", "lat=22.28&lng=-181", http.StatusBadRequest, "lng must be a longitude"},
+		{"zero radius", "lat=22.28&lng=114.16&radius_m=0", http.StatusBadRequest, "radius_m must be between 0 and 50000"},
+		{"radius too large", "lat=22.28&lng=114.16&radius_m=50001", http.StatusBadRequest, "radius_m must be between 0 and 50000"},
+		{"limit too large", "lat=22.28&lng=114.16&limit=101", http.StatusBadRequest, "limit must be between 1 and 100"},
+		{"nothing nearby", "lat=0&lng=0", http.StatusOK, "[]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler(w, httptest.NewRequest(http.MethodGet, "/clinics?"+tt.query, nil))
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+			if !strings.Contains(w.Body.String(), tt.want) {
+				t.Errorf("body = %s, want %q", w.Body, tt.want)
+			}
+		})
+	}
+
+	nearby := func(query string) []models.NearbyClinic {
+		t.Helper()
+		w := httptest.NewRecorder()
+		handler(w, httptest.NewRequest(http.MethodGet, "/clinics?"+query, nil))
+		var got []models.NearbyClinic
+		if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+			t.Fatalf("%s: %v: %s", query, err, w.Body)
+		}
+		return got
+	}
+	ids := func(clinics []models.NearbyClinic) []string {
+		var out []string
+		for _, c := range clinics {
+			out = append(out, c.ClinicID)
+		}
+		return out
+	}
+
+	// From Central, Wan Chai is about 1.5 km away and Sha Tin about 12 km.
+	got := nearby("lat=22.2820&lng=114.1580")
+	if want := "central wan_chai"; strings.Join(ids(got), " ") != want {
+		t.Fatalf("nearby = %v, want %s", ids(got), want)
+	}
+	if got[0].DistanceM != 0 || got[1].DistanceM < 1000 || got[1].DistanceM > 2000 {
+		t.Errorf("distances = %d, %d, want 0 and about 1500", got[0].DistanceM, got[1].DistanceM)
+	}
+	if got := ids(nearby("lat=22.2820&lng=114.1580&radius_m=20000")); strings.Join(got, " ") != "central wan_chai sha_tin" {
+		t.Errorf("nearby within 20 km = %v, want central wan_chai sha_tin", got)
+	}
+	if got := ids(nearby("lat=22.3830&lng=114.1880&radius_m=20000&limit=1")); strings.Join(got, " ") != "sha_tin" {
+		t.Errorf("nearest to Sha Tin = %v, want sha_tin", got)
+	}
+}
+
+func TestClinicsOpenAt(t *testing.T) {
+	cfg, db := openClinicsTestDB(t,
+		`INSERT INTO clinics (clinic_id, name, opening_hours, emergency_24h, latitude, longitude) VALUES
+			('a_day', 'Day Vet', 'Mon-Fri: 09:00-18:00', 'FALSE', '22.2820', '114.1580'),
+			('b_night', 'Night Vet', 'Daily: 20:00-02:00', 'FALSE', '22.2770', '114.1720'),
+			('c_always', '24h Vet', 'Daily: Open 24 hours', 'TRUE', '', ''),
+			('d_unknown_24h', 'Hospital', '', 'TRUE', '', ''),
+			('e_unknown', 'Clinic', 'by appointment', 'FALSE', '', '')`,
+	)
+	handler := NewClinicsHandler(cfg, db, nil)
+
+	for _, tt := range []struct {
+		name  string
+		query string
+		want  string // bad request message
+	}{
+		{"not a time", "open_at=tomorrow", "open_at must be a Hong Kong time"},
+		{"date only", "open_at=2026-10-19", "open_at must be a Hong Kong time"},
+		{"open_now not a bool", "open_now=maybe", "open_now must be true or false"},
+		{"both", "open_now=true&open_at=2026-10-19T10:00", "use either open_now or open_at"},
+		{"with bad lat", "open_at=2026-10-19T10:00&lat=x&lng=114.16", "lat must be a latitude"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler(w, httptest.NewRequest(http.MethodGet, "/clinics?"+tt.query, nil))
+			if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), tt.want) {
+				t.Errorf("status = %d, body = %s, want 400 %q", w.Code, w.Body, tt.want)
+			}
+		})
+	}
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"open_now=false", "a_day b_night c_always d_unknown_24h e_unknown"},
+		// Monday 19 October 2026, Hong Kong time.
+		{"open_at=2026-10-19T10:00", "a_day c_always d_unknown_24h"},
+		{"open_at=2026-10-19T02:00:00Z", "a_day c_always d_unknown_24h"},
+		{"open_at=2026-10-20T01:00", "b_night c_always d_unknown_24h"},
+		{"open_at=2026-10-19T18:00", "c_always d_unknown_24h"},
+		{"open_at=2026-10-19T10:00&lat=22.2820&lng=114.1580", "a_day"},
+		{"open_at=2026-10-19T23:00&lat=22.2820&lng=114.1580", "b_night"},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		handler(w, httptest.NewRequest(http.MethodGet, "/clinics?"+tt.query, nil))
+		var got []models.Clinic
+		if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+			t.Fatalf("%s: %v: %s", tt.query, err, w.Body)
+		}
+		var ids []string
+		for _, c := range got {
+			ids = append(ids, c.ClinicID)
+		}
+		if strings.Join(ids, " ") != tt.want {
+			t.Errorf("%s: clinics = %v, want %s", tt.query, ids, tt.want)
+		}
+	}
+}
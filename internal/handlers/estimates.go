@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vf0429/Petwell_Backend/internal/models"
+	"github.com/vf0429/Petwell_Backend/internal/services/eligibility"
+	"github.com/vf0429/Petwell_Backend/internal/services/payout"
+)
+
+// estimateRequest is an ad-hoc vet bill sent to POST /api/v1/estimates.
+type estimateRequest struct {
+	Items   []estimateItem `json:"items"`
+	PetType string         `json:"pet_type"` // "cat", "dog" or empty for any
+	PetAge  *float64       `json:"pet_age"`  // in years, e.g. 0.5 for six months
+}
+
+type estimateItem struct {
+	ItemName  string `json:"item_name"`
+	AmountHKD int    `json:"amount_hkd"`
+}
+
+// estimateResult is one ranked product in the estimate response.
+type estimateResult struct {
+	Rank int `json:"rank"`
+	payout.Result
+}
+
+func registerEstimateRoutes(r *gin.Engine, repo *models.InsuranceRepository) {
+	r.POST("/estimates", localizeRoute, func(c *gin.Context) {
+		var req estimateRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+			return
+		}
+		if len(req.Items) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "items must not be empty"})
+			return
+		}
+
+		items := make([]models.CostItem, len(req.Items))
+		total := 0
+		for i, item := range req.Items {
+			if item.ItemName == "" || item.AmountHKD <= 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("items[%d] needs an item_name and a positive amount_hkd", i)})
+				return
+			}
+			items[i] = models.CostItem{ItemName: item.ItemName, AmountHKD: item.AmountHKD}
+			total += item.AmountHKD
+		}
+
+		ageWeeks := -1
+		if req.PetAge != nil {
+			if *req.PetAge < 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "pet_age must not be negative"})
+				return
+			}
+			ageWeeks = int(*req.PetAge * eligibility.WeeksPerYear)
+		}
+		petType := eligibility.PetType(strings.ToLower(strings.TrimSpace(req.PetType)))
+		if petType != "" && petType != eligibility.PetCat && petType != eligibility.PetDog {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "pet_type must be cat or dog"})
+			return
+		}
+
+		results, uncovered, err := payout.EstimateBill(repo, items, petType, ageWeeks)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		estimates := make([]estimateResult, len(results))
+		for i, res := range results {
+			estimates[i] = estimateResult{Rank: i + 1, Result: res}
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"total_cost_hkd": total,
+			"estimates":      estimates,
+			// Eligible products that can't be estimated without coverage data
+			"no_coverage_product_ids": uncovered,
+		})
+	})
+}
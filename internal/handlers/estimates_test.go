@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+func TestEstimatesValidation(t *testing.T) {
+	// Every request here fails before the repository is used.
+	r := gin.New()
+	registerEstimateRoutes(r, nil)
+
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"not json", `items=1`, "Invalid request body"},
+		{"no items", `{"items": []}`, "items must not be empty"},
+		{"missing name", `{"items": [{"item_name": "consultation", "amount_hkd": 500}, {"amount_hkd": 200}]}`, "items[1] needs an item_name and a positive amount_hkd"},
+		{"zero amount", `{"items": [{"item_name": "consultation", "amount_hkd": 0}]}`, "items[0] needs an item_name and a positive amount_hkd"},
+		{"negative age", `{"items": [{"item_name": "consultation", "amount_hkd": 500}], "pet_age": -1}`, "pet_age must not be negative"},
+		{"unknown pet type", `{"items": [{"item_name": "consultation", "amount_hkd": 500}], "pet_type": "rabbit"}`, "pet_type must be cat or dog"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/estimates", strings.NewReader(tt.body)))
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("status = %d, want 400: %s", w.Code, w.Body)
+			}
+			if !strings.Contains(w.Body.String(), tt.want) {
+				t.Errorf("body = %s, want error %q", w.Body, tt.want)
+			}
+		})
+	}
+}
+
+func TestEstimates(t *testing.T) {
+	repo := openInsuranceTestRepo(t,
+		"INSERT INTO insurance_provider (company_id, company_name) VALUES (1, 'OneDegree')",
+		"INSERT INTO product (insurance_id, provider_id, insurance_name, suitable_pet_type) VALUES (1, 1, 'Essential Plan', 'cat, dog'), (2, 1, 'Plus Plan', 'cat, dog'), (3, 1, 'Dog Plan', 'dog')",
+		"INSERT INTO coverage_list (coverage_id, coverage_type) VALUES (1, 'Medical Expenses')",
+		"INSERT INTO coverage_limit (coverage_id, product_id, coverage_limit) VALUES (1, 1, '30000'), (1, 3, '30000')",
+	)
+	r := gin.New()
+	registerEstimateRoutes(r, repo)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/estimates", strings.NewReader(`{"items": [{"item_name": "consultation", "amount_hkd": 500}], "pet_type": "Cat"}`)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", w.Code, w.Body)
+	}
+	var resp struct {
+		TotalCostHKD int `json:"total_cost_hkd"`
+		Estimates    []struct {
+			Rank      int `json:"rank"`
+			ProductID int `json:"product_id"`
+		} `json:"estimates"`
+		NoCoverageProductIDs []int `json:"no_coverage_product_ids"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	// The dog plan is not offered for cats; the Plus Plan has no coverage data.
+	if resp.TotalCostHKD != 500 || len(resp.Estimates) != 1 || resp.Estimates[0].ProductID != 1 || resp.Estimates[0].Rank != 1 {
+		t.Errorf("estimates = %+v, want product 1 ranked first for $500", resp)
+	}
+	if len(resp.NoCoverageProductIDs) != 1 || resp.NoCoverageProductIDs[0] != 2 {
+		t.Errorf("no_coverage_product_ids = %v, want [2]", resp.NoCoverageProductIDs)
+	}
+}
@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/vf0429/Petwell_Backend/internal/config"
+	"github.com/vf0429/Petwell_Backend/internal/models"
+	"github.com/vf0429/Petwell_Backend/internal/services/holidays"
+	"gorm.io/gorm"
+)
+
+// NewPublicHolidaysHandler lists the Hong Kong public holidays of ?year=,
+// this year by default.
+func NewPublicHolidaysHandler(cal *holidays.Calendar) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		EnableCors(&w)
+		w.Header().Set("Content-Type", "application/json")
+
+		year := time.Now().In(models.HongKong).Year()
+		if s := r.URL.Query().Get("year"); s != "" {
+			var err error
+			if year, err = strconv.Atoi(s); err != nil {
+				http.Error(w, "year must be an integer", http.StatusBadRequest)
+				return
+			}
+		}
+		if !cal.Covers(year) {
+			http.Error(w, fmt.Sprintf("holiday calendar %s has no holidays for %d", cal.Version, year), http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(struct {
+			Version  string             `json:"version"`
+			Year     int                `json:"year"`
+			Holidays []holidays.Holiday `json:"holidays"`
+		}{cal.Version, year, cal.InYear(year)})
+	}
+}
+
+// ClinicHolidayHours is what one clinic opens on a public holiday. Source
+// says where the hours come from: the clinic's own holiday hours
+// ("holiday"), its usual hours for that weekday ("weekday"), or nowhere
+// ("unknown", with Hours null).
+type ClinicHolidayHours struct {
+	ClinicID   string          `json:"clinic_id"`
+	Name       string          `json:"name"`
+	Hours      models.DayHours `json:"hours"`
+	Source     string          `json:"source"`
+	OpenAllDay bool            `json:"open_all_day"`
+}
+
+// NewClinicHolidayHoursHandler lists every clinic's hours on the public
+// holiday ?date=, or on the next one when no date is given.
+func NewClinicHolidayHoursHandler(cfg *config.Config, db *gorm.DB, cal *holidays.Calendar) http.HandlerFunc {
+	svc := getClinicsService(cfg, db)
+	return func(w http.ResponseWriter, r *http.Request) {
+		EnableCors(&w)
+		w.Header().Set("Content-Type", "application/json")
+
+		var holiday holidays.Holiday
+		var ok bool
+		if s := r.URL.Query().Get("date"); s != "" {
+			day, err := time.ParseInLocation("2006-01-02", s, models.HongKong)
+			if err != nil {
+				http.Error(w, "date must be a date (2006-01-02)", http.StatusBadRequest)
+				return
+			}
+			if holiday, ok = cal.On(day); !ok {
+				http.Error(w, fmt.Sprintf("%s is not a public holiday in holiday calendar %s", s, cal.Version), http.StatusNotFound)
+				return
+			}
+		} else if holiday, ok = cal.Next(time.Now()); !ok {
+			http.Error(w, fmt.Sprintf("holiday calendar %s has no upcoming holidays", cal.Version), http.StatusNotFound)
+			return
+		}
+
+		clinics, err := svc.list()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		day := holiday.Day()
+		hours := make([]ClinicHolidayHours, len(clinics))
+		for i, c := range clinics {
+			h := ClinicHolidayHours{ClinicID: c.ClinicID, Name: c.Name, Source: "unknown", OpenAllDay: c.OpenAllDay(day, cal.IsHoliday)}
+			if c.Schedule != nil {
+				h.Hours, h.Source = append(models.DayHours{}, c.Schedule.HoursOn(day, cal.IsHoliday)...), "weekday"
+				if c.Schedule.PublicHoliday != nil {
+					h.Source = "holiday"
+				}
+			}
+			hours[i] = h
+		}
+		json.NewEncoder(w).Encode(struct {
+			CalendarVersion string               `json:"calendar_version"`
+			Holiday         holidays.Holiday     `json:"holiday"`
+			Clinics         []ClinicHolidayHours `json:"clinics"`
+		}{cal.Version, holiday, hours})
+	}
+}
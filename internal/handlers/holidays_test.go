@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/vf0429/Petwell_Backend/internal/models"
+	"github.com/vf0429/Petwell_Backend/internal/services/holidays"
+)
+
+func loadTestCalendar(t *testing.T) *holidays.Calendar {
+	t.Helper()
+	cal, err := holidays.Load("../../" + holidays.DefaultPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cal
+}
+
+func TestPublicHolidays(t *testing.T) {
+	handler := NewPublicHolidaysHandler(loadTestCalendar(t))
+
+	tests := []struct {
+		query  string
+		status int
+		want   string // substring of the response body
+	}{
+		{"year=abc", http.StatusBadRequest, "year must be an integer"},
+		{"year=2030", http.StatusNotFound, "has no holidays for 2030"},
+		{"year=2026", http.StatusOK, `"name":"Christmas Day"`},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		handler(w, httptest.NewRequest(http.MethodGet, "/public-holidays?"+tt.query, nil))
+		if w.Code != tt.status || !strings.Contains(w.Body.String(), tt.want) {
+			t.Errorf("%s: status = %d, body = %s, want %d %q", tt.query, w.Code, w.Body, tt.status, tt.want)
+		}
+	}
+}
+
+func TestClinicHolidayHours(t *testing.T) {
+	cal := loadTestCalendar(t)
+	cfg, db := openClinicsTestDB(t,
+		`INSERT INTO clinics (clinic_id, name, opening_hours, emergency_24h) VALUES
+			('a_day', 'Day Vet', 'Mon-Fri: 09:00-18:00', 'FALSE'),
+			('b_holiday', 'Holiday Vet', 'Mon-Fri: 09:00-18:00; Public holidays: 10:00-14:00', 'FALSE'),
+			('c_always', '24h Vet', 'Daily: Open 24 hours', 'TRUE'),
+			('d_days_only', 'Weekday Hospital', 'Daily: Open 24 hours; Public holidays: 10:00-18:00', 'FALSE'),
+			('e_unknown', 'Clinic', '', 'FALSE')`,
+	)
+	hoursHandler := NewClinicHolidayHoursHandler(cfg, db, cal)
+	emergencyHandler := NewEmergencyClinicsHandler(cfg, db, cal)
+
+	for _, tt := range []struct {
+		name    string
+		handler http.HandlerFunc
+		path    string
+		status  int
+		want    string
+	}{
+		{"hours bad date", hoursHandler, "/clinics/holiday-hours?date=19/10/2026", http.StatusBadRequest, "date must be a date"},
+		{"hours not a holiday", hoursHandler, "/clinics/holiday-hours?date=2026-10-20", http.StatusNotFound, "2026-10-20 is not a public holiday"},
+		{"emergency bad date", emergencyHandler, "/emergency-clinics?date=tomorrow", http.StatusBadRequest, "date must be a date"},
+	} {
+		w := httptest.NewRecorder()
+		tt.handler(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if w.Code != tt.status || !strings.Contains(w.Body.String(), tt.want) {
+			t.Errorf("%s: status = %d, body = %s, want %d %q", tt.name, w.Code, w.Body, tt.status, tt.want)
+		}
+	}
+
+	// Monday 19 October 2026 is the day following Chung Yeung Festival.
+	w := httptest.NewRecorder()
+	hoursHandler(w, httptest.NewRequest(http.MethodGet, "/clinics/holiday-hours?date=2026-10-19", nil))
+	var resp struct {
+		Holiday holidays.Holiday `json:"holiday"`
+		Clinics []struct {
+			ClinicID   string          `json:"clinic_id"`
+			Hours      json.RawMessage `json:"hours"`
+			Source     string          `json:"source"`
+			OpenAllDay bool            `json:"open_all_day"`
+		} `json:"clinics"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("%v: %s", err, w.Body)
+	}
+	if resp.Holiday.Date != "2026-10-19" {
+		t.Errorf("holiday = %+v, want 2026-10-19", resp.Holiday)
+	}
+	want := map[string]string{
+		"a_day":       `weekday [{"open":"09:00","close":"18:00"}] false`,
+		"b_holiday":   `holiday [{"open":"10:00","close":"14:00"}] false`,
+		"c_always":    `weekday [{"open":"00:00","close":"24:00"}] true`,
+		"d_days_only": `holiday [{"open":"10:00","close":"18:00"}] false`,
+		"e_unknown":   `unknown null false`,
+	}
+	if len(resp.Clinics) != len(want) {
+		t.Fatalf("clinics = %d, want %d", len(resp.Clinics), len(want))
+	}
+	for _, c := range resp.Clinics {
+		got := strings.Join([]string{c.Source, string(c.Hours), strconv.FormatBool(c.OpenAllDay)}, " ")
+		if got != want[c.ClinicID] {
+			t.Errorf("%s: hours = %s, want %s", c.ClinicID, got, want[c.ClinicID])
+		}
+	}
+
+	for _, tt := range []struct{ date, want string }{
+		{"2026-10-19", "c_always"},
+		{"2026-10-20", "c_always d_days_only"},
+	} {
+		w := httptest.NewRecorder()
+		emergencyHandler(w, httptest.NewRequest(http.MethodGet, "/emergency-clinics?date="+tt.date, nil))
+		var got []models.Clinic
+		if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+			t.Fatalf("%s: %v: %s", tt.date, err, w.Body)
+		}
+		var ids []string
+		for _, c := range got {
+			ids = append(ids, c.ClinicID)
+		}
+		if strings.Join(ids, " ") != tt.want {
+			t.Errorf("emergency clinics on %s = %v, want %s", tt.date, ids, tt.want)
+		}
+	}
+}
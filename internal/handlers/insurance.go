@@ -1,211 +1,252 @@
 package handlers
 
 import (
-	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
 	"net/http"
-	"os"
-	"path/filepath"
+	"net/url"
+	"strconv"
+	"strings"
 
 	"github.com/vf0429/Petwell_Backend/internal/models"
-
-	_ "github.com/mattn/go-sqlite3"
+	"github.com/vf0429/Petwell_Backend/internal/services/eligibility"
+	"github.com/vf0429/Petwell_Backend/internal/services/payout"
 )
 
-const insuranceDBPath = "assets/pet_insurance.db"
-
-func OpenInsuranceDB() (*sql.DB, error) {
-	if _, err := os.Stat(insuranceDBPath); err == nil {
-		return sql.Open("sqlite3", insuranceDBPath)
+// insuranceDBError logs a failed insurance DB read and reports it as a 500,
+// or as a 503 when the coverage data hasn't been imported yet.
+func insuranceDBError(w http.ResponseWriter, err error) {
+	log.Printf("insurance db: %v", err)
+	status := http.StatusInternalServerError
+	if errors.Is(err, payout.ErrNoCoverage) {
+		status = http.StatusServiceUnavailable
 	}
+	http.Error(w, err.Error(), status)
+}
 
-	ex, err := os.Executable()
-	if err == nil {
-		path := filepath.Join(filepath.Dir(ex), insuranceDBPath)
-		if _, err := os.Stat(path); err == nil {
-			return sql.Open("sqlite3", path)
+func NewInsuranceCompaniesHandler(repo *models.InsuranceRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		EnableCors(&w)
+		if r.Method == http.MethodOptions {
+			return
 		}
-	}
 
-	return sql.Open("sqlite3", insuranceDBPath)
-}
+		companies, err := repo.Companies()
+		if err != nil {
+			insuranceDBError(w, err)
+			return
+		}
 
-func InsuranceCompaniesHandler(w http.ResponseWriter, r *http.Request) {
-	EnableCors(&w)
-	if r.Method == http.MethodOptions {
-		return
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(companies)
 	}
+}
 
-	db, err := OpenInsuranceDB()
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-	defer db.Close()
-
-	rows, err := db.Query("SELECT company_id, company_name, company_name_zh, company_logo FROM insurance_provider")
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-	defer rows.Close()
+// NewInsuranceTagsHandler lists the product tags in display order with the
+// number of products carrying each, for rendering filter chips.
+// GET /insurance-tags
+func NewInsuranceTagsHandler(repo *models.InsuranceRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		EnableCors(&w)
+		if r.Method == http.MethodOptions {
+			return
+		}
 
-	var companies []models.InsuranceCompany
-	for rows.Next() {
-		var c models.InsuranceCompany
-		if err := rows.Scan(&c.CompanyId, &c.CompanyName, &c.CompanyNameZh, &c.CompanyLogo); err == nil {
-			companies = append(companies, c)
+		tags, err := repo.Tags()
+		if err != nil {
+			insuranceDBError(w, err)
+			return
 		}
-	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(companies)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(tags)
+	}
 }
 
-func InsuranceProductsHandler(w http.ResponseWriter, r *http.Request) {
-	EnableCors(&w)
-	if r.Method == http.MethodOptions {
-		return
-	}
+func NewInsuranceProductsHandler(repo *models.InsuranceRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		EnableCors(&w)
+		if r.Method == http.MethodOptions {
+			return
+		}
 
-	db, err := OpenInsuranceDB()
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-	defer db.Close()
+		q, filtered, err := parseProductQuery(r.URL.Query())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 
-	rows, err := db.Query(`SELECT insurance_id, provider_id, insurance_name, insurance_name_zh, remark, remark_zh, 
-		min_age, min_age_zh, max_age, max_age_zh, coinsurance, coinsurance_zh, suitable_pet_type, suitable_pet_type_zh,
-		cat_breed_type, cat_breed_type_zh, dog_breed_type, dog_breed_type_zh, breed_type_remark, breed_type_remark_zh,
-		payment_mode, payment_mode_zh, waiting_period, waiting_period_zh, information_link, information_link_zh, update_time,
-		NULL as tag, NULL as tag_zh
-		FROM product`)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-	defer rows.Close()
+		reader, ok := insuranceReader(w, r, repo)
+		if !ok {
+			return
+		}
+		products, err := reader.Products()
+		if err != nil {
+			insuranceDBError(w, err)
+			return
+		}
 
-	var products []models.InsuranceProduct
-	for rows.Next() {
-		var p models.InsuranceProduct
-		if err := rows.Scan(&p.InsuranceId, &p.ProviderId, &p.InsuranceName, &p.InsuranceNameZh, &p.Remark, &p.RemarkZh,
-			&p.MinAge, &p.MinAgeZh, &p.MaxAge, &p.MaxAgeZh, &p.Coinsurance, &p.CoinsuranceZh, &p.SuitablePetType, &p.SuitablePetTypeZh,
-			&p.CatBreedType, &p.CatBreedTypeZh, &p.DogBreedType, &p.DogBreedTypeZh, &p.BreedTypeRemark, &p.BreedTypeRemarkZh,
-			&p.PaymentMode, &p.PaymentModeZh, &p.WaitingPeriod, &p.WaitingPeriodZh, &p.InformationLink, &p.InformationLinkZh, &p.UpdateTime,
-			&p.Tag, &p.TagZh); err == nil {
-			products = append(products, p)
+		if filtered {
+			if coverageType := r.URL.Query().Get("coverage_type"); coverageType != "" {
+				if q.CoverageLimits, err = coverageLimitsByType(reader, coverageType); err != nil {
+					insuranceDBError(w, err)
+					return
+				}
+			}
+			matches := eligibility.Search(products, q)
+			products = make([]models.InsuranceProduct, len(matches))
+			for i, m := range matches {
+				products[i] = m.Product
+			}
 		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(products)
 	}
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(products)
 }
 
-func CoverageListHandler(w http.ResponseWriter, r *http.Request) {
-	EnableCors(&w)
-	if r.Method == http.MethodOptions {
-		return
+// parseProductQuery reads the optional /insurance-products filters:
+// pet_type, pet_age (years), breed, provider_id, tag (comma separated
+// slugs, all required unless tag_match=any), max_coinsurance (customer share in %), and coverage_type with min_limit
+// (HKD). filtered is false when none were given.
+func parseProductQuery(v url.Values) (q eligibility.Query, filtered bool, err error) {
+	q.AgeWeeks = -1
+	for _, key := range []string{"pet_type", "pet_age", "breed", "provider_id", "tag", "max_coinsurance", "coverage_type", "min_limit"} {
+		if v.Get(key) != "" {
+			filtered = true
+		}
 	}
 
-	db, err := OpenInsuranceDB()
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+	if s := strings.ToLower(v.Get("pet_type")); s != "" {
+		q.PetType = eligibility.PetType(s)
+		if q.PetType != eligibility.PetCat && q.PetType != eligibility.PetDog {
+			return q, filtered, fmt.Errorf("pet_type must be cat or dog")
+		}
 	}
-	defer db.Close()
-
-	rows, err := db.Query("SELECT coverage_id, coverage_type, coverage_type_zh FROM coverage_list")
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+	if s := v.Get("pet_age"); s != "" {
+		years, err := strconv.ParseFloat(s, 64)
+		if err != nil || years < 0 {
+			return q, filtered, fmt.Errorf("pet_age must be a non-negative number of years")
+		}
+		q.AgeWeeks = int(years * eligibility.WeeksPerYear)
 	}
-	defer rows.Close()
-
-	var list []models.CoverageItem
-	for rows.Next() {
-		var item models.CoverageItem
-		if err := rows.Scan(&item.CoverageId, &item.CoverageType, &item.CoverageTypeZh); err == nil {
-			list = append(list, item)
+	q.Breed = v.Get("breed")
+	if s := v.Get("provider_id"); s != "" {
+		if q.ProviderID, err = strconv.Atoi(s); err != nil {
+			return q, filtered, fmt.Errorf("provider_id must be an integer")
 		}
 	}
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(list)
-}
-
-func CoverageLimitsHandler(w http.ResponseWriter, r *http.Request) {
-	EnableCors(&w)
-	if r.Method == http.MethodOptions {
-		return
+	if s := v.Get("tag"); s != "" {
+		q.Tags = eligibility.SplitTags(s)
 	}
-
-	db, err := OpenInsuranceDB()
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+	switch v.Get("tag_match") {
+	case "", "all":
+	case "any":
+		q.AnyTag = true
+	default:
+		return q, filtered, fmt.Errorf("tag_match must be all or any")
+	}
+	if s := v.Get("max_coinsurance"); s != "" {
+		pct, err := strconv.ParseFloat(strings.TrimSuffix(s, "%"), 64)
+		if err != nil {
+			return q, filtered, fmt.Errorf("max_coinsurance must be a percentage")
+		}
+		q.MaxCoinsurancePct = &pct
 	}
-	defer db.Close()
+	if s := v.Get("min_limit"); s != "" {
+		if v.Get("coverage_type") == "" {
+			return q, filtered, fmt.Errorf("min_limit requires coverage_type")
+		}
+		if q.MinLimitHKD, err = strconv.Atoi(s); err != nil {
+			return q, filtered, fmt.Errorf("min_limit must be an integer amount in HKD")
+		}
+	}
+	return q, filtered, nil
+}
 
-	rows, err := db.Query("SELECT coverage_id, product_id, coverage_limit, remark, remark_zh FROM coverage_limit")
+// coverageLimitsByType returns each product's limit for the coverage type
+// named in English or Chinese.
+func coverageLimitsByType(repo models.InsuranceReader, coverageType string) (map[int]int, error) {
+	rows, err := repo.CoverageLimitsByType(coverageType)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+		return nil, err
 	}
-	defer rows.Close()
+	limits := make(map[int]int, len(rows))
+	for _, l := range rows {
+		limits[l.ProductId] = payout.ParseAmount(l.CoverageLimit)
+	}
+	return limits, nil
+}
 
-	var limits []models.CoverageLimit
-	for rows.Next() {
-		var l models.CoverageLimit
-		if err := rows.Scan(&l.CoverageId, &l.ProductId, &l.CoverageLimit, &l.Remark, &l.RemarkZh); err == nil {
-			limits = append(limits, l)
+// NewInsuranceRulesHandler returns the typed eligibility rules parsed from
+// the product table, plus the rows whose free text couldn't be parsed.
+func NewInsuranceRulesHandler(repo *models.InsuranceRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		EnableCors(&w)
+		if r.Method == http.MethodOptions {
+			return
 		}
+
+		products, err := repo.Products()
+		if err != nil {
+			insuranceDBError(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(eligibility.ParseAll(products))
 	}
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(limits)
 }
 
-func SubCoverageLimitsHandler(w http.ResponseWriter, r *http.Request) {
-	EnableCors(&w)
-	if r.Method == http.MethodOptions {
-		return
-	}
+func NewCoverageListHandler(repo *models.InsuranceRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		EnableCors(&w)
+		if r.Method == http.MethodOptions {
+			return
+		}
 
-	db, err := OpenInsuranceDB()
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+		list, err := repo.CoverageList()
+		if err != nil {
+			insuranceDBError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(list)
 	}
-	defer db.Close()
+}
 
-	rows, err := db.Query(`SELECT sub_coverage_id, parent_coverage_id, product_id, sub_coverage_name, sub_coverage_name_zh, 
-		sub_limit, sub_coverage_remark, sub_coverage_remark_zh FROM sub_coverage_limit`)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-	defer rows.Close()
+func NewCoverageLimitsHandler(repo *models.InsuranceRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		EnableCors(&w)
+		if r.Method == http.MethodOptions {
+			return
+		}
 
-	var limits []models.SubCoverageLimit
-	for rows.Next() {
-		var l models.SubCoverageLimit
-		if err := rows.Scan(&l.SubCoverageId, &l.ParentCoverageId, &l.ProductId, &l.SubCoverageName, &l.SubCoverageNameZh,
-			&l.SubLimit, &l.SubCoverageRemark, &l.SubCoverageRemarkZh); err == nil {
-			limits = append(limits, l)
+		limits, err := repo.CoverageLimits()
+		if err != nil {
+			insuranceDBError(w, err)
+			return
 		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(limits)
 	}
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(limits)
 }
 
-// Legacy Handlers - Return empty
-func InsuranceProvidersHandler(w http.ResponseWriter, r *http.Request) {
-	EnableCors(&w)
-	w.Header().Set("Content-Type", "application/json")
-	w.Write([]byte("[]"))
-}
+func NewSubCoverageLimitsHandler(repo *models.InsuranceRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		EnableCors(&w)
+		if r.Method == http.MethodOptions {
+			return
+		}
 
-func ServiceSubcategoriesHandler(w http.ResponseWriter, r *http.Request) {
-	EnableCors(&w)
-	w.Header().Set("Content-Type", "application/json")
-	w.Write([]byte("[]"))
+		limits, err := repo.SubCoverageLimits()
+		if err != nil {
+			insuranceDBError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(limits)
+	}
 }
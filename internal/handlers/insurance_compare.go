@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/vf0429/Petwell_Backend/internal/models"
+)
+
+const maxCompareProducts = 10
+
+// compareCell is one product's terms for one coverage type.
+type compareCell struct {
+	ProductID         int                       `json:"product_id"`
+	Covered           bool                      `json:"covered"`
+	CoverageLimit     models.NullJsonString     `json:"coverage_limit"`
+	Remark            models.NullJsonString     `json:"remark"`
+	RemarkZh          models.NullJsonString     `json:"remark_zh"`
+	SubCoverageLimits []models.SubCoverageLimit `json:"sub_coverage_limits"`
+	Differs           bool                      `json:"differs"`
+	signature         string
+}
+
+// compareRow is one coverage_list entry across the compared products.
+type compareRow struct {
+	CoverageID     int                   `json:"coverage_id"`
+	CoverageTypeZh models.NullJsonString `json:"coverage_type_zh"`
+	Differs        bool                  `json:"differs"`
+	Cells          []compareCell         `json:"cells"`
+}
+
+type compareResponse struct {
+	Products []models.InsuranceProduct `json:"products"`
+	Matrix   map[string]*compareRow    `json:"matrix"`
+}
+
+// NewInsuranceProductsCompareHandler returns a side-by-side matrix of
+// products.
+// GET /insurance-products/compare?ids=1,4,9[&as_of=2026-02-03]
+//
+// The matrix is keyed by coverage_list.coverage_type; each row holds one
+// cell per product, in the order the ids were given. A cell is flagged
+// when its limits differ from what most of the other products offer.
+func NewInsuranceProductsCompareHandler(repo *models.InsuranceRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		EnableCors(&w)
+		if r.Method == http.MethodOptions {
+			return
+		}
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		ids, err := parseIDList(r.URL.Query().Get("ids"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if len(ids) < 2 || len(ids) > maxCompareProducts {
+			http.Error(w, fmt.Sprintf("ids must list between 2 and %d products", maxCompareProducts), http.StatusBadRequest)
+			return
+		}
+
+		reader, ok := insuranceReader(w, r, repo)
+		if !ok {
+			return
+		}
+
+		products := make([]models.InsuranceProduct, 0, len(ids))
+		var limits []models.CoverageLimit
+		var subLimits []models.SubCoverageLimit
+		for _, id := range ids {
+			p, err := reader.Product(id)
+			if errors.Is(err, models.ErrNotFound) {
+				http.Error(w, fmt.Sprintf("product %d not found", id), http.StatusNotFound)
+				return
+			}
+			if err != nil {
+				insuranceDBError(w, err)
+				return
+			}
+			products = append(products, *p)
+
+			l, err := reader.ProductCoverageLimits(id)
+			if err != nil {
+				insuranceDBError(w, err)
+				return
+			}
+			limits = append(limits, l...)
+			s, err := reader.ProductSubCoverageLimits(id)
+			if err != nil {
+				insuranceDBError(w, err)
+				return
+			}
+			subLimits = append(subLimits, s...)
+		}
+
+		coverageList, err := reader.CoverageList()
+		if err != nil {
+			insuranceDBError(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(compareResponse{
+			Products: products,
+			Matrix:   buildCompareMatrix(ids, coverageList, limits, subLimits),
+		})
+	}
+}
+
+type coverageKey struct{ coverageID, productID int }
+
+func buildCompareMatrix(ids []int, coverageList []models.CoverageItem, limits []models.CoverageLimit, subLimits []models.SubCoverageLimit) map[string]*compareRow {
+	limitByKey := make(map[coverageKey]models.CoverageLimit)
+	for _, l := range limits {
+		limitByKey[coverageKey{l.CoverageId, l.ProductId}] = l
+	}
+	subsByKey := make(map[coverageKey][]models.SubCoverageLimit)
+	for _, s := range subLimits {
+		key := coverageKey{s.ParentCoverageId, s.ProductId}
+		subsByKey[key] = append(subsByKey[key], s)
+	}
+
+	matrix := make(map[string]*compareRow)
+	for _, item := range coverageList {
+		row := &compareRow{CoverageID: item.CoverageId, CoverageTypeZh: item.CoverageTypeZh}
+		anyCovered := false
+		for _, id := range ids {
+			key := coverageKey{item.CoverageId, id}
+			cell := compareCell{ProductID: id, SubCoverageLimits: subsByKey[key]}
+			if l, ok := limitByKey[key]; ok {
+				cell.Covered = true
+				cell.CoverageLimit = l.CoverageLimit
+				cell.Remark = l.Remark
+				cell.RemarkZh = l.RemarkZh
+				anyCovered = true
+			}
+			if cell.SubCoverageLimits == nil {
+				cell.SubCoverageLimits = []models.SubCoverageLimit{}
+			}
+			cell.signature = cellSignature(cell)
+			row.Cells = append(row.Cells, cell)
+		}
+		if !anyCovered {
+			continue
+		}
+		flagDifferences(row)
+		matrix[item.CoverageType] = row
+	}
+	return matrix
+}
+
+// cellSignature captures the parts of a cell that matter for comparison:
+// whether it's covered, the limit and the sub-limits. Remarks are ignored
+// since insurers word identical terms differently.
+func cellSignature(c compareCell) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%t|%s", c.Covered, strings.TrimSpace(c.CoverageLimit.String))
+	for _, s := range c.SubCoverageLimits {
+		fmt.Fprintf(&b, "|%s=%s", strings.ToLower(strings.TrimSpace(s.SubCoverageName.String)), strings.TrimSpace(s.SubLimit.String))
+	}
+	return b.String()
+}
+
+// flagDifferences marks cells whose signature isn't the most common one in
+// the row. When no signature is shared by two cells, every cell differs.
+func flagDifferences(row *compareRow) {
+	counts := make(map[string]int)
+	modal, best := "", 0
+	for _, c := range row.Cells {
+		counts[c.signature]++
+		if counts[c.signature] > best {
+			modal, best = c.signature, counts[c.signature]
+		}
+	}
+	for i := range row.Cells {
+		if len(counts) > 1 && (best == 1 || row.Cells[i].signature != modal) {
+			row.Cells[i].Differs = true
+			row.Differs = true
+		}
+	}
+}
+
+// parseIDList parses a comma-separated list of positive integers,
+// dropping duplicates but keeping the order.
+func parseIDList(s string) ([]int, error) {
+	var ids []int
+	seen := make(map[int]bool)
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		id, err := strconv.Atoi(part)
+		if err != nil || id <= 0 {
+			return nil, fmt.Errorf("invalid id %q", part)
+		}
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/vf0429/Petwell_Backend/internal/models"
+)
+
+// coverageNode is one coverage_list entry of a product together with the
+// product's limit for it and the sub-limits hanging off it.
+type coverageNode struct {
+	CoverageId        int                       `json:"coverage_id"`
+	CoverageType      string                    `json:"coverage_type"`
+	CoverageTypeZh    models.NullJsonString     `json:"coverage_type_zh,omitempty"`
+	CoverageLimit     models.NullJsonString     `json:"coverage_limit"`
+	Remark            models.NullJsonString     `json:"remark,omitempty"`
+	RemarkZh          models.NullJsonString     `json:"remark_zh,omitempty"`
+	SubCoverageLimits []models.SubCoverageLimit `json:"sub_coverage_limits"`
+}
+
+type productDetail struct {
+	Product   models.InsuranceProduct  `json:"product"`
+	Provider  *models.InsuranceCompany `json:"provider"`
+	Coverages []coverageNode           `json:"coverages"`
+}
+
+// NewInsuranceProductHandler returns one product with its provider and the
+// coverage tree coverage_list -> coverage_limit -> sub_coverage_limit.
+// GET /insurance-products/{id}[?as_of=2026-02-03]
+func NewInsuranceProductHandler(repo *models.InsuranceRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		EnableCors(&w)
+		if r.Method == http.MethodOptions {
+			return
+		}
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		id, err := strconv.Atoi(r.PathValue("id"))
+		if err != nil {
+			http.Error(w, "product id must be an integer", http.StatusBadRequest)
+			return
+		}
+
+		reader, ok := insuranceReader(w, r, repo)
+		if !ok {
+			return
+		}
+
+		product, err := reader.Product(id)
+		if errors.Is(err, models.ErrNotFound) {
+			http.Error(w, fmt.Sprintf("product %d not found", id), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			insuranceDBError(w, err)
+			return
+		}
+		detail := productDetail{Product: *product}
+
+		provider, err := reader.Company(product.ProviderId)
+		if err != nil && !errors.Is(err, models.ErrNotFound) {
+			insuranceDBError(w, err)
+			return
+		}
+		detail.Provider = provider
+
+		coverageList, err := reader.CoverageList()
+		if err != nil {
+			insuranceDBError(w, err)
+			return
+		}
+		limits, err := reader.ProductCoverageLimits(id)
+		if err != nil {
+			insuranceDBError(w, err)
+			return
+		}
+		subLimits, err := reader.ProductSubCoverageLimits(id)
+		if err != nil {
+			insuranceDBError(w, err)
+			return
+		}
+		detail.Coverages = buildCoverageTree(coverageList, limits, subLimits)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(detail)
+	}
+}
+
+// buildCoverageTree nests one product's limits and sub-limits under their
+// coverage types, in coverage_list order. Coverage types the product has
+// neither a limit nor a sub-limit for are left out.
+func buildCoverageTree(coverageList []models.CoverageItem, limits []models.CoverageLimit, subLimits []models.SubCoverageLimit) []coverageNode {
+	limitByCoverage := make(map[int]models.CoverageLimit)
+	for _, l := range limits {
+		limitByCoverage[l.CoverageId] = l
+	}
+	subsByParent := make(map[int][]models.SubCoverageLimit)
+	for _, s := range subLimits {
+		subsByParent[s.ParentCoverageId] = append(subsByParent[s.ParentCoverageId], s)
+	}
+
+	tree := []coverageNode{}
+	for _, item := range coverageList {
+		l, hasLimit := limitByCoverage[item.CoverageId]
+		subs := subsByParent[item.CoverageId]
+		if !hasLimit && len(subs) == 0 {
+			continue
+		}
+		if subs == nil {
+			subs = []models.SubCoverageLimit{}
+		}
+		tree = append(tree, coverageNode{
+			CoverageId:        item.CoverageId,
+			CoverageType:      item.CoverageType,
+			CoverageTypeZh:    item.CoverageTypeZh,
+			CoverageLimit:     l.CoverageLimit,
+			Remark:            l.Remark,
+			RemarkZh:          l.RemarkZh,
+			SubCoverageLimits: subs,
+		})
+	}
+	return tree
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/vf0429/Petwell_Backend/internal/models"
+)
+
+type productHistory struct {
+	ProductID int             `json:"product_id"`
+	Changes   []models.Change `json:"changes"`
+}
+
+// NewInsuranceProductHistoryHandler lists the recorded changes to a product
+// and its coverage limits and sub-limits, newest first. Products that have
+// since been deleted still have a history.
+// GET /insurance-products/{id}/history
+func NewInsuranceProductHistoryHandler(repo *models.InsuranceRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		EnableCors(&w)
+		if r.Method == http.MethodOptions {
+			return
+		}
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		id, err := strconv.Atoi(r.PathValue("id"))
+		if err != nil {
+			http.Error(w, "product id must be an integer", http.StatusBadRequest)
+			return
+		}
+
+		changes, err := repo.ProductHistory(id)
+		if err != nil {
+			insuranceDBError(w, err)
+			return
+		}
+		if len(changes) == 0 {
+			_, err := repo.Product(id)
+			if errors.Is(err, models.ErrNotFound) {
+				http.Error(w, fmt.Sprintf("product %d not found", id), http.StatusNotFound)
+				return
+			}
+			if err != nil {
+				insuranceDBError(w, err)
+				return
+			}
+			changes = []models.Change{}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(productHistory{ProductID: id, Changes: changes})
+	}
+}
+
+// insuranceReader returns repo, or the tables as they were at ?as_of= when
+// the request has one. On error it has already answered the request.
+func insuranceReader(w http.ResponseWriter, r *http.Request, repo *models.InsuranceRepository) (models.InsuranceReader, bool) {
+	raw := r.URL.Query().Get("as_of")
+	if raw == "" {
+		return repo, true
+	}
+	asOf, err := parseAsOf(raw)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+	snapshot, err := repo.AsOf(asOf)
+	if err != nil {
+		insuranceDBError(w, err)
+		return nil, false
+	}
+	return snapshot, true
+}
+
+// parseAsOf accepts an RFC 3339 time or a date, which means the end of
+// that day in Hong Kong.
+func parseAsOf(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	day, err := time.ParseInLocation("2006-01-02", s, models.HongKong)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("as_of must be a date (2006-01-02) or an RFC 3339 time")
+	}
+	return day.AddDate(0, 0, 1).Add(-time.Nanosecond), nil
+}
@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/vf0429/Petwell_Backend/internal/models"
+	"github.com/vf0429/Petwell_Backend/internal/services/eligibility"
+	"github.com/vf0429/Petwell_Backend/internal/services/payout"
+	"gorm.io/gorm"
+)
+
+// quoteRequest is the pet profile sent to POST /insurance-quotes.
+type quoteRequest struct {
+	PetType    string   `json:"pet_type"`    // "cat" or "dog"
+	PetAge     *float64 `json:"pet_age"`     // in years, e.g. 0.5 for six months
+	Breed      string   `json:"breed"`       // optional; empty means a mixed breed
+	BreedClass string   `json:"breed_class"` // optional override: mixed, purebred, large or any
+	// ScenarioIDs limits the cost-versus-benefit figures to these
+	// scenarios; every scenario is used when empty.
+	ScenarioIDs []string `json:"scenario_ids"`
+}
+
+type quoteResponse struct {
+	PetType    string         `json:"pet_type"`
+	AgeWeeks   int            `json:"age_weeks"`
+	BreedClass string         `json:"breed_class"`
+	Quotes     []payout.Quote `json:"quotes"`
+	Unpriced   []int          `json:"unpriced_product_ids"`
+}
+
+// NewInsuranceQuotesHandler prices every product a pet is eligible for,
+// monthly and annually, and sets a year of premiums against what each
+// product would have paid for the claim scenarios.
+// POST /insurance-quotes
+func NewInsuranceQuotesHandler(repo *models.InsuranceRepository, db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		EnableCors(&w)
+		if r.Method == http.MethodOptions {
+			return
+		}
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req quoteRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		profile := payout.Profile{
+			PetType: eligibility.PetType(strings.ToLower(strings.TrimSpace(req.PetType))),
+			Breed:   req.Breed,
+		}
+		if profile.PetType != eligibility.PetCat && profile.PetType != eligibility.PetDog {
+			http.Error(w, "pet_type must be cat or dog", http.StatusBadRequest)
+			return
+		}
+		if req.PetAge == nil || *req.PetAge < 0 {
+			http.Error(w, "pet_age must be a non-negative number of years", http.StatusBadRequest)
+			return
+		}
+		profile.AgeWeeks = int(*req.PetAge * eligibility.WeeksPerYear)
+		if req.BreedClass != "" {
+			class, ok := eligibility.ParseBreedClass(req.BreedClass)
+			if !ok {
+				http.Error(w, "breed_class must be mixed, purebred, large or any", http.StatusBadRequest)
+				return
+			}
+			profile.BreedClass = class
+		} else {
+			profile.BreedClass = eligibility.ClassifyBreed(profile.PetType, profile.Breed)
+		}
+
+		ids := make([]string, 0, len(req.ScenarioIDs))
+		seen := make(map[string]bool, len(req.ScenarioIDs))
+		for _, id := range req.ScenarioIDs {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+
+		var scenarios []models.Scenario
+		query := db.Preload("CostItems").Order("id")
+		if len(ids) > 0 {
+			query = query.Where("id IN ?", ids)
+		}
+		if err := query.Find(&scenarios).Error; err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if len(scenarios) < len(ids) {
+			http.Error(w, "scenario_ids contains unknown scenarios", http.StatusBadRequest)
+			return
+		}
+
+		quotes, unpriced, err := payout.QuoteProducts(repo, profile, scenarios)
+		if err != nil {
+			insuranceDBError(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(quoteResponse{
+			PetType:    string(profile.PetType),
+			AgeWeeks:   profile.AgeWeeks,
+			BreedClass: string(profile.BreedClass),
+			Quotes:     quotes,
+			Unpriced:   unpriced,
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/vf0429/Petwell_Backend/internal/migrations"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// openScenarioTestDB builds a scenario DB in a temp dir and runs stmts on it.
+func openScenarioTestDB(t *testing.T, stmts ...string) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "scenarios.db")), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+	if _, err := migrations.Scenarios.Up(sqlDB); err != nil {
+		t.Fatal(err)
+	}
+	for _, stmt := range stmts {
+		if err := db.Exec(stmt).Error; err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+	return db
+}
+
+func TestInsuranceQuotes(t *testing.T) {
+	repo := openInsuranceTestRepo(t,
+		"INSERT INTO insurance_provider (company_id, company_name) VALUES (1, 'OneDegree')",
+		"INSERT INTO product (insurance_id, provider_id, insurance_name, suitable_pet_type) VALUES (1, 1, 'Essential Plan', 'cat, dog'), (2, 1, 'Plus Plan', 'cat, dog')",
+		"INSERT INTO coverage_list (coverage_id, coverage_type) VALUES (1, 'Medical Expenses')",
+		"INSERT INTO coverage_limit (coverage_id, product_id, coverage_limit) VALUES (1, 1, '30000'), (1, 2, '60000')",
+		"INSERT INTO premium (product_id, pet_type, min_age_weeks, breed_class, payment_mode, premium_hkd) VALUES (1, 'cat', 0, 'any', 'monthly', 150)",
+	)
+	db := openScenarioTestDB(t,
+		"INSERT INTO scenarios (id, title, total_cost_hkd) VALUES ('case_001', 'Vet visit', 1000)",
+		"INSERT INTO cost_items (id, scenario_id, item_name, amount_hkd) VALUES ('c1', 'case_001', 'consultation', 1000)",
+	)
+	handler := NewInsuranceQuotesHandler(repo, db)
+
+	tests := []struct {
+		name   string
+		method string
+		body   string
+		status int
+		want   string // substring of the response body
+	}{
+		{"get", http.MethodGet, "", http.StatusMethodNotAllowed, "Method not allowed"},
+		{"not json", http.MethodPost, "pet_type=cat", http.StatusBadRequest, "Invalid request body"},
+		{"unknown pet type", http.MethodPost, `{"pet_type": "rabbit", "pet_age": 1}`, http.StatusBadRequest, "pet_type must be cat or dog"},
+		{"missing age", http.MethodPost, `{"pet_type": "cat"}`, http.StatusBadRequest, "pet_age must be a non-negative number of years"},
+		{"negative age", http.MethodPost, `{"pet_type": "cat", "pet_age": -0.5}`, http.StatusBadRequest, "pet_age must be a non-negative number of years"},
+		{"unknown breed class", http.MethodPost, `{"pet_type": "cat", "pet_age": 1, "breed_class": "giant"}`, http.StatusBadRequest, "breed_class must be mixed, purebred, large or any"},
+		{"unknown scenario", http.MethodPost, `{"pet_type": "cat", "pet_age": 1, "scenario_ids": ["case_404"]}`, http.StatusBadRequest, "scenario_ids contains unknown scenarios"},
+		{"quote", http.MethodPost, `{"pet_type": "Cat", "pet_age": 1}`, http.StatusOK, `"unpriced_product_ids":[2]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler(w, httptest.NewRequest(tt.method, "/insurance-quotes", strings.NewReader(tt.body)))
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+			if !strings.Contains(w.Body.String(), tt.want) {
+				t.Errorf("body = %s, want %q", w.Body, tt.want)
+			}
+		})
+	}
+
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodPost, "/insurance-quotes", strings.NewReader(`{"pet_type": "cat", "pet_age": 1}`)))
+	var resp quoteResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Quotes) != 1 || resp.Quotes[0].ProductID != 1 || resp.Quotes[0].AnnualCostHKD != 1800 {
+		t.Fatalf("quotes = %+v, want product 1 at $1800 a year", resp.Quotes)
+	}
+	if got := resp.Quotes[0].Scenarios; len(got) != 1 || got[0].ScenarioID != "case_001" || got[0].EstimatedPayoutHKD == 0 {
+		t.Errorf("scenarios = %+v, want a payout for case_001", got)
+	}
+}
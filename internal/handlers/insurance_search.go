@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/vf0429/Petwell_Backend/internal/models"
+)
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+)
+
+type searchResponse struct {
+	Query string `json:"query"`
+	// Products lists every product a hit belongs to, best hit first.
+	Products []searchProduct    `json:"products"`
+	Hits     []models.SearchHit `json:"hits"`
+}
+
+type searchProduct struct {
+	ProductID int    `json:"product_id"`
+	Link      string `json:"link"`
+}
+
+// NewInsuranceSearchHandler searches product names, remarks and tags,
+// coverage types and limit remarks in English and Chinese. Snippets wrap
+// the matched words in <mark></mark>.
+// GET /insurance/search?q=癌症&limit=20
+func NewInsuranceSearchHandler(repo *models.InsuranceRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		EnableCors(&w)
+		if r.Method == http.MethodOptions {
+			return
+		}
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		q := r.URL.Query().Get("q")
+		limit := defaultSearchLimit
+		if v := r.URL.Query().Get("limit"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 || n > maxSearchLimit {
+				http.Error(w, fmt.Sprintf("limit must be between 1 and %d", maxSearchLimit), http.StatusBadRequest)
+				return
+			}
+			limit = n
+		}
+
+		hits, err := repo.Search(q, limit)
+		if errors.Is(err, models.ErrEmptySearch) {
+			http.Error(w, "q must contain at least one word", http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			insuranceDBError(w, err)
+			return
+		}
+
+		resp := searchResponse{Query: q, Products: []searchProduct{}, Hits: hits}
+		seen := make(map[int]bool)
+		for _, h := range hits {
+			for _, id := range h.ProductIds {
+				if !seen[id] {
+					seen[id] = true
+					resp.Products = append(resp.Products, searchProduct{ProductID: id, Link: fmt.Sprintf("/insurance-products/%d", id)})
+				}
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}
+}
@@ -1,10 +1,16 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/vf0429/Petwell_Backend/internal/config"
 	"github.com/vf0429/Petwell_Backend/internal/models"
+	"github.com/vf0429/Petwell_Backend/internal/services/payout"
 	"gorm.io/gorm"
 )
 
@@ -16,63 +22,79 @@ type ScenarioResponse struct {
 	TotalCostHKD  int                      `json:"total_cost_hkd"`
 	CostBreakdown []models.CostItem        `json:"cost_breakdown"`
 	Payouts       []ScenarioPayoutResponse `json:"payouts"`
+	// LocalCosts is set when the figures were rescaled to a district or clinic.
+	LocalCosts *payout.LocalCosts `json:"local_costs,omitempty"`
 }
 
 type ScenarioPayoutResponse struct {
+	ID                 string  `json:"id"`
 	InsurerID          string  `json:"insurer_id"`
 	InsurerName        string  `json:"insurer_name"`
 	PlanName           string  `json:"plan_name"`
+	CompanyID          *int    `json:"company_id"`
+	ProductID          *int    `json:"product_id"`
+	ProductURL         string  `json:"product_url,omitempty"` // the linked product's detail endpoint
 	EstimatedPayoutHKD int     `json:"estimated_payout_hkd"`
 	CoveragePercentage float64 `json:"coverage_percentage"`
 	Analysis           string  `json:"analysis,omitempty"`
 	IsRecommended      bool    `json:"is_recommended"`
 }
 
-func NewInsuranceV1Handler(db *gorm.DB) *gin.Engine {
+// recomputeRequest is the body of POST /api/v1/scenarios/:id/recompute.
+// Nothing is written unless Apply is set.
+type recomputeRequest struct {
+	Products map[string]int `json:"products"`
+	Apply    bool           `json:"apply"`
+}
+
+func toScenarioResponse(s models.Scenario) ScenarioResponse {
+	payouts := make([]ScenarioPayoutResponse, len(s.Payouts))
+	for i, p := range s.Payouts {
+		payouts[i] = ScenarioPayoutResponse{
+			ID:                 p.ID,
+			InsurerID:          p.InsurerID,
+			InsurerName:        p.Insurer.Name,
+			PlanName:           p.Insurer.PlanName,
+			CompanyID:          p.Insurer.CompanyID,
+			ProductID:          p.Insurer.ProductID,
+			EstimatedPayoutHKD: p.EstimatedPayoutHKD,
+			CoveragePercentage: p.CoveragePercentage,
+			Analysis:           p.Analysis,
+			IsRecommended:      p.IsRecommended,
+		}
+		if p.Insurer.ProductID != nil {
+			payouts[i].ProductURL = fmt.Sprintf("/insurance-products/%d", *p.Insurer.ProductID)
+		}
+	}
+	return ScenarioResponse{
+		ID:            s.ID,
+		Title:         s.Title,
+		Description:   s.Description,
+		TotalCostHKD:  s.TotalCostHKD,
+		CostBreakdown: s.CostItems,
+		Payouts:       payouts,
+	}
+}
+
+func NewInsuranceV1Handler(cfg *config.Config, db *gorm.DB, repo *models.InsuranceRepository) *gin.Engine {
 	r := gin.Default()
 
 	// Scenarios endpoints
 	v1 := r.Group("/scenarios")
 	{
-		v1.GET("", func(c *gin.Context) {
-			var scenarios []models.Scenario
-			result := db.Preload("CostItems").Preload("Payouts").Preload("Payouts.Insurer").Find(&scenarios)
-			if result.Error != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": result.Error.Error()})
-				return
-			}
-
-			var response struct {
-				Scenarios []ScenarioResponse `json:"scenarios"`
-			}
+		v1.GET("", localizeRoute, func(c *gin.Context) {
+			listScenarios(c, db)
+		})
 
-			for _, s := range scenarios {
-				payouts := make([]ScenarioPayoutResponse, len(s.Payouts))
-				for i, p := range s.Payouts {
-					payouts[i] = ScenarioPayoutResponse{
-						InsurerID:          p.InsurerID,
-						InsurerName:        p.Insurer.Name,
-						PlanName:           p.Insurer.PlanName,
-						EstimatedPayoutHKD: p.EstimatedPayoutHKD,
-						CoveragePercentage: p.CoveragePercentage,
-						Analysis:           p.Analysis,
-						IsRecommended:      p.IsRecommended,
-					}
-				}
-				response.Scenarios = append(response.Scenarios, ScenarioResponse{
-					ID:            s.ID,
-					Title:         s.Title,
-					Description:   s.Description,
-					TotalCostHKD:  s.TotalCostHKD,
-					CostBreakdown: s.CostItems,
-					Payouts:       payouts,
-				})
+		// ?district= or ?clinic_id= rescales the costs and payouts by the
+		// cost benchmarks of that district or clinic.
+		v1.GET("/:id", localizeRoute, func(c *gin.Context) {
+			loc := payout.Location{District: strings.TrimSpace(c.Query("district")), ClinicID: strings.TrimSpace(c.Query("clinic_id"))}
+			if loc.District != "" && loc.ClinicID != "" {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Use either district or clinic_id, not both"})
+				return
 			}
 
-			c.JSON(http.StatusOK, response)
-		})
-
-		v1.GET("/:id", func(c *gin.Context) {
 			id := c.Param("id")
 			var s models.Scenario
 			result := db.Preload("CostItems").Preload("Payouts").Preload("Payouts.Insurer").First(&s, "id = ?", id)
@@ -85,36 +107,76 @@ func NewInsuranceV1Handler(db *gorm.DB) *gin.Engine {
 				return
 			}
 
-			payouts := make([]ScenarioPayoutResponse, len(s.Payouts))
-			for i, p := range s.Payouts {
-				payouts[i] = ScenarioPayoutResponse{
-					InsurerID:          p.InsurerID,
-					InsurerName:        p.Insurer.Name,
-					PlanName:           p.Insurer.PlanName,
-					EstimatedPayoutHKD: p.EstimatedPayoutHKD,
-					CoveragePercentage: p.CoveragePercentage,
-					Analysis:           p.Analysis,
-					IsRecommended:      p.IsRecommended,
+			var local *payout.LocalCosts
+			if loc != (payout.Location{}) {
+				var err error
+				local, err = payout.LocalizeCosts(db, repo, &s, loc)
+				if err != nil {
+					c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+					return
 				}
 			}
 
-			response := ScenarioResponse{
-				ID:            s.ID,
-				Title:         s.Title,
-				Description:   s.Description,
-				TotalCostHKD:  s.TotalCostHKD,
-				CostBreakdown: s.CostItems,
-				Payouts:       payouts,
+			response := toScenarioResponse(s)
+			response.LocalCosts = local
+			c.JSON(http.StatusOK, response)
+		})
+
+		// Recalculate the scenario's payouts from the insurance DB. Each insurer's
+		// linked product is used unless the body maps its ID to another
+		// product.insurance_id, e.g. {"products": {"bluecross": 5}}. Admins
+		// only; the results replace the curated payouts only with
+		// {"apply": true}.
+		v1.POST("/:id/recompute", requireAdmin(cfg.AdminTokens), func(c *gin.Context) {
+			var req recomputeRequest
+			if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+				return
 			}
 
-			c.JSON(http.StatusOK, response)
+			id := c.Param("id")
+			results, skipped, err := payout.RecomputeScenario(db, repo, id, req.Products, !req.Apply)
+			if err != nil {
+				if err == payout.ErrScenarioNotFound {
+					c.JSON(http.StatusNotFound, gin.H{"error": "Scenario not found"})
+				} else if errors.Is(err, models.ErrNotFound) {
+					c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				} else if errors.Is(err, payout.ErrNoCoverage) {
+					c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+				} else {
+					c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				}
+				return
+			}
+
+			var s models.Scenario
+			if err := db.Preload("CostItems").Preload("Payouts").Preload("Payouts.Insurer").First(&s, "id = ?", id).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+
+			c.JSON(http.StatusOK, gin.H{
+				"scenario": toScenarioResponse(s),
+				"results":  results,
+				"skipped":  skipped,
+				"applied":  req.Apply,
+			})
 		})
 	}
 
+	// Ad-hoc bill estimates
+	registerEstimateRoutes(r, repo)
+
+	// Insurance data editing, bearer-token protected
+	registerAdminRoutes(r, cfg.AdminTokens, repo)
+
+	// Scenario and insurer authoring, bearer-token protected
+	registerScenarioAdminRoutes(r, cfg.AdminTokens, db, repo)
+
 	// Insurers endpoints
 	insurers := r.Group("/insurers")
 	{
-		insurers.GET("", func(c *gin.Context) {
+		insurers.GET("", localizeRoute, func(c *gin.Context) {
 			var list []models.Insurer
 			result := db.Find(&list)
 			if result.Error != nil {
@@ -123,6 +185,16 @@ func NewInsuranceV1Handler(db *gorm.DB) *gin.Engine {
 			}
 			c.JSON(http.StatusOK, gin.H{"insurers": list})
 		})
+
+		// Insurers without a matching insurance product
+		insurers.GET("/unmatched", localizeRoute, func(c *gin.Context) {
+			issues, err := models.CheckInsurerProducts(db, repo)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"insurers": issues})
+		})
 	}
 
 	return r
@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/vf0429/Petwell_Backend/internal/models"
+	"github.com/vf0429/Petwell_Backend/internal/services/eligibility"
+)
+
+// The legacy endpoints were deprecated on legacyDeprecated and are removed
+// after legacySunset, giving old app builds two release cycles to move on.
+var (
+	legacyDeprecated = time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC)
+	legacySunset     = time.Date(2027, 4, 16, 0, 0, 0, 0, time.UTC)
+)
+
+// legacyUsage counts calls to the legacy endpoints since the server
+// started, so we can tell when the old builds are gone.
+var legacyUsage = &usageCounter{endpoints: make(map[string]*endpointUsage)}
+
+// Clients are counted by User-Agent, at most maxLegacyClients of them per
+// endpoint and each cut to maxUserAgentLen bytes; calls from further
+// clients are counted under otherClients.
+const (
+	maxLegacyClients = 50
+	maxUserAgentLen  = 200
+	otherClients     = "other"
+)
+
+type usageCounter struct {
+	mu        sync.Mutex
+	endpoints map[string]*endpointUsage
+}
+
+// endpointUsage is what GET /api/v1/admin/legacy-usage reports per path.
+type endpointUsage struct {
+	Calls        int            `json:"calls"`
+	LastCalledAt time.Time      `json:"last_called_at"`
+	Clients      map[string]int `json:"clients"` // calls by User-Agent, see maxLegacyClients
+}
+
+func (u *usageCounter) record(r *http.Request) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	e, ok := u.endpoints[r.URL.Path]
+	if !ok {
+		e = &endpointUsage{Clients: make(map[string]int)}
+		u.endpoints[r.URL.Path] = e
+	}
+	e.Calls++
+	e.LastCalledAt = time.Now().UTC()
+	ua := r.UserAgent()
+	if len(ua) > maxUserAgentLen {
+		ua = strings.ToValidUTF8(ua[:maxUserAgentLen], "")
+	}
+	if _, seen := e.Clients[ua]; !seen && len(e.Clients) >= maxLegacyClients {
+		ua = otherClients
+	}
+	e.Clients[ua]++
+}
+
+// snapshot copies the counters so they can be encoded without the lock.
+func (u *usageCounter) snapshot() map[string]endpointUsage {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	out := make(map[string]endpointUsage, len(u.endpoints))
+	for path, e := range u.endpoints {
+		clients := make(map[string]int, len(e.Clients))
+		for ua, n := range e.Clients {
+			clients[ua] = n
+		}
+		out[path] = endpointUsage{Calls: e.Calls, LastCalledAt: e.LastCalledAt, Clients: clients}
+	}
+	return out
+}
+
+// deprecated serves h with Deprecation (RFC 9745) and Sunset (RFC 8594)
+// headers and a Link to the endpoint replacing it, and counts the calls.
+func deprecated(successor string, h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodOptions {
+			legacyUsage.record(r)
+		}
+		w.Header().Set("Deprecation", fmt.Sprintf("@%d", legacyDeprecated.Unix()))
+		w.Header().Set("Sunset", legacySunset.Format(http.TimeFormat))
+		w.Header().Set("Link", fmt.Sprintf(`<%s>; rel="successor-version"`, successor))
+		h(w, r)
+	}
+}
+
+// NewInsuranceProvidersHandler serves the products in the shape of the old
+// pet_insurance_comparison table, one row per plan, for app builds that
+// predate /insurance-products. The cash benefits and categories have no
+// counterpart in the product tables and are left empty.
+// GET /insurance-providers (deprecated)
+func NewInsuranceProvidersHandler(repo *models.InsuranceRepository) http.HandlerFunc {
+	return deprecated("/insurance-products", func(w http.ResponseWriter, r *http.Request) {
+		EnableCors(&w)
+		if r.Method == http.MethodOptions {
+			return
+		}
+
+		companies, err := repo.Companies()
+		if err != nil {
+			insuranceDBError(w, err)
+			return
+		}
+		names := make(map[int]string, len(companies))
+		for _, c := range companies {
+			names[c.CompanyId] = strings.TrimSpace(c.CompanyName)
+		}
+		products, err := repo.Products()
+		if err != nil {
+			insuranceDBError(w, err)
+			return
+		}
+
+		rows := make([]models.PetInsuranceComparison, len(products))
+		for i, p := range products {
+			company, plan := names[p.ProviderId], strings.TrimSpace(p.InsuranceName)
+			rows[i] = models.PetInsuranceComparison{
+				ID:                p.InsuranceId,
+				InsuranceProvider: company + " —— " + plan,
+				ProviderKey:       legacyKey(company + " " + plan),
+			}
+			if schedule, _ := eligibility.ParseCoinsurance(p.Coinsurance.String); len(schedule) > 0 {
+				rows[i].CoveragePercentage = fmt.Sprintf("%g%%", schedule.ReimbursementRate(-1, true)*100)
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(rows)
+	})
+}
+
+// legacyKey turns "One Degree Essential Plan" into "one_degree_essential_plan".
+func legacyKey(s string) string {
+	words := strings.FieldsFunc(strings.ToLower(s), func(c rune) bool {
+		return !unicode.IsLetter(c) && !unicode.IsDigit(c)
+	})
+	return strings.Join(words, "_")
+}
+
+// NewServiceSubcategoriesHandler serves the coverage types in the shape of
+// the old service_subcategories table.
+// GET /service-subcategories (deprecated)
+func NewServiceSubcategoriesHandler(repo *models.InsuranceRepository) http.HandlerFunc {
+	return deprecated("/coverage-list", func(w http.ResponseWriter, r *http.Request) {
+		EnableCors(&w)
+		if r.Method == http.MethodOptions {
+			return
+		}
+
+		list, err := repo.CoverageList()
+		if err != nil {
+			insuranceDBError(w, err)
+			return
+		}
+		rows := make([]models.ServiceSubcategory, len(list))
+		for i, c := range list {
+			rows[i] = models.ServiceSubcategory{ID: c.CoverageId, Name: c.CoverageType, DisplayOrder: i + 1}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(rows)
+	})
+}
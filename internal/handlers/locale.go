@@ -0,0 +1,249 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Locales a client can ask for with ?lang=.
+const (
+	localeEN = "en"
+	localeZH = "zh-HK"
+)
+
+// Localize collapses every "x" / "x_zh" pair of the JSON responses of next
+// into a single "x" in the locale picked with ?lang=, falling back to the
+// other language when the preferred value is null or empty. A lone "x_zh"
+// becomes "x" in Chinese and is dropped in English. Without ?lang= the
+// dual-field format is returned unchanged; Accept-Language is ignored
+// because browsers and apps send it on every request.
+func Localize(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lang, err := requestLocale(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if lang == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		buf := &bufferedResponse{header: w.Header(), status: http.StatusOK}
+		next.ServeHTTP(buf, r)
+		writeLocalized(w, buf.status, buf.body.Bytes(), lang)
+	})
+}
+
+// localizeRoute is Localize as Gin middleware. It goes on the public read
+// routes only, so admin responses keep their fields as stored.
+func localizeRoute(c *gin.Context) {
+	lang, err := requestLocale(c.Request)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if lang == "" {
+		c.Next()
+		return
+	}
+
+	w := c.Writer
+	buf := &bufferedGinResponse{ResponseWriter: w, status: http.StatusOK}
+	c.Writer = buf
+	c.Next()
+	c.Writer = w
+	writeLocalized(w, buf.status, buf.body.Bytes(), lang)
+}
+
+// requestLocale returns the locale asked for with ?lang=, or "" when the
+// parameter is missing or empty.
+func requestLocale(r *http.Request) (string, error) {
+	param := strings.TrimSpace(r.URL.Query().Get("lang"))
+	lang := matchLocale(param)
+	if lang == "" && param != "" {
+		return "", errors.New("lang must be en or zh-HK")
+	}
+	return lang, nil
+}
+
+// writeLocalized writes a held-back response, localizing JSON bodies.
+func writeLocalized(w http.ResponseWriter, status int, body []byte, lang string) {
+	if strings.HasPrefix(w.Header().Get("Content-Type"), "application/json") {
+		if out, err := localizeJSON(body, lang); err == nil {
+			body = out
+			w.Header().Set("Content-Language", lang)
+		}
+	}
+	w.Header().Del("Content-Length")
+	w.WriteHeader(status)
+	w.Write(body)
+}
+
+// bufferedResponse holds a response back so its body can be rewritten.
+type bufferedResponse struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (b *bufferedResponse) Header() http.Header         { return b.header }
+func (b *bufferedResponse) WriteHeader(status int)      { b.status = status }
+func (b *bufferedResponse) Write(p []byte) (int, error) { return b.body.Write(p) }
+
+// bufferedGinResponse is bufferedResponse for Gin handlers.
+type bufferedGinResponse struct {
+	gin.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (b *bufferedGinResponse) WriteHeader(status int)            { b.status = status }
+func (b *bufferedGinResponse) WriteHeaderNow()                   {}
+func (b *bufferedGinResponse) Write(p []byte) (int, error)       { return b.body.Write(p) }
+func (b *bufferedGinResponse) WriteString(s string) (int, error) { return b.body.WriteString(s) }
+func (b *bufferedGinResponse) Status() int                       { return b.status }
+func (b *bufferedGinResponse) Size() int                         { return b.body.Len() }
+func (b *bufferedGinResponse) Written() bool                     { return b.body.Len() > 0 }
+
+// matchLocale maps a language tag to a supported locale, or "".
+func matchLocale(tag string) string {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	switch {
+	case tag == "en" || strings.HasPrefix(tag, "en-"):
+		return localeEN
+	case tag == "zh" || strings.HasPrefix(tag, "zh-"):
+		return localeZH
+	}
+	return ""
+}
+
+// jsonObject is a decoded JSON object that keeps its key order.
+type jsonObject []jsonMember
+
+type jsonMember struct {
+	key   string
+	value interface{}
+}
+
+func (o jsonObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, m := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(m.key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(m.value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func localizeJSON(body []byte, lang string) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	v, err := decodeOrdered(dec)
+	if err != nil {
+		return nil, err
+	}
+	out, err := json.Marshal(localizeValue(v, lang))
+	if err != nil {
+		return nil, err
+	}
+	return append(out, '\n'), nil
+}
+
+// decodeOrdered decodes the next value of dec, reading objects into
+// jsonObject so the response keeps its field order.
+func decodeOrdered(dec *json.Decoder) (interface{}, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch tok {
+	case json.Delim('{'):
+		obj := jsonObject{}
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := decodeOrdered(dec)
+			if err != nil {
+				return nil, err
+			}
+			obj = append(obj, jsonMember{key: key.(string), value: value})
+		}
+		_, err := dec.Token()
+		return obj, err
+	case json.Delim('['):
+		arr := []interface{}{}
+		for dec.More() {
+			value, err := decodeOrdered(dec)
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, value)
+		}
+		_, err := dec.Token()
+		return arr, err
+	}
+	return tok, nil
+}
+
+func localizeValue(v interface{}, lang string) interface{} {
+	switch t := v.(type) {
+	case jsonObject:
+		return localizeObject(t, lang)
+	case []interface{}:
+		for i := range t {
+			t[i] = localizeValue(t[i], lang)
+		}
+	}
+	return v
+}
+
+func localizeObject(obj jsonObject, lang string) jsonObject {
+	index := make(map[string]int, len(obj))
+	for i, m := range obj {
+		index[m.key] = i
+	}
+
+	out := make(jsonObject, 0, len(obj))
+	for _, m := range obj {
+		m.value = localizeValue(m.value, lang)
+		if base, ok := strings.CutSuffix(m.key, "_zh"); ok {
+			if _, paired := index[base]; !paired && lang == localeZH {
+				out = append(out, jsonMember{key: base, value: m.value})
+			}
+			continue
+		}
+		if i, ok := index[m.key+"_zh"]; ok {
+			zh := localizeValue(obj[i].value, lang)
+			if missing(m.value) || lang == localeZH && !missing(zh) {
+				m.value = zh
+			}
+		}
+		out = append(out, m)
+	}
+	return out
+}
+
+func missing(v interface{}) bool {
+	return v == nil || v == ""
+}
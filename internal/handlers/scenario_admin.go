@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vf0429/Petwell_Backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// registerScenarioAdminRoutes mounts the authenticated write endpoints for
+// scenarios, their cost items and payouts, and insurers next to the public
+// GETs. Scenario writes answer with the whole scenario as GET
+// /scenarios/:id returns it; ids in the path win over ids in the body.
+func registerScenarioAdminRoutes(r *gin.Engine, tokens map[string]string, db *gorm.DB, repo *models.InsuranceRepository) {
+	scenarios := r.Group("/scenarios", requireAdmin(tokens))
+
+	// respond answers with scenario id as it is after a write.
+	respond := func(c *gin.Context, status int, id string) {
+		s, err := models.LoadScenario(db, id)
+		if err != nil {
+			adminError(c, err)
+			return
+		}
+		c.JSON(status, toScenarioResponse(*s))
+	}
+
+	// Scenarios, written whole with their cost_breakdown and payouts
+	scenarios.POST("", func(c *gin.Context) {
+		var s models.Scenario
+		if !bindBody(c, &s) {
+			return
+		}
+		s.CreatedAt, s.UpdatedAt = time.Time{}, time.Time{}
+		if err := models.CreateScenario(db, &s); err != nil {
+			adminError(c, err)
+			return
+		}
+		respond(c, http.StatusCreated, s.ID)
+	})
+	scenarios.PUT("/:id", func(c *gin.Context) {
+		var s models.Scenario
+		if !bindBody(c, &s) {
+			return
+		}
+		s.ID = c.Param("id")
+		if err := models.UpdateScenario(db, &s); err != nil {
+			adminError(c, err)
+			return
+		}
+		respond(c, http.StatusOK, s.ID)
+	})
+	scenarios.DELETE("/:id", func(c *gin.Context) {
+		if err := models.DeleteScenario(db, c.Param("id")); err != nil {
+			adminError(c, err)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+
+	// Cost items; total_cost_hkd follows their amounts
+	scenarios.POST("/:id/cost-items", func(c *gin.Context) {
+		var item models.CostItem
+		if !bindBody(c, &item) {
+			return
+		}
+		item.ScenarioID = c.Param("id")
+		if err := models.CreateCostItem(db, &item); err != nil {
+			adminError(c, err)
+			return
+		}
+		respond(c, http.StatusCreated, item.ScenarioID)
+	})
+	scenarios.PUT("/:id/cost-items/:item_id", func(c *gin.Context) {
+		var item models.CostItem
+		if !bindBody(c, &item) {
+			return
+		}
+		item.ID, item.ScenarioID = c.Param("item_id"), c.Param("id")
+		if err := models.UpdateCostItem(db, &item); err != nil {
+			adminError(c, err)
+			return
+		}
+		respond(c, http.StatusOK, item.ScenarioID)
+	})
+	scenarios.DELETE("/:id/cost-items/:item_id", func(c *gin.Context) {
+		if err := models.DeleteCostItem(db, c.Param("id"), c.Param("item_id")); err != nil {
+			adminError(c, err)
+			return
+		}
+		respond(c, http.StatusOK, c.Param("id"))
+	})
+
+	// Payouts; recommending one un-recommends the rest
+	scenarios.POST("/:id/payouts", func(c *gin.Context) {
+		var p models.Payout
+		if !bindBody(c, &p) {
+			return
+		}
+		p.ScenarioID = c.Param("id")
+		if err := models.CreatePayout(db, &p); err != nil {
+			adminError(c, err)
+			return
+		}
+		respond(c, http.StatusCreated, p.ScenarioID)
+	})
+	scenarios.PUT("/:id/payouts/:payout_id", func(c *gin.Context) {
+		var p models.Payout
+		if !bindBody(c, &p) {
+			return
+		}
+		p.ID, p.ScenarioID = c.Param("payout_id"), c.Param("id")
+		if err := models.UpdatePayout(db, &p); err != nil {
+			adminError(c, err)
+			return
+		}
+		respond(c, http.StatusOK, p.ScenarioID)
+	})
+	scenarios.DELETE("/:id/payouts/:payout_id", func(c *gin.Context) {
+		if err := models.DeletePayout(db, c.Param("id"), c.Param("payout_id")); err != nil {
+			adminError(c, err)
+			return
+		}
+		respond(c, http.StatusOK, c.Param("id"))
+	})
+
+	// Insurers; product_id links one to an insurance product, and
+	// company_id follows from it
+	insurers := r.Group("/insurers", requireAdmin(tokens))
+	insurers.POST("", func(c *gin.Context) {
+		var i models.Insurer
+		if !bindBody(c, &i) {
+			return
+		}
+		if err := models.CreateInsurer(db, repo, &i); err != nil {
+			adminError(c, err)
+			return
+		}
+		c.JSON(http.StatusCreated, i)
+	})
+	insurers.PUT("/:id", func(c *gin.Context) {
+		var i models.Insurer
+		if !bindBody(c, &i) {
+			return
+		}
+		i.ID = c.Param("id")
+		if err := models.UpdateInsurer(db, repo, &i); err != nil {
+			adminError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, i)
+	})
+	insurers.DELETE("/:id", func(c *gin.Context) {
+		if err := models.DeleteInsurer(db, c.Param("id")); err != nil {
+			adminError(c, err)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+}
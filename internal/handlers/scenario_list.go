@@ -0,0 +1,257 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vf0429/Petwell_Backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// Page sizes of GET /api/v1/scenarios.
+const (
+	defaultScenarioLimit = 50
+	maxScenarioLimit     = 200
+)
+
+// scenarioFieldNames are the fields= values, in response order.
+var scenarioFieldNames = []string{"id", "title", "description", "total_cost_hkd", "cost_breakdown", "payouts"}
+
+// scenarioCursor is where the previous page ended: the sort key and id of
+// its last scenario. It is sent to clients as opaque base64.
+type scenarioCursor struct {
+	Sort string  `json:"s"`
+	Key  float64 `json:"k"`
+	ID   string  `json:"id"`
+}
+
+func (c scenarioCursor) encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeScenarioCursor(s string) (scenarioCursor, bool) {
+	var c scenarioCursor
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || json.Unmarshal(b, &c) != nil {
+		return c, false
+	}
+	return c, true
+}
+
+// scenarioSort is a parsed sort= value: cost, payout_ratio:<insurer> or
+// neither (by id), ascending unless prefixed with "-".
+type scenarioSort struct {
+	raw     string
+	key     string // SQL expression of the sort key; empty sorts by id
+	desc    bool
+	insurer string
+}
+
+func parseScenarioSort(s string) (scenarioSort, bool) {
+	sort := scenarioSort{raw: s}
+	s, sort.desc = strings.CutPrefix(s, "-")
+	field, insurer, _ := strings.Cut(s, ":")
+	switch {
+	case field == "" || field == "id":
+		return sort, insurer == "" && !sort.desc
+	case field == "cost" && insurer == "":
+		sort.key = "scenarios.total_cost_hkd"
+	case field == "payout_ratio" && insurer != "":
+		// Scenarios the insurer pays nothing for rank as a ratio of 0.
+		sort.insurer = insurer
+		sort.key = "COALESCE(CAST(sort_payout.estimated_payout_hkd AS REAL) / NULLIF(scenarios.total_cost_hkd, 0), 0)"
+	default:
+		return sort, false
+	}
+	return sort, true
+}
+
+// listScenarios serves GET /api/v1/scenarios:
+//
+//	insurer=a,b       scenarios with a payout from any of the insurers
+//	recommended=a,b   scenarios recommending one of the insurers
+//	min_cost, max_cost  total cost range in HKD, inclusive
+//	sort=cost|-cost|payout_ratio:<insurer>|-payout_ratio:<insurer>
+//	fields=id,title,...  response fields; cost items and payouts are only
+//	                  loaded when asked for
+//	limit, cursor     page size and the next_cursor of the previous page
+func listScenarios(c *gin.Context, db *gorm.DB) {
+	badRequest := func(msg string) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+	}
+
+	sort, ok := parseScenarioSort(c.Query("sort"))
+	if !ok {
+		badRequest("sort must be cost, payout_ratio:{insurer_id}, optionally prefixed with -")
+		return
+	}
+	limit := defaultScenarioLimit
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxScenarioLimit {
+			badRequest("limit must be between 1 and " + strconv.Itoa(maxScenarioLimit))
+			return
+		}
+		limit = n
+	}
+	fields := scenarioFieldNames
+	if v := c.Query("fields"); v != "" {
+		fields = []string{"id"}
+		for _, f := range strings.Split(v, ",") {
+			f = strings.TrimSpace(f)
+			if !containsString(scenarioFieldNames, f) {
+				badRequest("fields must be a list of " + strings.Join(scenarioFieldNames, ", "))
+				return
+			}
+			if !containsString(fields, f) {
+				fields = append(fields, f)
+			}
+		}
+	}
+
+	// The page is picked on ids and sort keys alone; the scenarios are
+	// then loaded with only the associations fields asks for.
+	sortKey := "0"
+	if sort.key != "" {
+		sortKey = sort.key
+	}
+	query := db.Table("scenarios").Select("scenarios.id, " + sortKey + " AS sort_key")
+	if ids := splitList(c.Query("insurer")); len(ids) > 0 {
+		query = query.Where("EXISTS (SELECT 1 FROM payouts WHERE payouts.scenario_id = scenarios.id AND payouts.insurer_id IN ?)", ids)
+	}
+	if ids := splitList(c.Query("recommended")); len(ids) > 0 {
+		query = query.Where("EXISTS (SELECT 1 FROM payouts WHERE payouts.scenario_id = scenarios.id AND payouts.is_recommended AND payouts.insurer_id IN ?)", ids)
+	}
+	for _, bound := range []struct{ param, op string }{{"min_cost", ">="}, {"max_cost", "<="}} {
+		v := c.Query(bound.param)
+		if v == "" {
+			continue
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			badRequest(bound.param + " must be a whole number of HKD")
+			return
+		}
+		query = query.Where("scenarios.total_cost_hkd "+bound.op+" ?", n)
+	}
+	if sort.insurer != "" {
+		query = query.Joins("LEFT JOIN payouts AS sort_payout ON sort_payout.scenario_id = scenarios.id AND sort_payout.insurer_id = ?", sort.insurer)
+	}
+
+	// Keyset pagination: ties on the sort key are broken by id.
+	if v := c.Query("cursor"); v != "" {
+		cur, ok := decodeScenarioCursor(v)
+		if !ok || cur.Sort != sort.raw {
+			badRequest("cursor is invalid or from a different sort")
+			return
+		}
+		if sort.key == "" {
+			query = query.Where("scenarios.id > ?", cur.ID)
+		} else {
+			op := ">"
+			if sort.desc {
+				op = "<"
+			}
+			query = query.Where("("+sort.key+" "+op+" ? OR ("+sort.key+" = ? AND scenarios.id > ?))", cur.Key, cur.Key, cur.ID)
+		}
+	}
+	if sort.desc {
+		query = query.Order(sort.key + " DESC")
+	} else if sort.key != "" {
+		query = query.Order(sort.key)
+	}
+
+	var page []struct {
+		ID      string
+		SortKey float64
+	}
+	if err := query.Order("scenarios.id").Limit(limit + 1).Scan(&page).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	var nextCursor string
+	if len(page) > limit {
+		last := page[limit-1]
+		nextCursor = scenarioCursor{Sort: sort.raw, Key: last.SortKey, ID: last.ID}.encode()
+		page = page[:limit]
+	}
+
+	ids := make([]string, len(page))
+	for i, p := range page {
+		ids[i] = p.ID
+	}
+	load := db.Where("id IN ?", ids)
+	if containsString(fields, "cost_breakdown") {
+		load = load.Preload("CostItems")
+	}
+	if containsString(fields, "payouts") {
+		load = load.Preload("Payouts").Preload("Payouts.Insurer")
+	}
+	var scenarios []models.Scenario
+	if len(ids) > 0 {
+		if err := load.Find(&scenarios).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	byID := make(map[string]models.Scenario, len(scenarios))
+	for _, s := range scenarios {
+		byID[s.ID] = s
+	}
+
+	response := struct {
+		Scenarios  []jsonObject `json:"scenarios"`
+		NextCursor string       `json:"next_cursor,omitempty"`
+	}{Scenarios: make([]jsonObject, 0, len(ids)), NextCursor: nextCursor}
+	for _, id := range ids {
+		if s, ok := byID[id]; ok {
+			response.Scenarios = append(response.Scenarios, scenarioFields(toScenarioResponse(s), fields))
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// scenarioFields keeps the named fields of s, in response order.
+func scenarioFields(s ScenarioResponse, fields []string) jsonObject {
+	values := map[string]interface{}{
+		"id":             s.ID,
+		"title":          s.Title,
+		"description":    s.Description,
+		"total_cost_hkd": s.TotalCostHKD,
+		"cost_breakdown": s.CostBreakdown,
+		"payouts":        s.Payouts,
+	}
+	obj := make(jsonObject, 0, len(fields))
+	for _, name := range scenarioFieldNames {
+		if containsString(fields, name) {
+			obj = append(obj, jsonMember{key: name, value: values[name]})
+		}
+	}
+	return obj
+}
+
+// splitList splits a comma-separated query value, dropping empty items.
+func splitList(s string) []string {
+	var out []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
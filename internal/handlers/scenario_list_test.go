@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestListScenarios(t *testing.T) {
+	db := openScenarioTestDB(t,
+		`INSERT INTO scenarios (id, title, total_cost_hkd) VALUES
+			('a', 'Ear infection', 1000), ('b', 'Fracture', 3000), ('c', 'Dental', 2000), ('d', 'Tumour', 3000), ('e', 'Vaccination', 500)`,
+		"INSERT INTO insurers (id, name, plan_name) VALUES ('x', 'Insurer X', 'Plan X')",
+		`INSERT INTO payouts (id, scenario_id, insurer_id, estimated_payout_hkd, coverage_percentage, is_recommended) VALUES
+			('p1', 'a', 'x', 800, 80, false), ('p2', 'b', 'x', 1500, 50, true)`,
+	)
+	r := gin.New()
+	r.GET("/scenarios", func(c *gin.Context) { listScenarios(c, db) })
+
+	type page struct {
+		Scenarios  []map[string]interface{} `json:"scenarios"`
+		NextCursor string                   `json:"next_cursor"`
+	}
+	get := func(t *testing.T, query url.Values) (int, page, string) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/scenarios?"+query.Encode(), nil))
+		var p page
+		if w.Code == http.StatusOK {
+			if err := json.Unmarshal(w.Body.Bytes(), &p); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return w.Code, p, w.Body.String()
+	}
+
+	// Following next_cursor visits every scenario once, in order.
+	for _, tt := range []struct {
+		query url.Values
+		want  []string
+	}{
+		{url.Values{"limit": {"2"}}, []string{"a", "b", "c", "d", "e"}},
+		{url.Values{"limit": {"2"}, "sort": {"-cost"}}, []string{"b", "d", "c", "a", "e"}},
+		{url.Values{"limit": {"3"}, "sort": {"cost"}}, []string{"e", "a", "c", "b", "d"}},
+		{url.Values{"limit": {"1"}, "sort": {"-payout_ratio:x"}}, []string{"a", "b", "c", "d", "e"}},
+		{url.Values{"insurer": {"x"}}, []string{"a", "b"}},
+		{url.Values{"recommended": {"x,y"}}, []string{"b"}},
+		{url.Values{"min_cost": {"1000"}, "max_cost": {"2000"}}, []string{"a", "c"}},
+	} {
+		var got []string
+		query := tt.query
+		for pages := 0; pages < 10; pages++ {
+			status, p, body := get(t, query)
+			if status != http.StatusOK {
+				t.Fatalf("%s: status = %d: %s", query.Encode(), status, body)
+			}
+			for _, s := range p.Scenarios {
+				got = append(got, s["id"].(string))
+			}
+			if p.NextCursor == "" {
+				break
+			}
+			query = url.Values{"cursor": {p.NextCursor}}
+			for k, v := range tt.query {
+				query[k] = v
+			}
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: scenarios = %v, want %v", tt.query.Encode(), got, tt.want)
+		}
+	}
+
+	// fields= keeps only the named fields and the id.
+	_, p, _ := get(t, url.Values{"fields": {"title"}, "limit": {"1"}})
+	if want := (map[string]interface{}{"id": "a", "title": "Ear infection"}); len(p.Scenarios) != 1 || !reflect.DeepEqual(p.Scenarios[0], want) {
+		t.Errorf("fields=title: scenarios = %v, want [%v]", p.Scenarios, want)
+	}
+
+	_, first, _ := get(t, url.Values{"limit": {"2"}, "sort": {"cost"}})
+	for _, query := range []url.Values{
+		{"sort": {"name"}},
+		{"sort": {"-id"}},
+		{"sort": {"payout_ratio"}},
+		{"limit": {"0"}},
+		{"limit": {"201"}},
+		{"fields": {"title,secret"}},
+		{"min_cost": {"cheap"}},
+		{"cursor": {"not a cursor"}},
+		{"cursor": {first.NextCursor}, "sort": {"-cost"}},
+	} {
+		if status, _, body := get(t, query); status != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400: %s", query.Encode(), status, body)
+		}
+	}
+}
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS sub_coverage_limit;
+DROP TABLE IF EXISTS coverage_limit;
+DROP TABLE IF EXISTS product;
+DROP TABLE IF EXISTS coverage_list;
+DROP TABLE IF EXISTS insurance_provider;
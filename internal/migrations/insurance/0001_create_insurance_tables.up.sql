@@ -0,0 +1,64 @@
+-- The insurance tables as the old Python build scripts left them. Existing
+-- databases keep their tables; new ones get foreign keys declared.
+CREATE TABLE IF NOT EXISTS insurance_provider (
+	company_id INTEGER PRIMARY KEY,
+	company_name TEXT NOT NULL,
+	company_name_zh TEXT,
+	company_logo TEXT
+);
+
+CREATE TABLE IF NOT EXISTS coverage_list (
+	coverage_id INTEGER PRIMARY KEY,
+	coverage_type TEXT NOT NULL,
+	coverage_type_zh TEXT
+);
+
+CREATE TABLE IF NOT EXISTS product (
+	insurance_id INTEGER PRIMARY KEY,
+	provider_id INTEGER NOT NULL REFERENCES insurance_provider(company_id),
+	insurance_name TEXT NOT NULL,
+	insurance_name_zh TEXT,
+	remark TEXT,
+	remark_zh TEXT,
+	min_age TEXT,
+	min_age_zh TEXT,
+	max_age TEXT,
+	max_age_zh TEXT,
+	coinsurance TEXT,
+	coinsurance_zh TEXT,
+	suitable_pet_type TEXT,
+	suitable_pet_type_zh TEXT,
+	cat_breed_type TEXT,
+	cat_breed_type_zh TEXT,
+	dog_breed_type TEXT,
+	dog_breed_type_zh TEXT,
+	breed_type_remark TEXT,
+	breed_type_remark_zh TEXT,
+	payment_mode TEXT,
+	payment_mode_zh TEXT,
+	waiting_period TEXT,
+	waiting_period_zh TEXT,
+	information_link TEXT,
+	information_link_zh TEXT,
+	update_time TEXT
+);
+
+CREATE TABLE IF NOT EXISTS coverage_limit (
+	coverage_id INTEGER NOT NULL REFERENCES coverage_list(coverage_id),
+	product_id INTEGER NOT NULL REFERENCES product(insurance_id),
+	coverage_limit TEXT,
+	remark TEXT,
+	remark_zh TEXT,
+	PRIMARY KEY (coverage_id, product_id)
+);
+
+CREATE TABLE IF NOT EXISTS sub_coverage_limit (
+	sub_coverage_id INTEGER PRIMARY KEY,
+	parent_coverage_id INTEGER NOT NULL REFERENCES coverage_list(coverage_id),
+	product_id INTEGER NOT NULL REFERENCES product(insurance_id),
+	sub_coverage_name TEXT,
+	sub_coverage_name_zh TEXT,
+	sub_limit TEXT,
+	sub_coverage_remark TEXT,
+	sub_coverage_remark_zh TEXT
+);
@@ -0,0 +1,2 @@
+ALTER TABLE product DROP COLUMN tag_zh;
+ALTER TABLE product DROP COLUMN tag;
@@ -0,0 +1,2 @@
+ALTER TABLE product ADD COLUMN tag TEXT;
+ALTER TABLE product ADD COLUMN tag_zh TEXT;
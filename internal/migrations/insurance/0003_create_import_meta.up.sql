@@ -0,0 +1,5 @@
+-- Written by cmd/import_insurance: source_version and imported_at.
+CREATE TABLE IF NOT EXISTS import_meta (
+	key TEXT PRIMARY KEY,
+	value TEXT
+);
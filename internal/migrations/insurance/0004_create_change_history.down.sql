@@ -0,0 +1 @@
+DROP TABLE change_history;
@@ -0,0 +1,17 @@
+-- One row per change to a product, coverage_limit or sub_coverage_limit
+-- row. old_value/new_value hold the row as JSON; old_value is NULL for
+-- inserts and new_value for deletes. changed_at is UTC in a fixed-width
+-- format so it sorts as text.
+CREATE TABLE change_history (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	table_name TEXT NOT NULL,
+	record_key TEXT NOT NULL,
+	product_id INTEGER NOT NULL,
+	action TEXT NOT NULL,
+	old_value TEXT,
+	new_value TEXT,
+	changed_by TEXT NOT NULL,
+	changed_at TEXT NOT NULL
+);
+CREATE INDEX change_history_product ON change_history (product_id, changed_at);
+CREATE INDEX change_history_changed_at ON change_history (changed_at);
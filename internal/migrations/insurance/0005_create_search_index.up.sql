@@ -0,0 +1,19 @@
+-- Full-text index over product, coverage and limit text, rebuilt by the
+-- application whenever the data changes. body holds the text with every
+-- CJK character split into its own token; text is the original for
+-- snippets. FTS4 is compiled into go-sqlite3 by default, so no build tag
+-- is needed.
+CREATE VIRTUAL TABLE search_index USING fts4(
+	body,
+	source,
+	field,
+	record_key,
+	product_ids,
+	text,
+	notindexed=source,
+	notindexed=field,
+	notindexed=record_key,
+	notindexed=product_ids,
+	notindexed=text,
+	tokenize=unicode61 "remove_diacritics=2"
+);
@@ -0,0 +1,11 @@
+ALTER TABLE product ADD COLUMN tag TEXT;
+ALTER TABLE product ADD COLUMN tag_zh TEXT;
+
+UPDATE product SET
+	tag = (SELECT group_concat('#' || t.slug, ' ') FROM product_tag pt JOIN tag t ON t.tag_id = pt.tag_id
+		WHERE pt.product_id = product.insurance_id),
+	tag_zh = (SELECT group_concat('#' || t.label_zh, ' ') FROM product_tag pt JOIN tag t ON t.tag_id = pt.tag_id
+		WHERE pt.product_id = product.insurance_id AND t.label_zh IS NOT NULL);
+
+DROP TABLE product_tag;
+DROP TABLE tag;
@@ -0,0 +1,39 @@
+-- Product tags move from the "#A #B" strings in product.tag/tag_zh to a
+-- tag table with bilingual labels and a product_tag join table. Existing
+-- tags keep their slug as the label until the sources provide one.
+CREATE TABLE tag (
+	tag_id INTEGER PRIMARY KEY AUTOINCREMENT,
+	slug TEXT NOT NULL UNIQUE,
+	label TEXT NOT NULL,
+	label_zh TEXT,
+	display_order INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE TABLE product_tag (
+	product_id INTEGER NOT NULL REFERENCES product(insurance_id) ON DELETE CASCADE,
+	tag_id INTEGER NOT NULL REFERENCES tag(tag_id) ON DELETE CASCADE,
+	PRIMARY KEY (product_id, tag_id)
+);
+CREATE INDEX product_tag_tag ON product_tag (tag_id);
+
+CREATE TEMP TABLE split_tags AS
+WITH RECURSIVE split(product_id, pos, rest, slug) AS (
+	SELECT insurance_id, 0, trim(replace(replace(tag, '#', ' '), ',', ' ')) || ' ', ''
+	FROM product WHERE tag IS NOT NULL
+	UNION ALL
+	SELECT product_id, pos + 1, ltrim(substr(rest, instr(rest, ' ') + 1)), substr(rest, 1, instr(rest, ' ') - 1)
+	FROM split WHERE rest <> ''
+)
+SELECT product_id, pos, slug FROM split WHERE slug <> '';
+
+INSERT INTO tag (slug, label, display_order)
+SELECT slug, slug, row_number() OVER (ORDER BY min(product_id * 1000 + pos))
+FROM split_tags GROUP BY slug ORDER BY min(product_id * 1000 + pos);
+
+INSERT OR IGNORE INTO product_tag (product_id, tag_id)
+SELECT s.product_id, t.tag_id FROM split_tags s JOIN tag t ON t.slug = s.slug;
+
+DROP TABLE split_tags;
+
+ALTER TABLE product DROP COLUMN tag;
+ALTER TABLE product DROP COLUMN tag_zh;
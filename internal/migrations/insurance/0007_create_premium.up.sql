@@ -0,0 +1,17 @@
+-- Premium tables: the price of a product for a pet type, age band and
+-- breed class under one payment mode. Ages are in weeks like the parsed
+-- eligibility rules; max_age_weeks is inclusive and NULL for no upper
+-- bound. Rows with breed_class 'any' apply when a product doesn't price
+-- the pet's breed class separately.
+CREATE TABLE premium (
+	premium_id INTEGER PRIMARY KEY AUTOINCREMENT,
+	product_id INTEGER NOT NULL REFERENCES product(insurance_id) ON DELETE CASCADE,
+	pet_type TEXT NOT NULL CHECK (pet_type IN ('cat', 'dog')),
+	min_age_weeks INTEGER NOT NULL DEFAULT 0,
+	max_age_weeks INTEGER,
+	breed_class TEXT NOT NULL DEFAULT 'any' CHECK (breed_class IN ('any', 'mixed', 'purebred', 'large')),
+	payment_mode TEXT NOT NULL CHECK (payment_mode IN ('monthly', 'annual')),
+	premium_hkd REAL NOT NULL CHECK (premium_hkd >= 0),
+	UNIQUE (product_id, pet_type, min_age_weeks, breed_class, payment_mode)
+);
+CREATE INDEX premium_product ON premium (product_id);
@@ -0,0 +1,11 @@
+-- Going back to the FTS5 table needs a build with -tags sqlite_fts5.
+DROP TABLE search_index;
+CREATE VIRTUAL TABLE search_index USING fts5(
+	body,
+	source UNINDEXED,
+	field UNINDEXED,
+	record_key UNINDEXED,
+	product_ids UNINDEXED,
+	text UNINDEXED,
+	tokenize = 'unicode61 remove_diacritics 2'
+);
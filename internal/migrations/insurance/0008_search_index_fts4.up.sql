@@ -0,0 +1,20 @@
+-- Databases migrated while 0005 created an FTS5 table get the FTS4 one
+-- instead; the index is refilled by the next import or admin write. The
+-- old table can only be dropped by a build that has FTS5 (-tags
+-- sqlite_fts5). Databases that already have the FTS4 table record this
+-- migration without running it.
+DROP TABLE search_index;
+CREATE VIRTUAL TABLE search_index USING fts4(
+	body,
+	source,
+	field,
+	record_key,
+	product_ids,
+	text,
+	notindexed=source,
+	notindexed=field,
+	notindexed=record_key,
+	notindexed=product_ids,
+	notindexed=text,
+	tokenize=unicode61 "remove_diacritics=2"
+);
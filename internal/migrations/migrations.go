@@ -0,0 +1,311 @@
+// Package migrations holds the numbered schema migrations of the two
+// SQLite databases: the scenario DB the /api/v1 router and the clinic
+// directory use through GORM and the insurance product DB. Each database records what it has applied
+// in its own schema_migrations table.
+package migrations
+
+import (
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed scenarios/*.sql insurance/*.sql
+var files embed.FS
+
+// Migration is one numbered pair of up/down scripts.
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Set is the ordered migrations of one database.
+type Set struct {
+	Name       string
+	Migrations []Migration
+
+	// present holds, by version, a query returning true when a database
+	// already has that migration's changes without having recorded it.
+	present map[int]string
+}
+
+// presentQueries are the present checks of each set. Insurance DBs built by
+// the old Python scripts (update_tags.py) or by earlier importers already
+// have product.tag and product.tag_zh, and only DBs whose 0005 created an
+// FTS5 search index need 0008.
+var presentQueries = map[string]map[int]string{
+	"insurance": {
+		2: "SELECT count(*) = 2 FROM pragma_table_info('product') WHERE name IN ('tag', 'tag_zh')",
+		8: "SELECT count(*) = 1 FROM sqlite_master WHERE name = 'search_index' AND sql LIKE '%USING fts4%'",
+	},
+}
+
+var (
+	// Scenarios migrates the scenario DB (scenarios, cost_items, insurers,
+	// payouts, cost_benchmarks and clinics).
+	Scenarios = mustLoad("scenarios")
+	// Insurance migrates the insurance product DB.
+	Insurance = mustLoad("insurance")
+)
+
+// Sets lists every database's migrations, in the order commands run them.
+var Sets = []*Set{Scenarios, Insurance}
+
+var fileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// mustLoad reads dir/NNNN_name.up.sql and its .down.sql counterpart. The
+// numbers must start at 1 and have no gaps.
+func mustLoad(dir string) *Set {
+	entries, err := fs.ReadDir(files, dir)
+	if err != nil {
+		panic(err)
+	}
+	byVersion := make(map[int]*Migration)
+	for _, e := range entries {
+		m := fileName.FindStringSubmatch(e.Name())
+		if m == nil {
+			panic(fmt.Sprintf("migrations: unexpected file %s/%s", dir, e.Name()))
+		}
+		version, _ := strconv.Atoi(m[1])
+		data, err := files.ReadFile(path.Join(dir, e.Name()))
+		if err != nil {
+			panic(err)
+		}
+		mig, ok := byVersion[version]
+		if !ok {
+			mig = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = mig
+		}
+		if mig.Name != m[2] {
+			panic(fmt.Sprintf("migrations: %s version %d has two names", dir, version))
+		}
+		if m[3] == "up" {
+			mig.Up = string(data)
+		} else {
+			mig.Down = string(data)
+		}
+	}
+
+	set := &Set{Name: dir, present: presentQueries[dir]}
+	for v := 1; v <= len(byVersion); v++ {
+		mig, ok := byVersion[v]
+		if !ok {
+			panic(fmt.Sprintf("migrations: %s is missing version %d", dir, v))
+		}
+		if strings.TrimSpace(mig.Up) == "" || strings.TrimSpace(mig.Down) == "" {
+			panic(fmt.Sprintf("migrations: %s version %d needs both up and down scripts", dir, v))
+		}
+		set.Migrations = append(set.Migrations, *mig)
+	}
+	return set
+}
+
+// Latest is the version a fully migrated database is at.
+func (s *Set) Latest() int {
+	return len(s.Migrations)
+}
+
+const createTable = `CREATE TABLE IF NOT EXISTS schema_migrations (
+	version INTEGER PRIMARY KEY,
+	name TEXT NOT NULL,
+	applied_at DATETIME NOT NULL
+)`
+
+// applied returns the applied versions with their timestamps. A database
+// without a schema_migrations table has applied nothing.
+func applied(db *sql.DB) (map[int]time.Time, error) {
+	var exists int
+	if err := db.QueryRow("SELECT count(*) FROM sqlite_master WHERE type = 'table' AND name = 'schema_migrations'").Scan(&exists); err != nil {
+		return nil, err
+	}
+	out := make(map[int]time.Time)
+	if exists == 0 {
+		return out, nil
+	}
+
+	rows, err := db.Query("SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var version int
+		var at time.Time
+		if err := rows.Scan(&version, &at); err != nil {
+			return nil, fmt.Errorf("scan schema_migrations: %w", err)
+		}
+		out[version] = at
+	}
+	return out, rows.Err()
+}
+
+// Current is the highest applied version, 0 for a fresh database.
+func (s *Set) Current(db *sql.DB) (int, error) {
+	done, err := applied(db)
+	if err != nil {
+		return 0, err
+	}
+	current := 0
+	for v := range done {
+		current = max(current, v)
+	}
+	return current, nil
+}
+
+// Up applies every pending migration, each in its own transaction, and
+// returns the ones it ran. A migration whose changes the database already
+// has is recorded as applied without running it.
+func (s *Set) Up(db *sql.DB) ([]Migration, error) {
+	if _, err := db.Exec(createTable); err != nil {
+		return nil, err
+	}
+	done, err := applied(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var ran []Migration
+	for _, m := range s.Migrations {
+		if _, ok := done[m.Version]; ok {
+			continue
+		}
+		err := inTx(db, func(tx *sql.Tx) error {
+			var have bool
+			if q, ok := s.present[m.Version]; ok {
+				if err := tx.QueryRow(q).Scan(&have); err != nil {
+					return err
+				}
+			}
+			if !have {
+				if _, err := tx.Exec(m.Up); err != nil {
+					return err
+				}
+			}
+			_, err := tx.Exec("INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)", m.Version, m.Name, time.Now().UTC())
+			return err
+		})
+		if err != nil {
+			return ran, fmt.Errorf("%s migration %04d_%s up: %w", s.Name, m.Version, m.Name, err)
+		}
+		ran = append(ran, m)
+	}
+	return ran, nil
+}
+
+// Down reverts the latest steps applied migrations, newest first.
+func (s *Set) Down(db *sql.DB, steps int) ([]Migration, error) {
+	done, err := applied(db)
+	if err != nil {
+		return nil, err
+	}
+	var versions []int
+	for v := range done {
+		versions = append(versions, v)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(versions)))
+
+	var ran []Migration
+	for _, v := range versions {
+		if len(ran) == steps {
+			break
+		}
+		if v > len(s.Migrations) {
+			return ran, fmt.Errorf("%s version %d is newer than this build knows", s.Name, v)
+		}
+		m := s.Migrations[v-1]
+		err := inTx(db, func(tx *sql.Tx) error {
+			if _, err := tx.Exec(m.Down); err != nil {
+				return err
+			}
+			_, err := tx.Exec("DELETE FROM schema_migrations WHERE version = ?", m.Version)
+			return err
+		})
+		if err != nil {
+			return ran, fmt.Errorf("%s migration %04d_%s down: %w", s.Name, m.Version, m.Name, err)
+		}
+		ran = append(ran, m)
+	}
+	return ran, nil
+}
+
+// Status is one migration and when it was applied, if it was.
+type Status struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// Status lists every migration with its applied time.
+func (s *Set) Status(db *sql.DB) ([]Status, error) {
+	done, err := applied(db)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Status, len(s.Migrations))
+	for i, m := range s.Migrations {
+		out[i] = Status{Migration: m}
+		if at, ok := done[m.Version]; ok {
+			out[i].AppliedAt = &at
+		}
+	}
+	return out, nil
+}
+
+// OutdatedError is returned by Check when a database isn't at the version
+// this build expects.
+type OutdatedError struct {
+	Set     string
+	Current int
+	Latest  int
+	Pending []int
+}
+
+func (e *OutdatedError) Error() string {
+	if e.Current > e.Latest {
+		return fmt.Sprintf("%s schema is at version %d, newer than this build (%d)", e.Set, e.Current, e.Latest)
+	}
+	return fmt.Sprintf("%s schema is outdated: pending migrations %v, run `go run ./cmd/migrate up`", e.Set, e.Pending)
+}
+
+// Check reports an *OutdatedError unless every migration of s has been
+// applied to db and nothing newer has.
+func (s *Set) Check(db *sql.DB) error {
+	done, err := applied(db)
+	if err != nil {
+		return err
+	}
+	current := 0
+	var pending []int
+	for v := range done {
+		current = max(current, v)
+	}
+	for _, m := range s.Migrations {
+		if _, ok := done[m.Version]; !ok {
+			pending = append(pending, m.Version)
+		}
+	}
+	if len(pending) > 0 || current > s.Latest() {
+		return &OutdatedError{Set: s.Name, Current: current, Latest: s.Latest(), Pending: pending}
+	}
+	return nil
+}
+
+func inTx(db *sql.DB, fn func(*sql.Tx) error) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS `payouts`;
+DROP TABLE IF EXISTS `insurers`;
+DROP TABLE IF EXISTS `cost_items`;
+DROP TABLE IF EXISTS `scenarios`;
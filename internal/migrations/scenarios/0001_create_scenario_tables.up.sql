@@ -0,0 +1,42 @@
+-- Matches the tables GORM's AutoMigrate created before migrations existed,
+-- so databases built that way pass through unchanged.
+CREATE TABLE IF NOT EXISTS `scenarios` (
+	`id` text,
+	`title` varchar(255) NOT NULL,
+	`description` text,
+	`total_cost_hkd` integer NOT NULL,
+	`created_at` datetime,
+	`updated_at` datetime,
+	PRIMARY KEY (`id`)
+);
+
+CREATE TABLE IF NOT EXISTS `cost_items` (
+	`id` text,
+	`scenario_id` text NOT NULL,
+	`item_name` varchar(255) NOT NULL,
+	`amount_hkd` integer NOT NULL,
+	PRIMARY KEY (`id`),
+	CONSTRAINT `fk_scenarios_cost_items` FOREIGN KEY (`scenario_id`) REFERENCES `scenarios`(`id`) ON DELETE CASCADE ON UPDATE CASCADE
+);
+CREATE INDEX IF NOT EXISTS `idx_cost_items_scenario_id` ON `cost_items`(`scenario_id`);
+
+CREATE TABLE IF NOT EXISTS `insurers` (
+	`id` varchar(50),
+	`name` varchar(255) NOT NULL,
+	`plan_name` varchar(255) NOT NULL,
+	PRIMARY KEY (`id`)
+);
+
+CREATE TABLE IF NOT EXISTS `payouts` (
+	`id` text,
+	`scenario_id` text NOT NULL,
+	`insurer_id` varchar(50) NOT NULL,
+	`estimated_payout_hkd` integer NOT NULL,
+	`coverage_percentage` real NOT NULL,
+	`analysis` text,
+	`is_recommended` numeric DEFAULT false,
+	PRIMARY KEY (`id`),
+	CONSTRAINT `fk_payouts_insurer` FOREIGN KEY (`insurer_id`) REFERENCES `insurers`(`id`) ON DELETE SET NULL ON UPDATE CASCADE,
+	CONSTRAINT `fk_scenarios_payouts` FOREIGN KEY (`scenario_id`) REFERENCES `scenarios`(`id`) ON DELETE CASCADE ON UPDATE CASCADE
+);
+CREATE INDEX IF NOT EXISTS `idx_payouts_scenario_id` ON `payouts`(`scenario_id`);
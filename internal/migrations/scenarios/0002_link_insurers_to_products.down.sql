@@ -0,0 +1,2 @@
+ALTER TABLE `insurers` DROP COLUMN `product_id`;
+ALTER TABLE `insurers` DROP COLUMN `company_id`;
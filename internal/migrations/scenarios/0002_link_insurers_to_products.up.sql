@@ -0,0 +1,5 @@
+-- The insurance product each scenario insurer stands for. The product
+-- lives in the other database, so these are plain ids rather than foreign
+-- keys; company_id is the product's provider_id at the time of linking.
+ALTER TABLE `insurers` ADD COLUMN `company_id` integer;
+ALTER TABLE `insurers` ADD COLUMN `product_id` integer;
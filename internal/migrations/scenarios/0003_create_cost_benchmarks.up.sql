@@ -0,0 +1,15 @@
+-- What a cost item typically costs in one district or at one clinic. The
+-- row with neither is the Hong Kong-wide baseline local prices are scaled
+-- against. clinic_id is models.Clinic.ClinicID; a clinic row may name the
+-- clinic's district so items the clinic has no price for fall back to it.
+CREATE TABLE IF NOT EXISTS `cost_benchmarks` (
+	`id` integer PRIMARY KEY AUTOINCREMENT,
+	`item_name` varchar(255) NOT NULL,
+	`district` varchar(50) NOT NULL DEFAULT '' COLLATE NOCASE,
+	`clinic_id` text NOT NULL DEFAULT '',
+	`amount_hkd` integer NOT NULL CHECK (`amount_hkd` > 0),
+	`updated_at` datetime,
+	UNIQUE (`item_name`, `district`, `clinic_id`)
+);
+CREATE INDEX IF NOT EXISTS `idx_cost_benchmarks_clinic_id` ON `cost_benchmarks`(`clinic_id`);
+CREATE INDEX IF NOT EXISTS `idx_cost_benchmarks_district` ON `cost_benchmarks`(`district`);
@@ -0,0 +1,22 @@
+-- The vet clinic directory, previously only kept in assets/clinics.csv.
+CREATE TABLE IF NOT EXISTS `clinics` (
+	`clinic_id` text,
+	`name` text NOT NULL,
+	`address` text,
+	`phone_regular` text,
+	`phone_emergency` text,
+	`whatsapp` text,
+	`opening_hours` text,
+	`emergency_24h` text,
+	`website_url` text,
+	`applemap_url` text,
+	`latitude` text,
+	`longitude` text,
+	`rating` text,
+	`google_place_id` text,
+	`photo_reference` text,
+	`created_at` datetime,
+	`updated_at` datetime,
+	PRIMARY KEY (`clinic_id`)
+);
+CREATE INDEX IF NOT EXISTS `idx_clinics_google_place_id` ON `clinics`(`google_place_id`);
@@ -0,0 +1,4 @@
+DROP TRIGGER IF EXISTS `clinics_location_update`;
+DROP TRIGGER IF EXISTS `clinics_location_delete`;
+DROP TRIGGER IF EXISTS `clinics_location_insert`;
+DROP TABLE IF EXISTS `clinic_locations`;
@@ -0,0 +1,50 @@
+-- R-tree over clinic coordinates for /clinics?lat=&lng=. Coordinates stay
+-- text in clinics; the triggers below index every clinic whose latitude and
+-- longitude parse as a point, whoever writes the row. clinic_id is an
+-- auxiliary column, so the index has its own ids and survives VACUUM.
+CREATE VIRTUAL TABLE IF NOT EXISTS `clinic_locations` USING rtree(
+	id,
+	min_lat, max_lat,
+	min_lng, max_lng,
+	+clinic_id
+);
+
+INSERT INTO `clinic_locations` (min_lat, max_lat, min_lng, max_lng, clinic_id)
+SELECT CAST(latitude AS REAL), CAST(latitude AS REAL), CAST(longitude AS REAL), CAST(longitude AS REAL), clinic_id
+FROM `clinics`
+WHERE CAST(latitude AS REAL) BETWEEN -90 AND 90 AND CAST(longitude AS REAL) BETWEEN -180 AND 180
+	AND (CAST(latitude AS REAL) <> 0 OR CAST(longitude AS REAL) <> 0);
+
+CREATE TRIGGER IF NOT EXISTS `clinics_location_insert` AFTER INSERT ON `clinics`
+WHEN CAST(NEW.latitude AS REAL) BETWEEN -90 AND 90 AND CAST(NEW.longitude AS REAL) BETWEEN -180 AND 180
+	AND (CAST(NEW.latitude AS REAL) <> 0 OR CAST(NEW.longitude AS REAL) <> 0)
+BEGIN
+	INSERT INTO `clinic_locations` (min_lat, max_lat, min_lng, max_lng, clinic_id)
+	VALUES (CAST(NEW.latitude AS REAL), CAST(NEW.latitude AS REAL), CAST(NEW.longitude AS REAL), CAST(NEW.longitude AS REAL), NEW.clinic_id);
+END;
+
+-- The old entry is found through the index by the old point; the R-tree
+-- rounds its boxes outwards, so the range always contains it.
+CREATE TRIGGER IF NOT EXISTS `clinics_location_delete` AFTER DELETE ON `clinics`
+BEGIN
+	DELETE FROM `clinic_locations` WHERE id IN (
+		SELECT id FROM `clinic_locations`
+		WHERE min_lat <= CAST(OLD.latitude AS REAL) AND max_lat >= CAST(OLD.latitude AS REAL)
+			AND min_lng <= CAST(OLD.longitude AS REAL) AND max_lng >= CAST(OLD.longitude AS REAL)
+			AND clinic_id = OLD.clinic_id
+	);
+END;
+
+CREATE TRIGGER IF NOT EXISTS `clinics_location_update` AFTER UPDATE OF clinic_id, latitude, longitude ON `clinics`
+BEGIN
+	DELETE FROM `clinic_locations` WHERE id IN (
+		SELECT id FROM `clinic_locations`
+		WHERE min_lat <= CAST(OLD.latitude AS REAL) AND max_lat >= CAST(OLD.latitude AS REAL)
+			AND min_lng <= CAST(OLD.longitude AS REAL) AND max_lng >= CAST(OLD.longitude AS REAL)
+			AND clinic_id = OLD.clinic_id
+	);
+	INSERT INTO `clinic_locations` (min_lat, max_lat, min_lng, max_lng, clinic_id)
+	SELECT CAST(NEW.latitude AS REAL), CAST(NEW.latitude AS REAL), CAST(NEW.longitude AS REAL), CAST(NEW.longitude AS REAL), NEW.clinic_id
+	WHERE CAST(NEW.latitude AS REAL) BETWEEN -90 AND 90 AND CAST(NEW.longitude AS REAL) BETWEEN -180 AND 180
+		AND (CAST(NEW.latitude AS REAL) <> 0 OR CAST(NEW.longitude AS REAL) <> 0);
+END;
@@ -0,0 +1,103 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Clinic is a vet clinic of the directory behind /clinics. ClinicID is
+// stable: the id the clinic was imported with, or a UUID. Emergency24h is
+// "TRUE" or "FALSE" as the opening hours say, and only taken as given for
+// clinics whose hours are missing or unreadable.
+type Clinic struct {
+	ClinicID       string        `gorm:"column:clinic_id;type:text;primaryKey" json:"clinic_id"`
+	Name           string        `gorm:"type:text;not null" json:"name"`
+	Address        string        `gorm:"type:text" json:"address"`
+	PhoneRegular   string        `gorm:"type:text" json:"phone_regular"`
+	PhoneEmergency string        `gorm:"type:text" json:"phone_emergency"`
+	Whatsapp       string        `gorm:"type:text" json:"whatsapp"`
+	OpeningHours   string        `gorm:"type:text" json:"opening_hours"`
+	Schedule       *OpeningHours `gorm:"-" json:"schedule"` // parsed from OpeningHours; nil when unknown
+	Emergency24h   string        `gorm:"column:emergency_24h;type:text" json:"emergency_24h"`
+	WebsiteURL     string        `gorm:"type:text" json:"website_url"`
+	ApplemapURL    string        `gorm:"type:text" json:"applemap_url"`
+	Latitude       string        `gorm:"type:text" json:"latitude"`
+	Longitude      string        `gorm:"type:text" json:"longitude"`
+	Rating         string        `gorm:"type:text" json:"rating"`
+	PhotoURL       string        `gorm:"-" json:"photo_url"` // built from PhotoReference and the Maps key
+	GooglePlaceID  string        `gorm:"type:text" json:"google_place_id"`
+	PhotoReference string        `gorm:"type:text" json:"photo_reference"`
+	CreatedAt      time.Time     `json:"created_at"`
+	UpdatedAt      time.Time     `json:"updated_at"`
+}
+
+func (c *Clinic) BeforeCreate(tx *gorm.DB) error {
+	if c.ClinicID == "" {
+		c.ClinicID = uuid.New().String()
+	}
+	return nil
+}
+
+// AfterFind parses the opening hours into Schedule.
+func (c *Clinic) AfterFind(tx *gorm.DB) error {
+	c.Schedule, _ = ParseOpeningHours(c.OpeningHours)
+	return nil
+}
+
+func (c *Clinic) BeforeSave(tx *gorm.DB) error {
+	c.DeriveEmergency24h()
+	return nil
+}
+
+// DeriveEmergency24h parses the opening hours into Schedule and sets
+// Emergency24h from it. It returns the parse error, leaving Emergency24h
+// alone, when the hours can't be read.
+func (c *Clinic) DeriveEmergency24h() error {
+	schedule, err := ParseOpeningHours(c.OpeningHours)
+	if err != nil {
+		c.Schedule = nil
+		return err
+	}
+	c.Schedule = schedule
+	if schedule != nil {
+		c.Emergency24h = "FALSE"
+		if schedule.AlwaysOpen() {
+			c.Emergency24h = "TRUE"
+		}
+	}
+	return nil
+}
+
+// AlwaysOpen reports whether the clinic never closes: from its schedule,
+// or from Emergency24h when its hours are unknown.
+func (c *Clinic) AlwaysOpen() bool {
+	if c.Schedule != nil {
+		return c.Schedule.AlwaysOpen()
+	}
+	return strings.EqualFold(strings.TrimSpace(c.Emergency24h), "TRUE")
+}
+
+// OpenAt reports whether the clinic is open at t; see OpeningHours.OpenAt.
+// Clinics with unknown hours count as open only when they never close.
+func (c *Clinic) OpenAt(t time.Time, holiday func(time.Time) bool) bool {
+	if c.Schedule != nil {
+		return c.Schedule.OpenAt(t, holiday)
+	}
+	return c.AlwaysOpen()
+}
+
+// OpenAllDay reports whether the clinic is open around the clock on date;
+// see OpeningHours.OpenAllDay. Clinics with unknown hours count only when
+// they never close.
+func (c *Clinic) OpenAllDay(date time.Time, holiday func(time.Time) bool) bool {
+	if c.Schedule != nil {
+		return c.Schedule.OpenAllDay(date, holiday)
+	}
+	return c.AlwaysOpen()
+}
+
+// ClinicOrder lists clinics in the order they were added.
+const ClinicOrder = "created_at, clinic_id"
@@ -0,0 +1,80 @@
+package models
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+const earthRadiusM = 6371008.8
+
+// NearbyClinic is a clinic with its distance from the point searched from.
+type NearbyClinic struct {
+	Clinic
+	DistanceM int `json:"distance_m"`
+}
+
+// DistanceM is the great-circle (haversine) distance in metres between two
+// points given in degrees.
+func DistanceM(lat1, lng1, lat2, lng2 float64) float64 {
+	rad := math.Pi / 180
+	dLat := (lat2 - lat1) * rad
+	dLng := (lng2 - lng1) * rad
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1*rad)*math.Cos(lat2*rad)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadiusM * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// Coordinates parses the clinic's latitude and longitude; ok is false when
+// it has no usable location.
+func (c *Clinic) Coordinates() (lat, lng float64, ok bool) {
+	lat, err1 := strconv.ParseFloat(strings.TrimSpace(c.Latitude), 64)
+	lng, err2 := strconv.ParseFloat(strings.TrimSpace(c.Longitude), 64)
+	if err1 != nil || err2 != nil || math.Abs(lat) > 90 || math.Abs(lng) > 180 {
+		return 0, 0, false
+	}
+	return lat, lng, true
+}
+
+// NearbyClinics returns up to limit clinics within radiusM metres of
+// lat/lng, nearest first, leaving out those keep rejects when it isn't
+// nil. Candidates come from the clinic_locations R-tree by bounding box,
+// so only clinics near the point are read.
+func NearbyClinics(db *gorm.DB, lat, lng, radiusM float64, limit int, keep func(*Clinic) bool) ([]NearbyClinic, error) {
+	dLat := radiusM / earthRadiusM * 180 / math.Pi
+	minLng, maxLng := -180.0, 180.0
+	if cos := math.Cos(lat * math.Pi / 180); cos > 1e-6 {
+		if dLng := dLat / cos; dLng < 180 {
+			minLng, maxLng = lng-dLng, lng+dLng
+		}
+	}
+	box := db.Table("clinic_locations").Select("clinic_id").
+		Where("max_lat >= ? AND min_lat <= ? AND max_lng >= ? AND min_lng <= ?", lat-dLat, lat+dLat, minLng, maxLng)
+
+	var candidates []Clinic
+	if err := db.Where("clinic_id IN (?)", box).Find(&candidates).Error; err != nil {
+		return nil, err
+	}
+	nearby := make([]NearbyClinic, 0, len(candidates))
+	for _, c := range candidates {
+		cLat, cLng, ok := c.Coordinates()
+		if !ok || keep != nil && !keep(&c) {
+			continue
+		}
+		if d := DistanceM(lat, lng, cLat, cLng); d <= radiusM {
+			nearby = append(nearby, NearbyClinic{Clinic: c, DistanceM: int(math.Round(d))})
+		}
+	}
+	sort.SliceStable(nearby, func(i, j int) bool {
+		if nearby[i].DistanceM != nearby[j].DistanceM {
+			return nearby[i].DistanceM < nearby[j].DistanceM
+		}
+		return nearby[i].ClinicID < nearby[j].ClinicID
+	})
+	if len(nearby) > limit {
+		nearby = nearby[:limit]
+	}
+	return nearby, nil
+}
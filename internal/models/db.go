@@ -5,28 +5,28 @@ import (
 	"log"
 
 	"github.com/vf0429/Petwell_Backend/internal/config"
+	"github.com/vf0429/Petwell_Backend/internal/migrations"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
 
 func InitDB(cfg *config.Config) (*gorm.DB, error) {
 	// Use SQLite instead of Postgres
-	db, err := gorm.Open(sqlite.Open("pet_insurance.db"), &gorm.Config{})
+	db, err := gorm.Open(sqlite.Open(cfg.ScenarioDBPath), &gorm.Config{})
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect database: %w", err)
 	}
 
-	// Auto Migrate the schema
-	err = db.AutoMigrate(
-		&Scenario{},
-		&CostItem{},
-		&Insurer{},
-		&Payout{},
-	)
+	// The schema is owned by internal/migrations; refuse to run against a
+	// database that hasn't been migrated to this build's version.
+	sqlDB, err := db.DB()
 	if err != nil {
-		return nil, fmt.Errorf("failed to auto migrate schema: %w", err)
+		return nil, fmt.Errorf("failed to connect database: %w", err)
+	}
+	if err := migrations.Scenarios.Check(sqlDB); err != nil {
+		return nil, fmt.Errorf("%s: %w", cfg.ScenarioDBPath, err)
 	}
 
-	log.Println("Database connection established and models migrated.")
+	log.Println("Database connection established and schema is up to date.")
 	return db, nil
 }
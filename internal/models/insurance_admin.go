@@ -0,0 +1,592 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrConflict is returned when a write would duplicate a key or orphan
+// rows that still reference the record.
+var ErrConflict = errors.New("conflict")
+
+// ValidationError reports a field that is missing or points at a record
+// that doesn't exist.
+type ValidationError struct {
+	Field   string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+// HongKong is the zone dates in the insurance data are written in. Hong
+// Kong has no DST, so a fixed offset avoids depending on the system tz
+// database.
+var HongKong = time.FixedZone("HKT", 8*60*60)
+
+// updateTimeFormat matches the update_time values of the imported data.
+const updateTimeFormat = "2006-01-02"
+
+// stampDate is the value written to product.update_time.
+func stampDate() string {
+	return time.Now().In(HongKong).Format(updateTimeFormat)
+}
+
+// write runs fn in a transaction on the repository's pool and brings the
+// search index up to date before committing.
+func (r *InsuranceRepository) write(fn func(tx *sql.Tx) error) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := RebuildSearchIndex(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// exists reports whether query, a SELECT 1 ..., returns a row.
+func exists(tx *sql.Tx, query string, args ...interface{}) (bool, error) {
+	var one int
+	err := tx.QueryRow(query, args...).Scan(&one)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// mustExist returns a ValidationError on field unless the referenced row exists.
+func mustExist(tx *sql.Tx, field, table, column string, id int) error {
+	ok, err := exists(tx, "SELECT 1 FROM "+table+" WHERE "+column+" = ?", id)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return &ValidationError{Field: field, Message: fmt.Sprintf("%s %d does not exist", table, id)}
+	}
+	return nil
+}
+
+// changed turns a zero RowsAffected into ErrNotFound.
+func changed(res sql.Result, err error) error {
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// autoID lets SQLite assign the primary key when the caller left it at 0.
+func autoID(id int) interface{} {
+	if id == 0 {
+		return nil
+	}
+	return id
+}
+
+// setClause turns "a, b, c" into "a = ?, b = ?, c = ?".
+func setClause(columns string) string {
+	parts := strings.Split(columns, ",")
+	for i, p := range parts {
+		parts[i] = strings.TrimSpace(p) + " = ?"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// touchProduct stamps update_time on a product whose plan changed.
+func touchProduct(tx *sql.Tx, actor string, productID int) error {
+	return TrackChange(tx, actor, "product", []interface{}{productID}, func() error {
+		_, err := tx.Exec("UPDATE product SET update_time = ? WHERE insurance_id = ?", stampDate(), productID)
+		return err
+	})
+}
+
+// deleteTracked deletes one tracked row, recording it in change_history.
+func deleteTracked(tx *sql.Tx, actor, table, where string, key ...interface{}) error {
+	return TrackChange(tx, actor, table, key, func() error {
+		return changed(tx.Exec("DELETE FROM "+table+" WHERE "+where, key...))
+	})
+}
+
+// --- Providers ---
+
+func validateCompany(c *InsuranceCompany) error {
+	if strings.TrimSpace(c.CompanyName) == "" {
+		return &ValidationError{Field: "company_name", Message: "is required"}
+	}
+	return nil
+}
+
+// CreateCompany inserts a provider. A zero CompanyId is assigned by the
+// database and written back to c.
+func (r *InsuranceRepository) CreateCompany(c *InsuranceCompany) error {
+	if err := validateCompany(c); err != nil {
+		return err
+	}
+	return r.write(func(tx *sql.Tx) error {
+		if c.CompanyId != 0 {
+			taken, err := exists(tx, "SELECT 1 FROM insurance_provider WHERE company_id = ?", c.CompanyId)
+			if err != nil {
+				return err
+			}
+			if taken {
+				return fmt.Errorf("%w: insurance_provider %d already exists", ErrConflict, c.CompanyId)
+			}
+		}
+		res, err := tx.Exec("INSERT INTO insurance_provider (company_id, company_name, company_name_zh, company_logo) VALUES (?, ?, ?, ?)",
+			autoID(c.CompanyId), c.CompanyName, c.CompanyNameZh, c.CompanyLogo)
+		if err != nil {
+			return err
+		}
+		id, err := res.LastInsertId()
+		c.CompanyId = int(id)
+		return err
+	})
+}
+
+// UpdateCompany replaces every field of provider c.CompanyId.
+func (r *InsuranceRepository) UpdateCompany(c *InsuranceCompany) error {
+	if err := validateCompany(c); err != nil {
+		return err
+	}
+	return r.write(func(tx *sql.Tx) error {
+		return changed(tx.Exec("UPDATE insurance_provider SET company_name = ?, company_name_zh = ?, company_logo = ? WHERE company_id = ?",
+			c.CompanyName, c.CompanyNameZh, c.CompanyLogo, c.CompanyId))
+	})
+}
+
+// DeleteCompany removes a provider that no longer has products.
+func (r *InsuranceRepository) DeleteCompany(id int) error {
+	return r.write(func(tx *sql.Tx) error {
+		var products int
+		if err := tx.QueryRow("SELECT count(*) FROM product WHERE provider_id = ?", id).Scan(&products); err != nil {
+			return err
+		}
+		if products > 0 {
+			return fmt.Errorf("%w: insurance_provider %d still has %d products", ErrConflict, id, products)
+		}
+		return changed(tx.Exec("DELETE FROM insurance_provider WHERE company_id = ?", id))
+	})
+}
+
+// --- Products ---
+
+func validateProduct(tx *sql.Tx, p *InsuranceProduct) error {
+	if strings.TrimSpace(p.InsuranceName) == "" {
+		return &ValidationError{Field: "insurance_name", Message: "is required"}
+	}
+	return mustExist(tx, "provider_id", "insurance_provider", "company_id", p.ProviderId)
+}
+
+// setProductTags replaces the tags of product p.InsuranceId with p.Tags,
+// each given by tag_id or slug, and writes the full tags back to p.
+func setProductTags(tx *sql.Tx, p *InsuranceProduct) error {
+	ids := make([]int, 0, len(p.Tags))
+	for _, t := range p.Tags {
+		var id int
+		err := tx.QueryRow("SELECT tag_id FROM tag WHERE tag_id = ? OR slug = ? COLLATE NOCASE", t.TagId, t.Slug).Scan(&id)
+		if err == sql.ErrNoRows {
+			name := t.Slug
+			if name == "" {
+				name = fmt.Sprint(t.TagId)
+			}
+			return &ValidationError{Field: "tags", Message: fmt.Sprintf("tag %s does not exist", name)}
+		}
+		if err != nil {
+			return err
+		}
+		ids = append(ids, id)
+	}
+	if _, err := tx.Exec("DELETE FROM product_tag WHERE product_id = ?", p.InsuranceId); err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if _, err := tx.Exec("INSERT OR IGNORE INTO product_tag (product_id, tag_id) VALUES (?, ?)", p.InsuranceId, id); err != nil {
+			return err
+		}
+	}
+	return loadProductTags(tx, p)
+}
+
+// productValues lists p's fields in productColumns order, without the id.
+func productValues(p *InsuranceProduct) []interface{} {
+	return []interface{}{p.ProviderId, p.InsuranceName, p.InsuranceNameZh, p.Remark, p.RemarkZh,
+		p.MinAge, p.MinAgeZh, p.MaxAge, p.MaxAgeZh, p.Coinsurance, p.CoinsuranceZh, p.SuitablePetType, p.SuitablePetTypeZh,
+		p.CatBreedType, p.CatBreedTypeZh, p.DogBreedType, p.DogBreedTypeZh, p.BreedTypeRemark, p.BreedTypeRemarkZh,
+		p.PaymentMode, p.PaymentModeZh, p.WaitingPeriod, p.WaitingPeriodZh, p.InformationLink, p.InformationLinkZh, p.UpdateTime}
+}
+
+// productDataColumns is productColumns without insurance_id.
+var productDataColumns = strings.TrimPrefix(productColumns, "insurance_id, ")
+
+// CreateProduct inserts a product after checking its provider exists and
+// tags it with p.Tags, given by tag_id or slug. The id is assigned by the database when zero, and update_time is stamped
+// with today's date; both are written back to p. Like every product and
+// limit write, it is recorded in change_history under actor.
+func (r *InsuranceRepository) CreateProduct(actor string, p *InsuranceProduct) error {
+	return r.write(func(tx *sql.Tx) error {
+		if err := validateProduct(tx, p); err != nil {
+			return err
+		}
+		if p.InsuranceId != 0 {
+			taken, err := exists(tx, "SELECT 1 FROM product WHERE insurance_id = ?", p.InsuranceId)
+			if err != nil {
+				return err
+			}
+			if taken {
+				return fmt.Errorf("%w: product %d already exists", ErrConflict, p.InsuranceId)
+			}
+		}
+		p.UpdateTime = NullJsonString{sql.NullString{String: stampDate(), Valid: true}}
+		args := append([]interface{}{autoID(p.InsuranceId)}, productValues(p)...)
+		res, err := tx.Exec("INSERT INTO product ("+productColumns+") VALUES (?"+strings.Repeat(", ?", len(args)-1)+")", args...)
+		if err != nil {
+			return err
+		}
+		id, err := res.LastInsertId()
+		if err != nil {
+			return err
+		}
+		p.InsuranceId = int(id)
+		if err := setProductTags(tx, p); err != nil {
+			return err
+		}
+		return recordChange(tx, actor, "product", []interface{}{p.InsuranceId}, rowState{})
+	})
+}
+
+// UpdateProduct replaces every field and the tags of product
+// p.InsuranceId and stamps update_time.
+func (r *InsuranceRepository) UpdateProduct(actor string, p *InsuranceProduct) error {
+	return r.write(func(tx *sql.Tx) error {
+		if err := mustFind(tx, "product", "insurance_id", p.InsuranceId); err != nil {
+			return err
+		}
+		if err := validateProduct(tx, p); err != nil {
+			return err
+		}
+		p.UpdateTime = NullJsonString{sql.NullString{String: stampDate(), Valid: true}}
+		args := append(productValues(p), p.InsuranceId)
+		return TrackChange(tx, actor, "product", []interface{}{p.InsuranceId}, func() error {
+			if err := changed(tx.Exec("UPDATE product SET "+setClause(productDataColumns)+" WHERE insurance_id = ?", args...)); err != nil {
+				return err
+			}
+			return setProductTags(tx, p)
+		})
+	})
+}
+
+// DeleteProduct removes a product together with its coverage limits and
+// sub-limits.
+func (r *InsuranceRepository) DeleteProduct(actor string, id int) error {
+	return r.write(func(tx *sql.Tx) error {
+		if err := mustFind(tx, "product", "insurance_id", id); err != nil {
+			return err
+		}
+		subIDs, err := queryInts(tx, "SELECT sub_coverage_id FROM sub_coverage_limit WHERE product_id = ?", id)
+		if err != nil {
+			return err
+		}
+		for _, subID := range subIDs {
+			if err := deleteTracked(tx, actor, "sub_coverage_limit", "sub_coverage_id = ?", subID); err != nil {
+				return err
+			}
+		}
+		coverageIDs, err := queryInts(tx, "SELECT coverage_id FROM coverage_limit WHERE product_id = ?", id)
+		if err != nil {
+			return err
+		}
+		for _, coverageID := range coverageIDs {
+			if err := deleteTracked(tx, actor, "coverage_limit", "coverage_id = ? AND product_id = ?", coverageID, id); err != nil {
+				return err
+			}
+		}
+		return deleteTracked(tx, actor, "product", "insurance_id = ?", id)
+	})
+}
+
+// queryInts reads a single integer column.
+func queryInts(tx *sql.Tx, query string, args ...interface{}) ([]int, error) {
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []int
+	for rows.Next() {
+		var v int
+		if err := rows.Scan(&v); err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+	}
+	return out, rows.Err()
+}
+
+// mustFind returns ErrNotFound unless the row being changed exists.
+func mustFind(tx *sql.Tx, table, column string, id int) error {
+	ok, err := exists(tx, "SELECT 1 FROM "+table+" WHERE "+column+" = ?", id)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// --- Tags ---
+
+func validateTag(t *Tag) error {
+	if t.Slug == "" || strings.ContainsAny(t.Slug, "#, \t\n") {
+		return &ValidationError{Field: "slug", Message: "is required and may not contain '#', commas or spaces"}
+	}
+	if strings.TrimSpace(t.Label) == "" {
+		return &ValidationError{Field: "label", Message: "is required"}
+	}
+	return nil
+}
+
+// slugTaken returns ErrConflict when another tag already uses t.Slug.
+func slugTaken(tx *sql.Tx, t *Tag) error {
+	taken, err := exists(tx, "SELECT 1 FROM tag WHERE slug = ? COLLATE NOCASE AND tag_id != ?", t.Slug, t.TagId)
+	if err != nil {
+		return err
+	}
+	if taken {
+		return fmt.Errorf("%w: tag %s already exists", ErrConflict, t.Slug)
+	}
+	return nil
+}
+
+// CreateTag adds a tag to the taxonomy. A zero TagId is assigned by the
+// database and written back to t.
+func (r *InsuranceRepository) CreateTag(t *Tag) error {
+	if err := validateTag(t); err != nil {
+		return err
+	}
+	return r.write(func(tx *sql.Tx) error {
+		if t.TagId != 0 {
+			taken, err := exists(tx, "SELECT 1 FROM tag WHERE tag_id = ?", t.TagId)
+			if err != nil {
+				return err
+			}
+			if taken {
+				return fmt.Errorf("%w: tag %d already exists", ErrConflict, t.TagId)
+			}
+		}
+		if err := slugTaken(tx, t); err != nil {
+			return err
+		}
+		res, err := tx.Exec("INSERT INTO tag (tag_id, slug, label, label_zh, display_order) VALUES (?, ?, ?, ?, ?)",
+			autoID(t.TagId), t.Slug, t.Label, t.LabelZh, t.DisplayOrder)
+		if err != nil {
+			return err
+		}
+		id, err := res.LastInsertId()
+		t.TagId = int(id)
+		return err
+	})
+}
+
+// UpdateTag replaces every field of tag t.TagId. Tags aren't tracked, so
+// relabelling one doesn't show up in the history of its products.
+func (r *InsuranceRepository) UpdateTag(t *Tag) error {
+	if err := validateTag(t); err != nil {
+		return err
+	}
+	return r.write(func(tx *sql.Tx) error {
+		if err := slugTaken(tx, t); err != nil {
+			return err
+		}
+		return changed(tx.Exec("UPDATE tag SET slug = ?, label = ?, label_zh = ?, display_order = ? WHERE tag_id = ?",
+			t.Slug, t.Label, t.LabelZh, t.DisplayOrder, t.TagId))
+	})
+}
+
+// DeleteTag removes a tag no product carries any more.
+func (r *InsuranceRepository) DeleteTag(id int) error {
+	return r.write(func(tx *sql.Tx) error {
+		var products int
+		if err := tx.QueryRow("SELECT count(*) FROM product_tag WHERE tag_id = ?", id).Scan(&products); err != nil {
+			return err
+		}
+		if products > 0 {
+			return fmt.Errorf("%w: tag %d is still used by %d products", ErrConflict, id, products)
+		}
+		return changed(tx.Exec("DELETE FROM tag WHERE tag_id = ?", id))
+	})
+}
+
+// --- Coverage limits ---
+
+func validateCoverageLimit(tx *sql.Tx, l *CoverageLimit) error {
+	if err := mustExist(tx, "coverage_id", "coverage_list", "coverage_id", l.CoverageId); err != nil {
+		return err
+	}
+	return mustExist(tx, "product_id", "product", "insurance_id", l.ProductId)
+}
+
+// CreateCoverageLimit adds a product's limit for one coverage type.
+func (r *InsuranceRepository) CreateCoverageLimit(actor string, l *CoverageLimit) error {
+	return r.write(func(tx *sql.Tx) error {
+		if err := validateCoverageLimit(tx, l); err != nil {
+			return err
+		}
+		taken, err := exists(tx, "SELECT 1 FROM coverage_limit WHERE coverage_id = ? AND product_id = ?", l.CoverageId, l.ProductId)
+		if err != nil {
+			return err
+		}
+		if taken {
+			return fmt.Errorf("%w: coverage_limit %d/%d already exists", ErrConflict, l.CoverageId, l.ProductId)
+		}
+		err = TrackChange(tx, actor, "coverage_limit", []interface{}{l.CoverageId, l.ProductId}, func() error {
+			_, err := tx.Exec("INSERT INTO coverage_limit (coverage_id, product_id, coverage_limit, remark, remark_zh) VALUES (?, ?, ?, ?, ?)",
+				l.CoverageId, l.ProductId, l.CoverageLimit, l.Remark, l.RemarkZh)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		return touchProduct(tx, actor, l.ProductId)
+	})
+}
+
+// UpdateCoverageLimit replaces the limit and remarks of one
+// coverage_limit row, keyed by coverage and product.
+func (r *InsuranceRepository) UpdateCoverageLimit(actor string, l *CoverageLimit) error {
+	return r.write(func(tx *sql.Tx) error {
+		err := TrackChange(tx, actor, "coverage_limit", []interface{}{l.CoverageId, l.ProductId}, func() error {
+			return changed(tx.Exec("UPDATE coverage_limit SET coverage_limit = ?, remark = ?, remark_zh = ? WHERE coverage_id = ? AND product_id = ?",
+				l.CoverageLimit, l.Remark, l.RemarkZh, l.CoverageId, l.ProductId))
+		})
+		if err != nil {
+			return err
+		}
+		return touchProduct(tx, actor, l.ProductId)
+	})
+}
+
+// DeleteCoverageLimit removes one coverage_limit row.
+func (r *InsuranceRepository) DeleteCoverageLimit(actor string, coverageID, productID int) error {
+	return r.write(func(tx *sql.Tx) error {
+		if err := deleteTracked(tx, actor, "coverage_limit", "coverage_id = ? AND product_id = ?", coverageID, productID); err != nil {
+			return err
+		}
+		return touchProduct(tx, actor, productID)
+	})
+}
+
+// --- Sub-coverage limits ---
+
+func validateSubCoverageLimit(tx *sql.Tx, l *SubCoverageLimit) error {
+	if err := mustExist(tx, "parent_coverage_id", "coverage_list", "coverage_id", l.ParentCoverageId); err != nil {
+		return err
+	}
+	return mustExist(tx, "product_id", "product", "insurance_id", l.ProductId)
+}
+
+// subCoverageValues lists l's fields in subCoverageColumns order, without the id.
+func subCoverageValues(l *SubCoverageLimit) []interface{} {
+	return []interface{}{l.ParentCoverageId, l.ProductId, l.SubCoverageName, l.SubCoverageNameZh,
+		l.SubLimit, l.SubCoverageRemark, l.SubCoverageRemarkZh}
+}
+
+var subCoverageDataColumns = strings.TrimPrefix(subCoverageColumns, "sub_coverage_id, ")
+
+// CreateSubCoverageLimit inserts a sub-limit. A zero SubCoverageId is
+// assigned by the database and written back to l.
+func (r *InsuranceRepository) CreateSubCoverageLimit(actor string, l *SubCoverageLimit) error {
+	return r.write(func(tx *sql.Tx) error {
+		if err := validateSubCoverageLimit(tx, l); err != nil {
+			return err
+		}
+		if l.SubCoverageId != 0 {
+			taken, err := exists(tx, "SELECT 1 FROM sub_coverage_limit WHERE sub_coverage_id = ?", l.SubCoverageId)
+			if err != nil {
+				return err
+			}
+			if taken {
+				return fmt.Errorf("%w: sub_coverage_limit %d already exists", ErrConflict, l.SubCoverageId)
+			}
+		}
+		args := append([]interface{}{autoID(l.SubCoverageId)}, subCoverageValues(l)...)
+		res, err := tx.Exec("INSERT INTO sub_coverage_limit ("+subCoverageColumns+") VALUES (?"+strings.Repeat(", ?", len(args)-1)+")", args...)
+		if err != nil {
+			return err
+		}
+		id, err := res.LastInsertId()
+		if err != nil {
+			return err
+		}
+		l.SubCoverageId = int(id)
+		if err := recordChange(tx, actor, "sub_coverage_limit", []interface{}{l.SubCoverageId}, rowState{}); err != nil {
+			return err
+		}
+		return touchProduct(tx, actor, l.ProductId)
+	})
+}
+
+// UpdateSubCoverageLimit replaces every field of sub-limit l.SubCoverageId.
+// Moving it to another product stamps both products.
+func (r *InsuranceRepository) UpdateSubCoverageLimit(actor string, l *SubCoverageLimit) error {
+	return r.write(func(tx *sql.Tx) error {
+		var oldProduct int
+		err := tx.QueryRow("SELECT product_id FROM sub_coverage_limit WHERE sub_coverage_id = ?", l.SubCoverageId).Scan(&oldProduct)
+		if err == sql.ErrNoRows {
+			return ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if err := validateSubCoverageLimit(tx, l); err != nil {
+			return err
+		}
+		args := append(subCoverageValues(l), l.SubCoverageId)
+		err = TrackChange(tx, actor, "sub_coverage_limit", []interface{}{l.SubCoverageId}, func() error {
+			return changed(tx.Exec("UPDATE sub_coverage_limit SET "+setClause(subCoverageDataColumns)+" WHERE sub_coverage_id = ?", args...))
+		})
+		if err != nil {
+			return err
+		}
+		if oldProduct != l.ProductId {
+			if err := touchProduct(tx, actor, oldProduct); err != nil {
+				return err
+			}
+		}
+		return touchProduct(tx, actor, l.ProductId)
+	})
+}
+
+// DeleteSubCoverageLimit removes one sub-limit.
+func (r *InsuranceRepository) DeleteSubCoverageLimit(actor string, id int) error {
+	return r.write(func(tx *sql.Tx) error {
+		var productID int
+		err := tx.QueryRow("SELECT product_id FROM sub_coverage_limit WHERE sub_coverage_id = ?", id).Scan(&productID)
+		if err == sql.ErrNoRows {
+			return ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if err := deleteTracked(tx, actor, "sub_coverage_limit", "sub_coverage_id = ?", id); err != nil {
+			return err
+		}
+		return touchProduct(tx, actor, productID)
+	})
+}
@@ -0,0 +1,447 @@
+package models
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// historyTimeFormat is how change_history.changed_at is stored: UTC with a
+// fixed width, so comparing the text compares the times.
+const historyTimeFormat = "2006-01-02T15:04:05.000000Z"
+
+// Change is one row of change_history.
+type Change struct {
+	ID        int64           `json:"id"`
+	Table     string          `json:"table"`
+	RecordKey string          `json:"record_key"`
+	ProductId int             `json:"product_id"`
+	Action    string          `json:"action"` // "create", "update" or "delete"
+	OldValue  json.RawMessage `json:"old_value"`
+	NewValue  json.RawMessage `json:"new_value"`
+	ChangedBy string          `json:"changed_by"`
+	ChangedAt time.Time       `json:"changed_at"`
+}
+
+// Tracked reports whether changes to table are kept in change_history.
+func Tracked(table string) bool {
+	switch table {
+	case "product", "coverage_limit", "sub_coverage_limit":
+		return true
+	}
+	return false
+}
+
+// loadRow reads the single row query returns, or nil when there is none.
+func loadRow[T any](tx *sql.Tx, query string, scan func(*sql.Rows, *T) error, args ...interface{}) (*T, error) {
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+	var v T
+	if err := scan(rows, &v); err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// rowState is a tracked row as JSON with its record key and product.
+type rowState struct {
+	data      json.RawMessage
+	key       string
+	productID int
+}
+
+// snapshot reads the tracked row identified by key. A missing row gives a
+// zero rowState.
+func snapshot(tx *sql.Tx, table string, key []interface{}) (rowState, error) {
+	var (
+		v   interface{}
+		st  rowState
+		err error
+	)
+	switch table {
+	case "product":
+		var p *InsuranceProduct
+		if p, err = loadRow(tx, "SELECT "+productColumns+" FROM product WHERE insurance_id = ?", scanProduct, key...); p != nil {
+			v, st.key, st.productID = p, fmt.Sprint(p.InsuranceId), p.InsuranceId
+			err = loadProductTags(tx, p)
+		}
+	case "coverage_limit":
+		var l *CoverageLimit
+		if l, err = loadRow(tx, "SELECT coverage_id, product_id, coverage_limit, remark, remark_zh FROM coverage_limit WHERE coverage_id = ? AND product_id = ?", scanCoverageLimit, key...); l != nil {
+			v, st.key, st.productID = l, coverageLimitKey(l.CoverageId, l.ProductId), l.ProductId
+		}
+	case "sub_coverage_limit":
+		var l *SubCoverageLimit
+		if l, err = loadRow(tx, "SELECT "+subCoverageColumns+" FROM sub_coverage_limit WHERE sub_coverage_id = ?", scanSubCoverageLimit, key...); l != nil {
+			v, st.key, st.productID = l, fmt.Sprint(l.SubCoverageId), l.ProductId
+		}
+	default:
+		return st, fmt.Errorf("%s is not a tracked table", table)
+	}
+	if err != nil || v == nil {
+		return st, err
+	}
+	st.data, err = json.Marshal(v)
+	return st, err
+}
+
+// loadProductTags sets p.Tags from product_tag.
+func loadProductTags(tx *sql.Tx, p *InsuranceProduct) error {
+	rows, err := tx.Query(productTagSelect+" WHERE pt.product_id = ?"+productTagOrder, p.InsuranceId)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	p.Tags = []Tag{}
+	for rows.Next() {
+		var t productTag
+		if err := scanProductTag(rows, &t); err != nil {
+			return err
+		}
+		p.Tags = append(p.Tags, t.Tag)
+	}
+	return rows.Err()
+}
+
+func coverageLimitKey(coverageID, productID int) string {
+	return fmt.Sprintf("%d/%d", coverageID, productID)
+}
+
+// TrackChange runs write, which must change at most the row of table
+// identified by key, and records the row's old and new value in
+// change_history under actor. Untracked tables and writes that leave the
+// row as it was record nothing. Tags are part of the product, so writes to
+// product_tag, keyed by product and tag, are recorded as product changes.
+func TrackChange(tx *sql.Tx, actor, table string, key []interface{}, write func() error) error {
+	log := NewChangeLog(tx, actor)
+	if err := log.Track(table, key, write); err != nil {
+		return err
+	}
+	return log.Flush()
+}
+
+// ChangeLog is TrackChange for a transaction that writes the same row
+// several times, such as a product and then each of its tags: every row
+// is recorded once, with its value before the first write and after the
+// last, when Flush is called.
+type ChangeLog struct {
+	tx     *sql.Tx
+	actor  string
+	before map[string]rowState
+	rows   []trackedRow // in order of first write
+}
+
+type trackedRow struct {
+	table string
+	key   []interface{}
+	id    string
+}
+
+// NewChangeLog starts a ChangeLog for tx under actor.
+func NewChangeLog(tx *sql.Tx, actor string) *ChangeLog {
+	return &ChangeLog{tx: tx, actor: actor, before: make(map[string]rowState)}
+}
+
+// Track runs write, which must change at most the row of table identified
+// by key, remembering the row's value the first time it is written.
+func (l *ChangeLog) Track(table string, key []interface{}, write func() error) error {
+	if table == "product_tag" {
+		table, key = "product", key[:1]
+	}
+	if !Tracked(table) {
+		return write()
+	}
+	id := table + ":" + fmt.Sprint(key...)
+	if _, ok := l.before[id]; !ok {
+		before, err := snapshot(l.tx, table, key)
+		if err != nil {
+			return err
+		}
+		l.before[id] = before
+		l.rows = append(l.rows, trackedRow{table: table, key: key, id: id})
+	}
+	return write()
+}
+
+// Flush records every row that was written and differs from its old value.
+func (l *ChangeLog) Flush() error {
+	for _, row := range l.rows {
+		if err := recordChange(l.tx, l.actor, row.table, row.key, l.before[row.id]); err != nil {
+			return err
+		}
+	}
+	l.rows, l.before = nil, make(map[string]rowState)
+	return nil
+}
+
+// recordChange compares the row identified by key with before and logs
+// the difference.
+func recordChange(tx *sql.Tx, actor, table string, key []interface{}, before rowState) error {
+	after, err := snapshot(tx, table, key)
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(before.data, after.data) {
+		return nil
+	}
+
+	action, st := "update", after
+	switch {
+	case before.data == nil:
+		action = "create"
+	case after.data == nil:
+		action, st = "delete", before
+	}
+	_, err = tx.Exec(`INSERT INTO change_history (table_name, record_key, product_id, action, old_value, new_value, changed_by, changed_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		table, st.key, st.productID, action, nullJSON(before.data), nullJSON(after.data), actor, time.Now().UTC().Format(historyTimeFormat))
+	return err
+}
+
+func nullJSON(data json.RawMessage) interface{} {
+	if data == nil {
+		return nil
+	}
+	return string(data)
+}
+
+// ProductHistory returns the recorded changes to a product and its limits,
+// newest first.
+func (r *InsuranceRepository) ProductHistory(productID int) ([]Change, error) {
+	rows, err := r.db.Query(`SELECT id, table_name, record_key, product_id, action, old_value, new_value, changed_by, changed_at
+		FROM change_history WHERE product_id = ? ORDER BY id DESC`, productID)
+	if err != nil {
+		return nil, fmt.Errorf("query change_history: %w", err)
+	}
+	defer rows.Close()
+
+	var out []Change
+	for rows.Next() {
+		var c Change
+		var oldValue, newValue sql.NullString
+		var changedAt string
+		if err := rows.Scan(&c.ID, &c.Table, &c.RecordKey, &c.ProductId, &c.Action, &oldValue, &newValue, &c.ChangedBy, &changedAt); err != nil {
+			return nil, fmt.Errorf("scan change_history row %d: %w", len(out)+1, err)
+		}
+		if c.ChangedAt, err = time.Parse(historyTimeFormat, changedAt); err != nil {
+			return nil, fmt.Errorf("change_history %d: %w", c.ID, err)
+		}
+		if oldValue.Valid {
+			c.OldValue = json.RawMessage(oldValue.String)
+		}
+		if newValue.Valid {
+			c.NewValue = json.RawMessage(newValue.String)
+		}
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
+
+// InsuranceReader is the read side of the insurance DB, implemented by the
+// live repository and by point-in-time snapshots.
+type InsuranceReader interface {
+	Companies() ([]InsuranceCompany, error)
+	Company(id int) (*InsuranceCompany, error)
+	Products() ([]InsuranceProduct, error)
+	Product(id int) (*InsuranceProduct, error)
+	CoverageList() ([]CoverageItem, error)
+	CoverageLimits() ([]CoverageLimit, error)
+	ProductCoverageLimits(productID int) ([]CoverageLimit, error)
+	CoverageLimitsByType(coverageType string) ([]CoverageLimit, error)
+	SubCoverageLimits() ([]SubCoverageLimit, error)
+	ProductSubCoverageLimits(productID int) ([]SubCoverageLimit, error)
+}
+
+var (
+	_ InsuranceReader = (*InsuranceRepository)(nil)
+	_ InsuranceReader = (*InsuranceSnapshot)(nil)
+)
+
+// InsuranceSnapshot is the product, coverage_limit and sub_coverage_limit
+// tables as they were at a point in time, rebuilt from change_history.
+// Providers and the coverage list aren't tracked and come from the live
+// repository.
+type InsuranceSnapshot struct {
+	repo      *InsuranceRepository
+	products  []InsuranceProduct
+	limits    []CoverageLimit
+	subLimits []SubCoverageLimit
+}
+
+// AsOf rebuilds the tracked tables as they were at t by undoing every
+// change recorded after it. Rows that were last changed before history
+// was kept are returned as they are now.
+func (r *InsuranceRepository) AsOf(t time.Time) (*InsuranceSnapshot, error) {
+	rows, err := r.db.Query("SELECT table_name, record_key, old_value FROM change_history WHERE changed_at > ? ORDER BY id",
+		t.UTC().Format(historyTimeFormat))
+	if err != nil {
+		return nil, fmt.Errorf("query change_history: %w", err)
+	}
+	defer rows.Close()
+
+	// The oldest change after t holds the row's value at t.
+	undo := make(map[string]sql.NullString)
+	for rows.Next() {
+		var table, key string
+		var oldValue sql.NullString
+		if err := rows.Scan(&table, &key, &oldValue); err != nil {
+			return nil, fmt.Errorf("scan change_history: %w", err)
+		}
+		if _, seen := undo[table+":"+key]; !seen {
+			undo[table+":"+key] = oldValue
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("query change_history: %w", err)
+	}
+
+	s := &InsuranceSnapshot{repo: r}
+	products, err := r.Products()
+	if err != nil {
+		return nil, err
+	}
+	if s.products, err = rewind(undo, "product", products, func(p InsuranceProduct) string {
+		return fmt.Sprint(p.InsuranceId)
+	}); err != nil {
+		return nil, err
+	}
+	sort.Slice(s.products, func(i, j int) bool { return s.products[i].InsuranceId < s.products[j].InsuranceId })
+	for i := range s.products {
+		// Products recorded before tags were structured have none.
+		if s.products[i].Tags == nil {
+			s.products[i].Tags = []Tag{}
+		}
+	}
+
+	limits, err := r.CoverageLimits()
+	if err != nil {
+		return nil, err
+	}
+	if s.limits, err = rewind(undo, "coverage_limit", limits, func(l CoverageLimit) string {
+		return coverageLimitKey(l.CoverageId, l.ProductId)
+	}); err != nil {
+		return nil, err
+	}
+	sort.Slice(s.limits, func(i, j int) bool {
+		a, b := s.limits[i], s.limits[j]
+		return a.ProductId < b.ProductId || a.ProductId == b.ProductId && a.CoverageId < b.CoverageId
+	})
+
+	subLimits, err := r.SubCoverageLimits()
+	if err != nil {
+		return nil, err
+	}
+	if s.subLimits, err = rewind(undo, "sub_coverage_limit", subLimits, func(l SubCoverageLimit) string {
+		return fmt.Sprint(l.SubCoverageId)
+	}); err != nil {
+		return nil, err
+	}
+	sort.Slice(s.subLimits, func(i, j int) bool { return s.subLimits[i].SubCoverageId < s.subLimits[j].SubCoverageId })
+	return s, nil
+}
+
+// rewind replaces the current rows of table with their values from undo,
+// dropping rows that didn't exist yet and restoring ones deleted since.
+func rewind[T any](undo map[string]sql.NullString, table string, current []T, key func(T) string) ([]T, error) {
+	prefix := table + ":"
+	out := make([]T, 0, len(current))
+	for _, row := range current {
+		if _, changed := undo[prefix+key(row)]; !changed {
+			out = append(out, row)
+		}
+	}
+	for k, old := range undo {
+		if !strings.HasPrefix(k, prefix) || !old.Valid {
+			continue
+		}
+		var row T
+		if err := json.Unmarshal([]byte(old.String), &row); err != nil {
+			return nil, fmt.Errorf("change_history %s: %w", k, err)
+		}
+		out = append(out, row)
+	}
+	return out, nil
+}
+
+// Companies returns every insurance provider as it is now.
+func (s *InsuranceSnapshot) Companies() ([]InsuranceCompany, error) { return s.repo.Companies() }
+
+// Company returns one provider as it is now, or ErrNotFound.
+func (s *InsuranceSnapshot) Company(id int) (*InsuranceCompany, error) { return s.repo.Company(id) }
+
+// CoverageList returns every coverage type as it is now.
+func (s *InsuranceSnapshot) CoverageList() ([]CoverageItem, error) { return s.repo.CoverageList() }
+
+// Products returns every product of the snapshot ordered by id.
+func (s *InsuranceSnapshot) Products() ([]InsuranceProduct, error) { return s.products, nil }
+
+// Product returns one product of the snapshot or ErrNotFound.
+func (s *InsuranceSnapshot) Product(id int) (*InsuranceProduct, error) {
+	for i := range s.products {
+		if s.products[i].InsuranceId == id {
+			p := s.products[i]
+			return &p, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// CoverageLimits returns every coverage_limit row of the snapshot.
+func (s *InsuranceSnapshot) CoverageLimits() ([]CoverageLimit, error) { return s.limits, nil }
+
+// ProductCoverageLimits returns the coverage limits of one product.
+func (s *InsuranceSnapshot) ProductCoverageLimits(productID int) ([]CoverageLimit, error) {
+	var out []CoverageLimit
+	for _, l := range s.limits {
+		if l.ProductId == productID {
+			out = append(out, l)
+		}
+	}
+	return out, nil
+}
+
+// CoverageLimitsByType returns every product's limit for the coverage type
+// named in English (case-insensitive) or Chinese.
+func (s *InsuranceSnapshot) CoverageLimitsByType(coverageType string) ([]CoverageLimit, error) {
+	list, err := s.repo.CoverageList()
+	if err != nil {
+		return nil, err
+	}
+	ids := make(map[int]bool)
+	for _, c := range list {
+		if strings.EqualFold(c.CoverageType, coverageType) || c.CoverageTypeZh.Valid && c.CoverageTypeZh.String == coverageType {
+			ids[c.CoverageId] = true
+		}
+	}
+	var out []CoverageLimit
+	for _, l := range s.limits {
+		if ids[l.CoverageId] {
+			out = append(out, l)
+		}
+	}
+	return out, nil
+}
+
+// SubCoverageLimits returns every sub_coverage_limit row of the snapshot.
+func (s *InsuranceSnapshot) SubCoverageLimits() ([]SubCoverageLimit, error) { return s.subLimits, nil }
+
+// ProductSubCoverageLimits returns the sub-limits of one product.
+func (s *InsuranceSnapshot) ProductSubCoverageLimits(productID int) ([]SubCoverageLimit, error) {
+	var out []SubCoverageLimit
+	for _, l := range s.subLimits {
+		if l.ProductId == productID {
+			out = append(out, l)
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,62 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestChangeLog(t *testing.T) {
+	repo := openSearchTestRepo(t)
+	tx, err := repo.db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec("INSERT INTO tag (tag_id, slug, label) VALUES (1, 'dental', 'Dental'), (2, 'cancer', 'Cancer')"); err != nil {
+		t.Fatal(err)
+	}
+
+	// A product written three times is recorded once; tags aren't tracked.
+	log := NewChangeLog(tx, "test")
+	writes := []struct {
+		table string
+		key   []interface{}
+		stmt  string
+	}{
+		{"product", []interface{}{1}, "UPDATE product SET remark = 'Basic cover' WHERE insurance_id = 1"},
+		{"product_tag", []interface{}{1, 1}, "INSERT INTO product_tag (product_id, tag_id) VALUES (1, 1)"},
+		{"product_tag", []interface{}{1, 2}, "INSERT INTO product_tag (product_id, tag_id) VALUES (1, 2)"},
+		{"coverage_limit", []interface{}{1, 2}, "UPDATE coverage_limit SET coverage_limit = '6000' WHERE coverage_id = 1 AND product_id = 2"},
+		{"tag", []interface{}{2}, "UPDATE tag SET label_zh = '癌症' WHERE tag_id = 2"},
+	}
+	for _, w := range writes {
+		err := log.Track(w.table, w.key, func() error {
+			_, err := tx.Exec(w.stmt)
+			return err
+		})
+		if err != nil {
+			t.Fatalf("%s: %v", w.stmt, err)
+		}
+	}
+	if err := log.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := tx.Query("SELECT table_name, record_key, action FROM change_history ORDER BY id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var got []string
+	for rows.Next() {
+		var table, key, action string
+		if err := rows.Scan(&table, &key, &action); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, table+"/"+key+"/"+action)
+	}
+	want := []string{"product/1/update", "coverage_limit/1/2/update"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("change_history = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,330 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/vf0429/Petwell_Backend/internal/config"
+	"github.com/vf0429/Petwell_Backend/internal/migrations"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// ErrNotFound is returned by the single-row repository lookups.
+var ErrNotFound = errors.New("record not found")
+
+const productColumns = `insurance_id, provider_id, insurance_name, insurance_name_zh, remark, remark_zh,
+	min_age, min_age_zh, max_age, max_age_zh, coinsurance, coinsurance_zh, suitable_pet_type, suitable_pet_type_zh,
+	cat_breed_type, cat_breed_type_zh, dog_breed_type, dog_breed_type_zh, breed_type_remark, breed_type_remark_zh,
+	payment_mode, payment_mode_zh, waiting_period, waiting_period_zh, information_link, information_link_zh, update_time`
+
+// productTagSelect lists the tags of every product in display order.
+const productTagSelect = `SELECT pt.product_id, t.tag_id, t.slug, t.label, t.label_zh, t.display_order
+	FROM product_tag pt JOIN tag t ON t.tag_id = pt.tag_id`
+
+const productTagOrder = " ORDER BY t.display_order, t.tag_id"
+
+const subCoverageColumns = `sub_coverage_id, parent_coverage_id, product_id, sub_coverage_name, sub_coverage_name_zh,
+	sub_limit, sub_coverage_remark, sub_coverage_remark_zh`
+
+// InsuranceRepository reads the insurance product tables. It holds one
+// connection pool and a set of prepared statements for the lifetime of
+// the server; all methods are safe for concurrent use.
+type InsuranceRepository struct {
+	db   *sql.DB
+	path string
+
+	companies         *sql.Stmt
+	company           *sql.Stmt
+	products          *sql.Stmt
+	product           *sql.Stmt
+	coverageList      *sql.Stmt
+	coverageLimits    *sql.Stmt
+	productLimits     *sql.Stmt
+	limitsByType      *sql.Stmt
+	subCoverageLimits *sql.Stmt
+	productSubLimits  *sql.Stmt
+	tags              *sql.Stmt
+	productTags       *sql.Stmt
+	oneProductTags    *sql.Stmt
+	premiums          *sql.Stmt
+}
+
+// OpenInsuranceRepository opens the insurance DB at cfg.InsuranceDBPath and
+// prepares every query up front, so a missing file or table fails at
+// startup rather than on the first request.
+func OpenInsuranceRepository(cfg *config.Config) (*InsuranceRepository, error) {
+	path := resolveDBPath(cfg.InsuranceDBPath)
+	// mode=rw refuses to create an empty database when the file is missing.
+	db, err := sql.Open("sqlite3", "file:"+path+"?mode=rw&_foreign_keys=on&_busy_timeout=5000")
+	if err != nil {
+		return nil, fmt.Errorf("open insurance db %s: %w", path, err)
+	}
+	db.SetMaxOpenConns(8)
+	db.SetMaxIdleConns(8)
+	db.SetConnMaxIdleTime(5 * time.Minute)
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("open insurance db %s: %w", path, err)
+	}
+
+	if err := migrations.Insurance.Check(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	productSelect := "SELECT " + productColumns + " FROM product"
+	repo := &InsuranceRepository{db: db, path: path}
+	statements := []struct {
+		stmt  **sql.Stmt
+		query string
+	}{
+		{&repo.companies, "SELECT company_id, company_name, company_name_zh, company_logo FROM insurance_provider ORDER BY company_id"},
+		{&repo.company, "SELECT company_id, company_name, company_name_zh, company_logo FROM insurance_provider WHERE company_id = ?"},
+		{&repo.products, productSelect + " ORDER BY insurance_id"},
+		{&repo.product, productSelect + " WHERE insurance_id = ?"},
+		{&repo.coverageList, "SELECT coverage_id, coverage_type, coverage_type_zh FROM coverage_list ORDER BY coverage_id"},
+		{&repo.coverageLimits, "SELECT coverage_id, product_id, coverage_limit, remark, remark_zh FROM coverage_limit"},
+		{&repo.productLimits, "SELECT coverage_id, product_id, coverage_limit, remark, remark_zh FROM coverage_limit WHERE product_id = ? ORDER BY coverage_id"},
+		{&repo.limitsByType, `SELECT cl.coverage_id, cl.product_id, cl.coverage_limit, cl.remark, cl.remark_zh FROM coverage_limit cl
+			JOIN coverage_list c ON c.coverage_id = cl.coverage_id
+			WHERE lower(c.coverage_type) = lower(?) OR c.coverage_type_zh = ?`},
+		{&repo.subCoverageLimits, "SELECT " + subCoverageColumns + " FROM sub_coverage_limit"},
+		{&repo.productSubLimits, "SELECT " + subCoverageColumns + " FROM sub_coverage_limit WHERE product_id = ? ORDER BY sub_coverage_id"},
+		{&repo.tags, `SELECT t.tag_id, t.slug, t.label, t.label_zh, t.display_order, count(pt.product_id) FROM tag t
+			LEFT JOIN product_tag pt ON pt.tag_id = t.tag_id GROUP BY t.tag_id ORDER BY t.display_order, t.tag_id`},
+		{&repo.productTags, productTagSelect + productTagOrder},
+		{&repo.oneProductTags, productTagSelect + " WHERE pt.product_id = ?" + productTagOrder},
+		{&repo.premiums, `SELECT premium_id, product_id, pet_type, min_age_weeks, max_age_weeks, breed_class, payment_mode, premium_hkd
+			FROM premium ORDER BY product_id, pet_type, min_age_weeks, breed_class, payment_mode`},
+	}
+	for _, s := range statements {
+		if *s.stmt, err = db.Prepare(s.query); err != nil {
+			repo.Close()
+			return nil, fmt.Errorf("prepare insurance query on %s: %w", path, err)
+		}
+	}
+
+	// Imports and admin writes keep the index current, but a freshly
+	// migrated file has none yet. Only then is it built here; everything
+	// but search works without it, e.g. on a read-only file.
+	missing, err := repo.searchIndexMissing()
+	if err != nil {
+		repo.Close()
+		return nil, fmt.Errorf("check search index of %s: %w", path, err)
+	}
+	if missing {
+		if err := repo.RebuildSearchIndex(); err != nil {
+			log.Printf("Search index of %s is empty and could not be built, search will find nothing: %v", path, err)
+		}
+	}
+	return repo, nil
+}
+
+// resolveDBPath finds a relative path in the working directory or, failing
+// that, next to the executable.
+func resolveDBPath(path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	if _, err := os.Stat(path); err == nil {
+		return path
+	}
+	if ex, err := os.Executable(); err == nil {
+		candidate := filepath.Join(filepath.Dir(ex), path)
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate
+		}
+	}
+	return path
+}
+
+// Path is the resolved location of the database file.
+func (r *InsuranceRepository) Path() string { return r.path }
+
+// Close releases the prepared statements and the pool.
+func (r *InsuranceRepository) Close() error {
+	for _, stmt := range []*sql.Stmt{r.companies, r.company, r.products, r.product, r.coverageList,
+		r.coverageLimits, r.productLimits, r.limitsByType, r.subCoverageLimits, r.productSubLimits,
+		r.tags, r.productTags, r.oneProductTags, r.premiums} {
+		if stmt != nil {
+			stmt.Close()
+		}
+	}
+	return r.db.Close()
+}
+
+// queryAll runs stmt and scans every row with scan. A row that fails to
+// scan aborts the query instead of being dropped.
+func queryAll[T any](stmt *sql.Stmt, table string, scan func(*sql.Rows, *T) error, args ...interface{}) ([]T, error) {
+	rows, err := stmt.Query(args...)
+	if err != nil {
+		return nil, fmt.Errorf("query %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	var out []T
+	for rows.Next() {
+		var v T
+		if err := scan(rows, &v); err != nil {
+			return nil, fmt.Errorf("scan %s row %d: %w", table, len(out)+1, err)
+		}
+		out = append(out, v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("query %s: %w", table, err)
+	}
+	return out, nil
+}
+
+func scanCompany(rows *sql.Rows, c *InsuranceCompany) error {
+	return rows.Scan(&c.CompanyId, &c.CompanyName, &c.CompanyNameZh, &c.CompanyLogo)
+}
+
+func scanProduct(rows *sql.Rows, p *InsuranceProduct) error {
+	return rows.Scan(&p.InsuranceId, &p.ProviderId, &p.InsuranceName, &p.InsuranceNameZh, &p.Remark, &p.RemarkZh,
+		&p.MinAge, &p.MinAgeZh, &p.MaxAge, &p.MaxAgeZh, &p.Coinsurance, &p.CoinsuranceZh, &p.SuitablePetType, &p.SuitablePetTypeZh,
+		&p.CatBreedType, &p.CatBreedTypeZh, &p.DogBreedType, &p.DogBreedTypeZh, &p.BreedTypeRemark, &p.BreedTypeRemarkZh,
+		&p.PaymentMode, &p.PaymentModeZh, &p.WaitingPeriod, &p.WaitingPeriodZh, &p.InformationLink, &p.InformationLinkZh, &p.UpdateTime)
+}
+
+func scanPremium(rows *sql.Rows, p *Premium) error {
+	return rows.Scan(&p.PremiumId, &p.ProductId, &p.PetType, &p.MinAgeWeeks, &p.MaxAgeWeeks, &p.BreedClass, &p.PaymentMode, &p.PremiumHKD)
+}
+
+// productTag is a row of productTagSelect.
+type productTag struct {
+	productID int
+	Tag
+}
+
+func scanProductTag(rows *sql.Rows, t *productTag) error {
+	return rows.Scan(&t.productID, &t.TagId, &t.Slug, &t.Label, &t.LabelZh, &t.DisplayOrder)
+}
+
+func scanTagCount(rows *sql.Rows, t *TagCount) error {
+	return rows.Scan(&t.TagId, &t.Slug, &t.Label, &t.LabelZh, &t.DisplayOrder, &t.ProductCount)
+}
+
+// attachTags sets the Tags of each product from rows of productTagSelect.
+// Products without tags get an empty list.
+func attachTags(products []InsuranceProduct, tags []productTag) {
+	byProduct := make(map[int][]Tag)
+	for _, t := range tags {
+		byProduct[t.productID] = append(byProduct[t.productID], t.Tag)
+	}
+	for i := range products {
+		products[i].Tags = byProduct[products[i].InsuranceId]
+		if products[i].Tags == nil {
+			products[i].Tags = []Tag{}
+		}
+	}
+}
+
+func scanCoverageItem(rows *sql.Rows, c *CoverageItem) error {
+	return rows.Scan(&c.CoverageId, &c.CoverageType, &c.CoverageTypeZh)
+}
+
+func scanCoverageLimit(rows *sql.Rows, l *CoverageLimit) error {
+	return rows.Scan(&l.CoverageId, &l.ProductId, &l.CoverageLimit, &l.Remark, &l.RemarkZh)
+}
+
+func scanSubCoverageLimit(rows *sql.Rows, l *SubCoverageLimit) error {
+	return rows.Scan(&l.SubCoverageId, &l.ParentCoverageId, &l.ProductId, &l.SubCoverageName, &l.SubCoverageNameZh,
+		&l.SubLimit, &l.SubCoverageRemark, &l.SubCoverageRemarkZh)
+}
+
+// first returns the only row of a single-row lookup or ErrNotFound.
+func first[T any](rows []T, err error) (*T, error) {
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, ErrNotFound
+	}
+	return &rows[0], nil
+}
+
+// Companies returns every insurance provider.
+func (r *InsuranceRepository) Companies() ([]InsuranceCompany, error) {
+	return queryAll(r.companies, "insurance_provider", scanCompany)
+}
+
+// Company returns one provider or ErrNotFound.
+func (r *InsuranceRepository) Company(id int) (*InsuranceCompany, error) {
+	return first(queryAll(r.company, "insurance_provider", scanCompany, id))
+}
+
+// Products returns every product with its tags, ordered by id.
+func (r *InsuranceRepository) Products() ([]InsuranceProduct, error) {
+	products, err := queryAll(r.products, "product", scanProduct)
+	if err != nil {
+		return nil, err
+	}
+	tags, err := queryAll(r.productTags, "product_tag", scanProductTag)
+	if err != nil {
+		return nil, err
+	}
+	attachTags(products, tags)
+	return products, nil
+}
+
+// Product returns one product with its tags or ErrNotFound.
+func (r *InsuranceRepository) Product(id int) (*InsuranceProduct, error) {
+	products, err := queryAll(r.product, "product", scanProduct, id)
+	if err != nil || len(products) == 0 {
+		return first(products, err)
+	}
+	tags, err := queryAll(r.oneProductTags, "product_tag", scanProductTag, id)
+	if err != nil {
+		return nil, err
+	}
+	attachTags(products, tags)
+	return &products[0], nil
+}
+
+// Premiums returns every premium table row, grouped by product.
+func (r *InsuranceRepository) Premiums() ([]Premium, error) {
+	return queryAll(r.premiums, "premium", scanPremium)
+}
+
+// Tags returns the tag taxonomy in display order with product counts.
+func (r *InsuranceRepository) Tags() ([]TagCount, error) {
+	return queryAll(r.tags, "tag", scanTagCount)
+}
+
+// CoverageList returns every coverage type.
+func (r *InsuranceRepository) CoverageList() ([]CoverageItem, error) {
+	return queryAll(r.coverageList, "coverage_list", scanCoverageItem)
+}
+
+// CoverageLimits returns every coverage_limit row.
+func (r *InsuranceRepository) CoverageLimits() ([]CoverageLimit, error) {
+	return queryAll(r.coverageLimits, "coverage_limit", scanCoverageLimit)
+}
+
+// ProductCoverageLimits returns the coverage limits of one product.
+func (r *InsuranceRepository) ProductCoverageLimits(productID int) ([]CoverageLimit, error) {
+	return queryAll(r.productLimits, "coverage_limit", scanCoverageLimit, productID)
+}
+
+// CoverageLimitsByType returns every product's limit for the coverage type
+// named in English (case-insensitive) or Chinese.
+func (r *InsuranceRepository) CoverageLimitsByType(coverageType string) ([]CoverageLimit, error) {
+	return queryAll(r.limitsByType, "coverage_limit", scanCoverageLimit, coverageType, coverageType)
+}
+
+// SubCoverageLimits returns every sub_coverage_limit row.
+func (r *InsuranceRepository) SubCoverageLimits() ([]SubCoverageLimit, error) {
+	return queryAll(r.subCoverageLimits, "sub_coverage_limit", scanSubCoverageLimit)
+}
+
+// ProductSubCoverageLimits returns the sub-limits of one product.
+func (r *InsuranceRepository) ProductSubCoverageLimits(productID int) ([]SubCoverageLimit, error) {
+	return queryAll(r.productSubLimits, "sub_coverage_limit", scanSubCoverageLimit, productID)
+}
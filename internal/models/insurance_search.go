@@ -0,0 +1,348 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ErrEmptySearch is returned by Search for a query without any words.
+var ErrEmptySearch = errors.New("search query has no words")
+
+// Snippet highlighting around the matched terms.
+const (
+	highlightOpen  = "<mark>"
+	highlightClose = "</mark>"
+	snippetContext = 30 // runes of context on each side of the first match
+)
+
+// SearchHit is one indexed text field that matched a search.
+type SearchHit struct {
+	Source     string `json:"source"` // product, tag, coverage_list, coverage_limit or sub_coverage_limit
+	Field      string `json:"field"`
+	RecordKey  string `json:"record_key"`
+	ProductIds []int  `json:"product_ids"`
+	Snippet    string `json:"snippet"`
+}
+
+// searchSources lists the indexed columns of each table. Every query
+// returns the row's key, the products it belongs to as a comma-separated
+// list, then the columns in order.
+var searchSources = []struct {
+	table   string
+	columns []string
+	query   string
+}{
+	{"product", []string{"insurance_name", "insurance_name_zh", "remark", "remark_zh"},
+		"SELECT insurance_id, insurance_id, insurance_name, insurance_name_zh, remark, remark_zh FROM product"},
+	{"tag", []string{"label", "label_zh"},
+		`SELECT t.tag_id, (SELECT group_concat(product_id) FROM product_tag pt WHERE pt.tag_id = t.tag_id),
+			t.label, t.label_zh FROM tag t`},
+	{"coverage_list", []string{"coverage_type", "coverage_type_zh"},
+		`SELECT c.coverage_id, (SELECT group_concat(product_id) FROM coverage_limit l WHERE l.coverage_id = c.coverage_id),
+			c.coverage_type, c.coverage_type_zh FROM coverage_list c`},
+	{"coverage_limit", []string{"remark", "remark_zh"},
+		"SELECT coverage_id || '/' || product_id, product_id, remark, remark_zh FROM coverage_limit"},
+	{"sub_coverage_limit", []string{"sub_coverage_name", "sub_coverage_name_zh", "sub_coverage_remark", "sub_coverage_remark_zh"},
+		"SELECT sub_coverage_id, product_id, sub_coverage_name, sub_coverage_name_zh, sub_coverage_remark, sub_coverage_remark_zh FROM sub_coverage_limit"},
+}
+
+// RebuildSearchIndex refills search_index from the insurance tables. The
+// tables are small enough that every write simply rebuilds it in the same
+// transaction.
+func RebuildSearchIndex(tx *sql.Tx) error {
+	if _, err := tx.Exec("DELETE FROM search_index"); err != nil {
+		return fmt.Errorf("clear search_index: %w", err)
+	}
+	insert, err := tx.Prepare("INSERT INTO search_index (body, source, field, record_key, product_ids, text) VALUES (?, ?, ?, ?, ?, ?)")
+	if err != nil {
+		return fmt.Errorf("prepare search_index insert: %w", err)
+	}
+	defer insert.Close()
+
+	for _, src := range searchSources {
+		rows, err := tx.Query(src.query)
+		if err != nil {
+			return fmt.Errorf("index %s: %w", src.table, err)
+		}
+		type indexRow struct {
+			key, products string
+			text          []sql.NullString
+		}
+		var pending []indexRow
+		for rows.Next() {
+			row := indexRow{text: make([]sql.NullString, len(src.columns))}
+			var products sql.NullString
+			dest := []interface{}{&row.key, &products}
+			for i := range row.text {
+				dest = append(dest, &row.text[i])
+			}
+			if err := rows.Scan(dest...); err != nil {
+				rows.Close()
+				return fmt.Errorf("index %s: %w", src.table, err)
+			}
+			row.products = products.String
+			pending = append(pending, row)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("index %s: %w", src.table, err)
+		}
+
+		for _, row := range pending {
+			for i, text := range row.text {
+				if strings.TrimSpace(text.String) == "" {
+					continue
+				}
+				if _, err := insert.Exec(segment(text.String), src.table, src.columns[i], row.key, row.products, text.String); err != nil {
+					return fmt.Errorf("index %s %s: %w", src.table, row.key, err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// RebuildSearchIndex refills the search index in its own transaction.
+func (r *InsuranceRepository) RebuildSearchIndex() error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := RebuildSearchIndex(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// searchIndexMissing reports whether the index is empty although there
+// are products to index.
+func (r *InsuranceRepository) searchIndexMissing() (bool, error) {
+	var missing bool
+	err := r.db.QueryRow("SELECT NOT EXISTS (SELECT 1 FROM search_index) AND EXISTS (SELECT 1 FROM product)").Scan(&missing)
+	return missing, err
+}
+
+// Search runs q against the index and returns up to limit hits, best
+// first. Latin words match as prefixes, case-insensitively; a run of CJK
+// characters matches that exact sequence. All words must match.
+func (r *InsuranceRepository) Search(q string, limit int) ([]SearchHit, error) {
+	terms := searchTerms(q)
+	if len(terms) == 0 {
+		return nil, ErrEmptySearch
+	}
+	// FTS4 has no rank column; matches are scored here from matchinfo.
+	rows, err := r.db.Query(`SELECT source, field, record_key, product_ids, text, matchinfo(search_index, 'pcnalx')
+		FROM search_index WHERE search_index MATCH ?`, matchExpression(terms))
+	if err != nil {
+		return nil, fmt.Errorf("query search_index: %w", err)
+	}
+	defer rows.Close()
+
+	type scoredHit struct {
+		SearchHit
+		score float64
+	}
+	var scored []scoredHit
+	for rows.Next() {
+		var h scoredHit
+		var products, text string
+		var info []byte
+		if err := rows.Scan(&h.Source, &h.Field, &h.RecordKey, &products, &text, &info); err != nil {
+			return nil, fmt.Errorf("scan search_index row %d: %w", len(scored)+1, err)
+		}
+		h.ProductIds = parseProductIDs(products)
+		h.Snippet = highlight(text, terms)
+		h.score = bm25(info)
+		scored = append(scored, h)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(scored, func(i, j int) bool { return scored[i].score > scored[j].score })
+	hits := make([]SearchHit, 0, min(limit, len(scored)))
+	for _, h := range scored[:min(limit, len(scored))] {
+		hits = append(hits, h.SearchHit)
+	}
+	return hits, nil
+}
+
+// BM25 parameters, the ones FTS5's built-in rank uses.
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// bm25 scores a row from its matchinfo(search_index, 'pcnalx') blob over
+// the indexed body column; higher is better.
+func bm25(info []byte) float64 {
+	v := make([]float64, len(info)/4)
+	for i := range v {
+		v[i] = float64(binary.NativeEndian.Uint32(info[i*4:]))
+	}
+	if len(v) < 3 {
+		return 0
+	}
+	phrases, columns, rows := int(v[0]), int(v[1]), v[2]
+	avgLen, rowLen := v[3], v[3+columns]
+	hits := v[3+2*columns:]
+	if len(hits) < 3*phrases*columns || avgLen == 0 {
+		return 0
+	}
+
+	score := 0.0
+	for p := 0; p < phrases; p++ {
+		tf, docs := hits[3*p*columns], hits[3*p*columns+2]
+		idf := math.Log((rows - docs + 0.5) / (docs + 0.5))
+		if idf <= 0 {
+			idf = 1e-6
+		}
+		score += idf * tf * (bm25K1 + 1) / (tf + bm25K1*(1-bm25B+bm25B*rowLen/avgLen))
+	}
+	return score
+}
+
+func parseProductIDs(s string) []int {
+	ids := []int{}
+	for _, part := range strings.Split(s, ",") {
+		if id, err := strconv.Atoi(strings.TrimSpace(part)); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// isCJK reports whether r is written without spaces between words, so
+// each character has to be its own token.
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
+
+// segment puts spaces around every CJK character, so unicode61 indexes
+// "遺傳病" as three tokens a phrase query can match in sequence.
+func segment(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		if isCJK(r) {
+			b.WriteByte(' ')
+			b.WriteRune(r)
+			b.WriteByte(' ')
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// searchTerm is a Latin word or a run of CJK characters from the query.
+type searchTerm struct {
+	text string
+	cjk  bool
+}
+
+// searchTerms splits q into words and CJK runs, dropping punctuation so
+// user input can't inject full-text query syntax.
+func searchTerms(q string) []searchTerm {
+	var terms []searchTerm
+	var cur []rune
+	cjk := false
+	flush := func() {
+		if len(cur) > 0 {
+			terms = append(terms, searchTerm{text: string(cur), cjk: cjk})
+			cur = nil
+		}
+	}
+	for _, r := range q {
+		switch {
+		case isCJK(r):
+			if !cjk {
+				flush()
+			}
+			cjk = true
+			cur = append(cur, r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if cjk {
+				flush()
+			}
+			cjk = false
+			cur = append(cur, r)
+		default:
+			flush()
+		}
+	}
+	flush()
+	return terms
+}
+
+// matchExpression ANDs the terms: words as prefix queries, CJK runs as
+// phrases of single characters.
+func matchExpression(terms []searchTerm) string {
+	parts := make([]string, len(terms))
+	for i, t := range terms {
+		if t.cjk {
+			parts[i] = `"` + strings.Join(strings.Split(t.text, ""), " ") + `"`
+		} else {
+			parts[i] = `"` + t.text + `*"`
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// highlight marks every occurrence of the terms in text and trims it to
+// the context around the first one.
+func highlight(text string, terms []searchTerm) string {
+	runes := []rune(text)
+	lower := make([]rune, len(runes))
+	for i, r := range runes {
+		lower[i] = unicode.ToLower(r)
+	}
+
+	marked := make([]bool, len(runes))
+	for _, t := range terms {
+		needle := []rune(strings.ToLower(t.text))
+		for i := 0; i+len(needle) <= len(lower); i++ {
+			if string(lower[i:i+len(needle)]) == string(needle) {
+				for j := i; j < i+len(needle); j++ {
+					marked[j] = true
+				}
+			}
+		}
+	}
+
+	firstMatch := 0
+	for i, m := range marked {
+		if m {
+			firstMatch = i
+			break
+		}
+	}
+	start := max(0, firstMatch-snippetContext)
+	end := min(len(runes), firstMatch+3*snippetContext)
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	for i := start; i < end; i++ {
+		if marked[i] && (i == start || !marked[i-1]) {
+			b.WriteString(highlightOpen)
+		}
+		b.WriteRune(runes[i])
+		if marked[i] && (i == end-1 || !marked[i+1]) {
+			b.WriteString(highlightClose)
+		}
+	}
+	if end < len(runes) {
+		b.WriteString("…")
+	}
+	return b.String()
+}
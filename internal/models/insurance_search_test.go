@@ -0,0 +1,123 @@
+package models
+
+import (
+	"database/sql"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/vf0429/Petwell_Backend/internal/config"
+	"github.com/vf0429/Petwell_Backend/internal/migrations"
+)
+
+func TestSegment(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"", ""},
+		{"Hereditary disease", "Hereditary disease"},
+		{"遺傳病", " 遺  傳  病 "},
+		{"MRI掃描", "MRI 掃  描 "},
+		{"HK$5,000 (每年)", "HK$5,000 ( 每  年 )"},
+	}
+	for _, tt := range tests {
+		if got := segment(tt.in); got != tt.want {
+			t.Errorf("segment(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestMatchExpression(t *testing.T) {
+	tests := []struct {
+		q     string
+		terms []searchTerm
+		want  string
+	}{
+		{"", nil, ""},
+		{"dental", []searchTerm{{"dental", false}}, `"dental*"`},
+		{"Dental cleaning", []searchTerm{{"Dental", false}, {"cleaning", false}}, `"Dental*" "cleaning*"`},
+		{"遺傳病", []searchTerm{{"遺傳病", true}}, `"遺 傳 病"`},
+		{"MRI掃描", []searchTerm{{"MRI", false}, {"掃描", true}}, `"MRI*" "掃 描"`},
+		// Quotes, operators and column filters are dropped, not passed to FTS4.
+		{`"cancer" OR name:x*`, []searchTerm{{"cancer", false}, {"OR", false}, {"name", false}, {"x", false}}, `"cancer*" "OR*" "name*" "x*"`},
+	}
+	for _, tt := range tests {
+		terms := searchTerms(tt.q)
+		if !reflect.DeepEqual(terms, tt.terms) {
+			t.Errorf("searchTerms(%q) = %+v, want %+v", tt.q, terms, tt.terms)
+			continue
+		}
+		if got := matchExpression(terms); got != tt.want {
+			t.Errorf("matchExpression(%q) = %s, want %s", tt.q, got, tt.want)
+		}
+	}
+}
+
+// openSearchTestRepo builds an insurance DB in a temp dir with a few
+// products and opens a repository on it.
+func openSearchTestRepo(t *testing.T) *InsuranceRepository {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "insurance.db")
+	db, err := sql.Open("sqlite3", "file:"+path+"?_foreign_keys=on")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := migrations.Insurance.Up(db); err != nil {
+		t.Fatal(err)
+	}
+	for _, stmt := range []string{
+		"INSERT INTO insurance_provider (company_id, company_name) VALUES (1, 'OneDegree')",
+		"INSERT INTO product (insurance_id, provider_id, insurance_name, insurance_name_zh, remark) VALUES (1, 1, 'Essential Plan', '精選計劃', NULL)",
+		"INSERT INTO product (insurance_id, provider_id, insurance_name, insurance_name_zh, remark) VALUES (2, 1, 'Prestige Plan', '尊寵計劃', 'Covers hereditary disease and dental treatment for hereditary conditions')",
+		"INSERT INTO coverage_list (coverage_id, coverage_type, coverage_type_zh) VALUES (1, 'Hereditary Disease', '遺傳病')",
+		"INSERT INTO coverage_limit (coverage_id, product_id, coverage_limit) VALUES (1, 2, '5000')",
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+
+	// The index is still empty, so opening the repository builds it.
+	repo, err := OpenInsuranceRepository(&config.Config{InsuranceDBPath: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { repo.Close() })
+	return repo
+}
+
+func TestSearch(t *testing.T) {
+	repo := openSearchTestRepo(t)
+	tests := []struct {
+		q    string
+		want []string // source/field/record_key of the hits, best first
+	}{
+		{"essent", []string{"product/insurance_name/1"}},
+		{"PLAN", []string{"product/insurance_name/1", "product/insurance_name/2"}},
+		{"hereditary", []string{"coverage_list/coverage_type/1", "product/remark/2"}},
+		{"hereditary dental", []string{"product/remark/2"}},
+		{"遺傳", []string{"coverage_list/coverage_type_zh/1"}},
+		{"傳遺", nil},
+		{"計劃", []string{"product/insurance_name_zh/1", "product/insurance_name_zh/2"}},
+		{"dental 遺傳", nil},
+	}
+	for _, tt := range tests {
+		hits, err := repo.Search(tt.q, 10)
+		if err != nil {
+			t.Fatalf("Search(%q): %v", tt.q, err)
+		}
+		var got []string
+		for _, h := range hits {
+			got = append(got, h.Source+"/"+h.Field+"/"+h.RecordKey)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Search(%q) = %v, want %v", tt.q, got, tt.want)
+		}
+	}
+
+	if hits, _ := repo.Search("plan", 1); len(hits) != 1 {
+		t.Errorf("Search with limit 1 returned %d hits", len(hits))
+	}
+	if _, err := repo.Search(" ,.! ", 10); err != ErrEmptySearch {
+		t.Errorf("Search of punctuation = %v, want ErrEmptySearch", err)
+	}
+}
@@ -0,0 +1,89 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// Problems CheckInsurerProducts reports for a scenario insurer.
+const (
+	InsurerUnlinked        = "unlinked"         // no product_id
+	InsurerProductMissing  = "product_missing"  // product_id isn't in the insurance DB
+	InsurerCompanyMismatch = "company_mismatch" // company_id isn't the product's provider
+)
+
+// InsurerProductIssue is a scenario insurer without a matching insurance
+// product.
+type InsurerProductIssue struct {
+	InsurerID string `json:"insurer_id"`
+	Name      string `json:"name"`
+	PlanName  string `json:"plan_name"`
+	CompanyID *int   `json:"company_id"`
+	ProductID *int   `json:"product_id"`
+	Problem   string `json:"problem"`
+	Message   string `json:"message"`
+}
+
+// linkProduct checks i.ProductID against the insurance DB and sets
+// i.CompanyID to the product's provider. An insurer may stay unlinked.
+func linkProduct(repo *InsuranceRepository, i *Insurer) error {
+	if i.ProductID == nil {
+		i.CompanyID = nil
+		return nil
+	}
+	product, err := repo.Product(*i.ProductID)
+	if errors.Is(err, ErrNotFound) {
+		return &ValidationError{Field: "product_id", Message: fmt.Sprintf("product %d does not exist", *i.ProductID)}
+	}
+	if err != nil {
+		return err
+	}
+	companyID := product.ProviderId
+	i.CompanyID = &companyID
+	return nil
+}
+
+// CheckInsurerProducts lists the scenario insurers that aren't linked to a
+// product, or whose product no longer exists or has moved to another
+// company, ordered by insurer id.
+func CheckInsurerProducts(db *gorm.DB, repo *InsuranceRepository) ([]InsurerProductIssue, error) {
+	var insurers []Insurer
+	if err := db.Order("id").Find(&insurers).Error; err != nil {
+		return nil, err
+	}
+	products, err := repo.Products()
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[int]InsuranceProduct, len(products))
+	for _, p := range products {
+		byID[p.InsuranceId] = p
+	}
+
+	issues := []InsurerProductIssue{}
+	for _, i := range insurers {
+		issue := InsurerProductIssue{InsurerID: i.ID, Name: i.Name, PlanName: i.PlanName, CompanyID: i.CompanyID, ProductID: i.ProductID}
+		if i.ProductID == nil {
+			issue.Problem = InsurerUnlinked
+			issue.Message = "not linked to an insurance product"
+			issues = append(issues, issue)
+			continue
+		}
+		product, ok := byID[*i.ProductID]
+		switch {
+		case !ok:
+			issue.Problem = InsurerProductMissing
+			issue.Message = fmt.Sprintf("product %d does not exist", *i.ProductID)
+		case i.CompanyID == nil || *i.CompanyID != product.ProviderId:
+			issue.Problem = InsurerCompanyMismatch
+			issue.Message = fmt.Sprintf("product %d (%s) belongs to company %d", product.InsuranceId, strings.TrimSpace(product.InsuranceName), product.ProviderId)
+		default:
+			continue
+		}
+		issues = append(issues, issue)
+	}
+	return issues, nil
+}
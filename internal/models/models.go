@@ -19,6 +19,18 @@ func (ns NullJsonString) MarshalJSON() ([]byte, error) {
 	return json.Marshal(ns.String)
 }
 
+func (ns *NullJsonString) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		ns.String, ns.Valid = "", false
+		return nil
+	}
+	if err := json.Unmarshal(data, &ns.String); err != nil {
+		return err
+	}
+	ns.Valid = true
+	return nil
+}
+
 // --- Models ---
 
 type User struct {
@@ -38,25 +50,6 @@ type BlogPost struct {
 	Timestamp    time.Time `json:"timestamp"`
 }
 
-type Clinic struct {
-	ClinicID       string `json:"clinic_id"`
-	Name           string `json:"name"`
-	Address        string `json:"address"`
-	PhoneRegular   string `json:"phone_regular"`
-	PhoneEmergency string `json:"phone_emergency"`
-	Whatsapp       string `json:"whatsapp"`
-	OpeningHours   string `json:"opening_hours"`
-	Emergency24h   string `json:"emergency_24h"`
-	WebsiteURL     string `json:"website_url"`
-	ApplemapURL    string `json:"applemap_url"`
-	Latitude       string `json:"latitude"`
-	Longitude      string `json:"longitude"`
-	Rating          string `json:"rating"`
-	PhotoURL        string `json:"photo_url"`
-	GooglePlaceID   string `json:"google_place_id"`
-	PhotoReference  string `json:"photo_reference"`
-}
-
 // --- Insurance Models ---
 
 type InsuranceCompany struct {
@@ -94,8 +87,22 @@ type InsuranceProduct struct {
 	InformationLink   NullJsonString `json:"information_link,omitempty"`
 	InformationLinkZh NullJsonString `json:"information_link_zh,omitempty"`
 	UpdateTime        NullJsonString `json:"update_time,omitempty"`
-	Tag               NullJsonString `json:"tag,omitempty"`
-	TagZh             NullJsonString `json:"tag_zh,omitempty"`
+	Tags              []Tag          `json:"tags"`
+}
+
+// Tag is an entry of the product tag taxonomy, shown as a chip in the app.
+type Tag struct {
+	TagId        int            `json:"tag_id"`
+	Slug         string         `json:"slug"`
+	Label        string         `json:"label"`
+	LabelZh      NullJsonString `json:"label_zh,omitempty"`
+	DisplayOrder int            `json:"display_order"`
+}
+
+// TagCount is a tag with the number of products carrying it.
+type TagCount struct {
+	Tag
+	ProductCount int `json:"product_count"`
 }
 
 type CoverageItem struct {
@@ -123,6 +130,19 @@ type SubCoverageLimit struct {
 	SubCoverageRemarkZh NullJsonString `json:"sub_coverage_remark_zh,omitempty"`
 }
 
+// Premium is one row of a product's premium table: the price for a pet
+// type, age band and breed class under one payment mode.
+type Premium struct {
+	PremiumId   int     `json:"premium_id"`
+	ProductId   int     `json:"product_id"`
+	PetType     string  `json:"pet_type"` // cat or dog
+	MinAgeWeeks int     `json:"min_age_weeks"`
+	MaxAgeWeeks *int    `json:"max_age_weeks"` // inclusive, nil for no upper bound
+	BreedClass  string  `json:"breed_class"`   // any, mixed, purebred or large
+	PaymentMode string  `json:"payment_mode"`  // monthly or annual
+	PremiumHKD  float64 `json:"premium_hkd"`
+}
+
 type ServiceSubcategory struct {
 	ID           int    `json:"id"`
 	Name         string `json:"name"`
@@ -131,16 +151,18 @@ type ServiceSubcategory struct {
 
 // --- Legacy Models (from old models/models.go) ---
 
+// PetInsuranceComparison is one plan as /insurance-providers served it
+// before the product tables existed.
 type PetInsuranceComparison struct {
-	ID                            int             `json:"id" db:"id"`
-	InsuranceProvider             string          `json:"insurance_provider" db:"insurance_provider"`
-	ProviderKey                   string          `json:"provider_key" db:"provider_key"`
-	Category                      string          `json:"category" db:"category"`
-	Subcategory                   string          `json:"subcategory" db:"subcategory"`
-	CoveragePercentage            string          `json:"coverage_percentage" db:"coverage_percentage"`
-	CancerCash                    sql.NullFloat64 `json:"cancer_cash" db:"cancer_cash"`
-	CancerCashNotes               sql.NullString  `json:"cancer_cash_notes" db:"cancer_cash_notes"`
-	AdditionalCriticalCashBenefit sql.NullFloat64 `json:"additional_critical_cash_benefit" db:"additional_critical_cash_benefit"`
+	ID                            int            `json:"id" db:"id"`
+	InsuranceProvider             string         `json:"insurance_provider" db:"insurance_provider"`
+	ProviderKey                   string         `json:"provider_key" db:"provider_key"`
+	Category                      string         `json:"category" db:"category"`
+	Subcategory                   string         `json:"subcategory" db:"subcategory"`
+	CoveragePercentage            string         `json:"coverage_percentage" db:"coverage_percentage"`
+	CancerCash                    *float64       `json:"cancer_cash" db:"cancer_cash"`
+	CancerCashNotes               NullJsonString `json:"cancer_cash_notes" db:"cancer_cash_notes"`
+	AdditionalCriticalCashBenefit *float64       `json:"additional_critical_cash_benefit" db:"additional_critical_cash_benefit"`
 }
 
 type LegacyCoverageLimit struct {
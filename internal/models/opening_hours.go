@@ -0,0 +1,346 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const minutesPerDay = 24 * 60
+
+// Interval is a span a clinic is open, in minutes after midnight Hong Kong
+// time. Close is after Open; past 24:00 it runs into the next day.
+type Interval struct {
+	Open  int
+	Close int
+}
+
+func (i Interval) MarshalJSON() ([]byte, error) {
+	clock := func(m int) string { return fmt.Sprintf("%02d:%02d", m/60, m%60) }
+	end := i.Close
+	if end > minutesPerDay {
+		end -= minutesPerDay
+	}
+	return json.Marshal(struct {
+		Open      string `json:"open"`
+		Close     string `json:"close"`
+		Overnight bool   `json:"overnight,omitempty"`
+	}{clock(i.Open), clock(end), i.Close > minutesPerDay})
+}
+
+// DayHours is what a clinic opens on one day; empty when it is closed.
+type DayHours []Interval
+
+// OpeningHours is a clinic's weekly schedule, indexed by time.Weekday.
+// PublicHoliday replaces the weekday's hours on public holidays; nil when
+// the clinic keeps its usual hours, empty when it closes.
+type OpeningHours struct {
+	Week          [7]DayHours
+	PublicHoliday DayHours
+}
+
+func (h *OpeningHours) MarshalJSON() ([]byte, error) {
+	day := func(d DayHours) DayHours {
+		if d == nil {
+			return DayHours{}
+		}
+		return d
+	}
+	var holiday *DayHours
+	if h.PublicHoliday != nil {
+		holiday = &h.PublicHoliday
+	}
+	return json.Marshal(struct {
+		Monday        DayHours  `json:"monday"`
+		Tuesday       DayHours  `json:"tuesday"`
+		Wednesday     DayHours  `json:"wednesday"`
+		Thursday      DayHours  `json:"thursday"`
+		Friday        DayHours  `json:"friday"`
+		Saturday      DayHours  `json:"saturday"`
+		Sunday        DayHours  `json:"sunday"`
+		PublicHoliday *DayHours `json:"public_holiday,omitempty"`
+	}{
+		day(h.Week[time.Monday]), day(h.Week[time.Tuesday]), day(h.Week[time.Wednesday]), day(h.Week[time.Thursday]),
+		day(h.Week[time.Friday]), day(h.Week[time.Saturday]), day(h.Week[time.Sunday]), holiday,
+	})
+}
+
+// hkDate is midnight of t's date in Hong Kong.
+func hkDate(t time.Time) time.Time {
+	t = t.In(HongKong)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, HongKong)
+}
+
+// HoursOn is the hours that apply on date's Hong Kong date: the public
+// holiday hours when holiday says it is one and the clinic has them, the
+// weekday's otherwise. holiday may be nil, treating every date as an
+// ordinary day.
+func (h *OpeningHours) HoursOn(date time.Time, holiday func(time.Time) bool) DayHours {
+	date = hkDate(date)
+	if h.PublicHoliday != nil && holiday != nil && holiday(date) {
+		return h.PublicHoliday
+	}
+	return h.Week[date.Weekday()]
+}
+
+// OpenAt reports whether the clinic is open at t, counting overnight spans
+// from the day before. holiday is as for HoursOn.
+func (h *OpeningHours) OpenAt(t time.Time, holiday func(time.Time) bool) bool {
+	today := hkDate(t)
+	minute := int(t.Sub(today) / time.Minute)
+	for _, i := range h.HoursOn(today, holiday) {
+		if i.Open <= minute && minute < i.Close {
+			return true
+		}
+	}
+	for _, i := range h.HoursOn(today.AddDate(0, 0, -1), holiday) {
+		if minute+minutesPerDay < i.Close {
+			return true
+		}
+	}
+	return false
+}
+
+// OpenAllDay reports whether the clinic is open from 00:00 to 24:00 on
+// date's Hong Kong date. holiday is as for HoursOn.
+func (h *OpeningHours) OpenAllDay(date time.Time, holiday func(time.Time) bool) bool {
+	date = hkDate(date)
+	return coversDay(h.HoursOn(date, holiday), h.HoursOn(date.AddDate(0, 0, -1), holiday))
+}
+
+// AlwaysOpen reports whether the schedule leaves no gap in the week, and
+// none on public holidays when it has holiday hours.
+func (h *OpeningHours) AlwaysOpen() bool {
+	for d := range h.Week {
+		prev := h.Week[(d+6)%7]
+		if !coversDay(h.Week[d], prev) {
+			return false
+		}
+		if h.PublicHoliday != nil && !coversDay(h.PublicHoliday, prev) {
+			return false
+		}
+	}
+	return true
+}
+
+// coversDay reports whether day, with what spills over from prev, is open
+// from 00:00 to 24:00.
+func coversDay(day, prev DayHours) bool {
+	var spans []Interval
+	spans = append(spans, day...)
+	for _, i := range prev {
+		if i.Close > minutesPerDay {
+			spans = append(spans, Interval{0, i.Close - minutesPerDay})
+		}
+	}
+	sort.Slice(spans, func(a, b int) bool { return spans[a].Open < spans[b].Open })
+	reached := 0
+	for _, s := range spans {
+		if s.Open > reached {
+			return false
+		}
+		reached = max(reached, s.Close)
+	}
+	return reached >= minutesPerDay
+}
+
+var (
+	hoursEntry = regexp.MustCompile(`^([^\d:]+?)\s*:\s*(.*)$`)
+	hoursRange = regexp.MustCompile(`^(\d{1,2})(?:[:.](\d{2}))?\s*(am|pm)?\s*(?:-|~|to|至)\s*(\d{1,2})(?:[:.](\d{2}))?\s*(am|pm)?$`)
+
+	hoursSpaces = strings.NewReplacer("\u00a0", " ", "\u2009", " ", "\u202f", " ", "：", ":", "–", "-", "—", "-", "－", "-", "，", ",", "、", ",", "a.m.", "am", "p.m.", "pm")
+
+	weekdayNames = map[string]time.Weekday{
+		"sunday": time.Sunday, "sun": time.Sunday, "星期日": time.Sunday, "星期天": time.Sunday, "週日": time.Sunday, "周日": time.Sunday,
+		"monday": time.Monday, "mon": time.Monday, "星期一": time.Monday, "週一": time.Monday, "周一": time.Monday,
+		"tuesday": time.Tuesday, "tue": time.Tuesday, "tues": time.Tuesday, "星期二": time.Tuesday, "週二": time.Tuesday, "周二": time.Tuesday,
+		"wednesday": time.Wednesday, "wed": time.Wednesday, "星期三": time.Wednesday, "週三": time.Wednesday, "周三": time.Wednesday,
+		"thursday": time.Thursday, "thu": time.Thursday, "thur": time.Thursday, "thurs": time.Thursday, "星期四": time.Thursday, "週四": time.Thursday, "周四": time.Thursday,
+		"friday": time.Friday, "fri": time.Friday, "星期五": time.Friday, "週五": time.Friday, "周五": time.Friday,
+		"saturday": time.Saturday, "sat": time.Saturday, "星期六": time.Saturday, "週六": time.Saturday, "周六": time.Saturday,
+	}
+	everyDayNames      = []string{"daily", "every day", "everyday", "每日", "每天"}
+	publicHolidayNames = []string{"public holidays", "public holiday", "holidays", "holiday", "ph", "公眾假期", "公众假期", "假期", "公假"}
+	closedNames        = []string{"closed", "休息", "休診", "休诊", "暫停營業"}
+	allDayNames        = []string{"open 24 hours", "24 hours", "24 hrs", "24/7", "24小時", "24小时"}
+)
+
+// ParseOpeningHours reads opening hours as Google's weekday text writes
+// them ("Monday: 9:00 AM – 1:00 PM, 2:30 – 7:00 PM; Tuesday: Open 24
+// hours; ..."), one "days: hours" entry per semicolon or line. Days may be
+// ranges ("Mon-Fri"), lists, "Daily" or "Public holidays"; hours may be
+// "Closed", "Open 24 hours" or comma separated ranges in 12- or 24-hour
+// time, which run past midnight when they close before they open. Days
+// that no entry names are closed. Empty text gives nil.
+func ParseOpeningHours(text string) (*OpeningHours, error) {
+	text = strings.ToLower(hoursSpaces.Replace(text))
+	if strings.TrimSpace(text) == "" {
+		return nil, nil
+	}
+	h := &OpeningHours{}
+	for _, entry := range strings.FieldsFunc(text, func(r rune) bool { return r == ';' || r == '\n' }) {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		m := hoursEntry.FindStringSubmatch(entry)
+		if m == nil {
+			return nil, fmt.Errorf("%q: expected \"day: hours\"", entry)
+		}
+		days, holiday, err := parseHoursDays(m[1])
+		if err != nil {
+			return nil, fmt.Errorf("%q: %w", entry, err)
+		}
+		hours, err := parseDayHours(m[2])
+		if err != nil {
+			return nil, fmt.Errorf("%q: %w", entry, err)
+		}
+		for _, d := range days {
+			h.Week[d] = append(h.Week[d], hours...)
+		}
+		if holiday {
+			if h.PublicHoliday == nil {
+				h.PublicHoliday = DayHours{}
+			}
+			h.PublicHoliday = append(h.PublicHoliday, hours...)
+		}
+	}
+	return h, nil
+}
+
+func matchesAny(s string, names []string) bool {
+	for _, n := range names {
+		if s == n {
+			return true
+		}
+	}
+	return false
+}
+
+// parseHoursDays reads the day part of an entry.
+func parseHoursDays(s string) (days []time.Weekday, holiday bool, err error) {
+	for _, part := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == '&' || r == '/' }) {
+		part = strings.Join(strings.Fields(strings.TrimSuffix(strings.TrimSpace(part), ".")), " ")
+		switch {
+		case matchesAny(part, everyDayNames):
+			days = append(days, time.Sunday, time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday, time.Saturday)
+			continue
+		case matchesAny(part, publicHolidayNames):
+			holiday = true
+			continue
+		}
+		if d, ok := weekdayNames[part]; ok {
+			days = append(days, d)
+			continue
+		}
+		from, to, ok := cutAny(part, " to ", "至", "-", "~")
+		from, to = strings.TrimSpace(from), strings.TrimSpace(to)
+		if _, known := weekdayNames[to]; !known && len(to) < len(from) {
+			to = from[:len(from)-len(to)] + to // 星期一至五
+		}
+		first, ok1 := weekdayNames[from]
+		last, ok2 := weekdayNames[to]
+		if !ok || !ok1 || !ok2 {
+			return nil, false, fmt.Errorf("unknown day %q", part)
+		}
+		for d := first; ; d = (d + 1) % 7 {
+			days = append(days, d)
+			if d == last {
+				break
+			}
+		}
+	}
+	if len(days) == 0 && !holiday {
+		return nil, false, fmt.Errorf("no day given")
+	}
+	return days, holiday, nil
+}
+
+func cutAny(s string, seps ...string) (before, after string, found bool) {
+	for _, sep := range seps {
+		if before, after, found = strings.Cut(s, sep); found {
+			return before, after, true
+		}
+	}
+	return s, "", false
+}
+
+// parseDayHours reads the hours part of an entry.
+func parseDayHours(s string) (DayHours, error) {
+	s = strings.Join(strings.Fields(s), " ")
+	switch {
+	case matchesAny(s, closedNames):
+		return DayHours{}, nil
+	case matchesAny(s, allDayNames):
+		return DayHours{{0, minutesPerDay}}, nil
+	}
+	hours := DayHours{}
+	for _, part := range strings.Split(strings.ReplaceAll(s, " and ", ","), ",") {
+		part = strings.TrimSpace(part)
+		m := hoursRange.FindStringSubmatch(part)
+		if m == nil {
+			return nil, fmt.Errorf("unreadable hours %q", part)
+		}
+		open, close, err := parseRange(m[1:])
+		if err != nil {
+			return nil, fmt.Errorf("%q: %w", part, err)
+		}
+		hours = append(hours, Interval{open, close})
+	}
+	return hours, nil
+}
+
+// parseRange turns the hour, minute and am/pm of both ends of a range into
+// minutes. Like Google, an opening time without am/pm takes the closing
+// time's, unless that would put it after the close.
+func parseRange(m []string) (open, close int, err error) {
+	clock := func(hour, minute, meridiem string) (int, error) {
+		h, _ := strconv.Atoi(hour)
+		mins, _ := strconv.Atoi(minute)
+		switch {
+		case mins > 59:
+			return 0, fmt.Errorf("bad minute %s", minute)
+		case meridiem == "":
+			if h > 24 || h == 24 && mins > 0 {
+				return 0, fmt.Errorf("bad hour %s", hour)
+			}
+			return h*60 + mins, nil
+		case h < 1 || h > 12:
+			return 0, fmt.Errorf("bad hour %s%s", hour, meridiem)
+		case meridiem == "pm":
+			h = h%12 + 12
+		default:
+			h %= 12
+		}
+		return h*60 + mins, nil
+	}
+	if close, err = clock(m[3], m[4], m[5]); err != nil {
+		return 0, 0, err
+	}
+	openMeridiem := m[2]
+	if openMeridiem == "" && m[5] != "" {
+		if h, _ := strconv.Atoi(m[0]); h >= 1 && h <= 12 {
+			openMeridiem = m[5]
+			if o, err := clock(m[0], m[1], openMeridiem); err == nil && o > close && close != 0 {
+				openMeridiem = map[string]string{"am": "pm", "pm": "am"}[m[5]]
+			}
+		}
+	}
+	if open, err = clock(m[0], m[1], openMeridiem); err != nil {
+		return 0, 0, err
+	}
+	if open == minutesPerDay {
+		open = 0
+	}
+	switch {
+	case close == minutesPerDay-1: // "11:59 PM" means midnight
+		close = minutesPerDay
+	case close <= open:
+		close += minutesPerDay
+	}
+	return open, close, nil
+}
@@ -0,0 +1,170 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseOpeningHours(t *testing.T) {
+	weekdays := func(d DayHours) [7]DayHours {
+		var w [7]DayHours
+		for day := time.Monday; day <= time.Friday; day++ {
+			w[day] = d
+		}
+		return w
+	}
+	daily := func(d DayHours) [7]DayHours {
+		var w [7]DayHours
+		for day := range w {
+			w[day] = d
+		}
+		return w
+	}
+
+	tests := []struct {
+		name string
+		text string
+		want *OpeningHours
+	}{
+		{"empty", "  ", nil},
+		{
+			"google weekday text",
+			"Monday: 9:00 AM – 1:00 PM, 2:30 – 7:00 PM; Tuesday: Closed",
+			&OpeningHours{Week: [7]DayHours{time.Monday: {{540, 780}, {870, 1140}}}},
+		},
+		{
+			"day range",
+			"Mon-Fri: 09:00-18:00",
+			&OpeningHours{Week: weekdays(DayHours{{540, 1080}})},
+		},
+		{
+			"overnight",
+			"Daily: 8:00 pm - 2:00 am",
+			&OpeningHours{Week: daily(DayHours{{1200, 1560}})},
+		},
+		{
+			"open 24 hours",
+			"Daily: Open 24 hours",
+			&OpeningHours{Week: daily(DayHours{{0, 1440}})},
+		},
+		{
+			"chinese with closed holidays",
+			"星期一至五：10:00-20:00\n公眾假期：休息",
+			&OpeningHours{Week: weekdays(DayHours{{600, 1200}}), PublicHoliday: DayHours{}},
+		},
+		{
+			"holiday hours",
+			"Mon-Fri: 09:00-18:00; Public holidays: 10:00-14:00",
+			&OpeningHours{Week: weekdays(DayHours{{540, 1080}}), PublicHoliday: DayHours{{600, 840}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseOpeningHours(tt.text)
+			if err != nil {
+				t.Fatalf("ParseOpeningHours(%q): %v", tt.text, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseOpeningHours(%q) = %+v, want %+v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseOpeningHoursErrors(t *testing.T) {
+	for _, text := range []string{
+		"Monday 9:00-17:00",
+		"Funday: 9:00-17:00",
+		"Monday: by appointment",
+	} {
+		if _, err := ParseOpeningHours(text); err == nil {
+			t.Errorf("ParseOpeningHours(%q) succeeded, want an error", text)
+		}
+	}
+}
+
+func TestOpenAt(t *testing.T) {
+	h, err := ParseOpeningHours("Mon-Sat: 09:00-18:00; Fri: 20:00-02:00; Public holidays: 10:00-14:00")
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2026, 10, day, hour, minute, 0, 0, HongKong)
+	}
+	// Monday 26 and Friday 30 October 2026 are treated as public holidays.
+	holiday := func(t time.Time) bool { return t.Day() == 26 || t.Day() == 30 }
+
+	tests := []struct {
+		name    string
+		t       time.Time
+		holiday func(time.Time) bool
+		want    bool
+	}{
+		{"weekday open", at(19, 10, 0), nil, true},
+		{"opening minute", at(19, 9, 0), nil, true},
+		{"closing minute", at(19, 18, 0), nil, false},
+		{"hong kong time from utc", time.Date(2026, 10, 19, 2, 0, 0, 0, time.UTC), nil, true},
+		{"friday night", at(23, 23, 30), nil, true},
+		{"overnight into saturday", at(24, 1, 30), nil, true},
+		{"overnight closed", at(24, 2, 0), nil, false},
+		{"sunday closed", at(25, 12, 0), nil, false},
+		{"no spill into monday", at(19, 1, 0), nil, false},
+		{"holiday hours", at(26, 11, 0), holiday, true},
+		{"weekday hours ignored on holiday", at(26, 9, 30), holiday, false},
+		{"holiday hours without calendar", at(26, 9, 30), nil, true},
+		{"holiday friday has no overnight", at(31, 1, 0), holiday, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := h.OpenAt(tt.t, tt.holiday); got != tt.want {
+				t.Errorf("OpenAt(%s) = %v, want %v", tt.t.In(HongKong).Format("Mon 2006-01-02 15:04"), got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAlwaysOpen(t *testing.T) {
+	tests := []struct {
+		text string
+		want bool
+	}{
+		{"Daily: Open 24 hours", true},
+		{"Daily: 08:00-20:00, 20:00-08:00", true},
+		{"Daily: Open 24 hours; Public holidays: Open 24 hours", true},
+		{"Daily: Open 24 hours; Public holidays: 10:00-18:00", false},
+		{"Mon-Sat: Open 24 hours; Sun: 09:00-18:00", false},
+		{"Daily: 08:00-20:00, 20:00-07:59", false},
+	}
+	for _, tt := range tests {
+		h, err := ParseOpeningHours(tt.text)
+		if err != nil {
+			t.Fatalf("ParseOpeningHours(%q): %v", tt.text, err)
+		}
+		if got := h.AlwaysOpen(); got != tt.want {
+			t.Errorf("AlwaysOpen(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestOpenAllDay(t *testing.T) {
+	h, err := ParseOpeningHours("Mon-Fri: Open 24 hours; Sat: 00:00-12:00; Public holidays: 09:00-17:00")
+	if err != nil {
+		t.Fatal(err)
+	}
+	holiday := func(t time.Time) bool { return t.Day() == 21 }
+	tests := []struct {
+		day  int
+		want bool
+	}{
+		{20, true},  // Tuesday
+		{21, false}, // Wednesday, a holiday
+		{24, false}, // Saturday, half day
+		{25, false}, // Sunday, closed
+	}
+	for _, tt := range tests {
+		if got := h.OpenAllDay(time.Date(2026, 10, tt.day, 15, 0, 0, 0, HongKong), holiday); got != tt.want {
+			t.Errorf("OpenAllDay(2026-10-%d) = %v, want %v", tt.day, got, tt.want)
+		}
+	}
+}
@@ -39,10 +39,15 @@ func (c *CostItem) BeforeCreate(tx *gorm.DB) error {
 	return nil
 }
 
+// Insurer is the plan a scenario payout is estimated for. ProductID links
+// it to product.insurance_id in the insurance DB and CompanyID to the
+// product's insurance_provider.company_id; both are nil until linked.
 type Insurer struct {
-	ID       string `gorm:"type:varchar(50);primary_key" json:"id"`
-	Name     string `gorm:"type:varchar(255);not null" json:"name"`
-	PlanName string `gorm:"type:varchar(255);not null" json:"plan_name"`
+	ID        string `gorm:"type:varchar(50);primary_key" json:"id"`
+	Name      string `gorm:"type:varchar(255);not null" json:"name"`
+	PlanName  string `gorm:"type:varchar(255);not null" json:"plan_name"`
+	CompanyID *int   `json:"company_id"`
+	ProductID *int   `json:"product_id"`
 }
 
 type Payout struct {
@@ -62,3 +67,15 @@ func (p *Payout) BeforeCreate(tx *gorm.DB) error {
 	}
 	return nil
 }
+
+// CostBenchmark is the typical price of a cost item in a district or at a
+// clinic. With both District and ClinicID empty it is the Hong Kong-wide
+// baseline; a clinic row may also carry the clinic's district.
+type CostBenchmark struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	ItemName  string    `gorm:"type:varchar(255);not null" json:"item_name"`
+	District  string    `gorm:"type:varchar(50);not null;default:''" json:"district"`
+	ClinicID  string    `gorm:"type:text;not null;default:''" json:"clinic_id"`
+	AmountHKD int       `gorm:"not null" json:"amount_hkd"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
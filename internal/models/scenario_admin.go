@@ -0,0 +1,406 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// A scenario's total_cost_hkd always equals the sum of its cost items, and
+// a scenario with payouts recommends exactly one of them. Writes to whole
+// scenarios are checked against both; writes to single cost items keep
+// the total in step, and recommending a payout takes the recommendation
+// away from the others.
+
+// LoadScenario reads a scenario with its cost items and payouts, or
+// ErrNotFound.
+func LoadScenario(db *gorm.DB, id string) (*Scenario, error) {
+	var s Scenario
+	res := db.Preload("CostItems").Preload("Payouts").Preload("Payouts.Insurer").Limit(1).Find(&s, "id = ?", id)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, ErrNotFound
+	}
+	return &s, nil
+}
+
+// found turns a gorm lookup that matched nothing into ErrNotFound.
+func found(res *gorm.DB) error {
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func validateCostItem(field string, item *CostItem) error {
+	if strings.TrimSpace(item.ItemName) == "" {
+		return &ValidationError{Field: field + ".item_name", Message: "is required"}
+	}
+	if item.AmountHKD < 0 {
+		return &ValidationError{Field: field + ".amount_hkd", Message: "must not be negative"}
+	}
+	return nil
+}
+
+func validatePayout(tx *gorm.DB, field string, p *Payout) error {
+	if p.InsurerID == "" {
+		return &ValidationError{Field: field + ".insurer_id", Message: "is required"}
+	}
+	var n int64
+	if err := tx.Model(&Insurer{}).Where("id = ?", p.InsurerID).Count(&n).Error; err != nil {
+		return err
+	}
+	if n == 0 {
+		return &ValidationError{Field: field + ".insurer_id", Message: fmt.Sprintf("insurer %s does not exist", p.InsurerID)}
+	}
+	// Cash benefits (e.g. a lump sum on a cancer diagnosis) are paid on top
+	// of the reimbursement, so a payout may exceed the bill.
+	if p.EstimatedPayoutHKD < 0 {
+		return &ValidationError{Field: field + ".estimated_payout_hkd", Message: "must not be negative"}
+	}
+	if p.CoveragePercentage < 0 {
+		return &ValidationError{Field: field + ".coverage_percentage", Message: "must not be negative"}
+	}
+	return nil
+}
+
+// validateScenario checks every field of a complete scenario and the
+// invariants between its children.
+func validateScenario(tx *gorm.DB, s *Scenario) error {
+	if strings.TrimSpace(s.Title) == "" {
+		return &ValidationError{Field: "title", Message: "is required"}
+	}
+	sum := 0
+	for i := range s.CostItems {
+		if err := validateCostItem(fmt.Sprintf("cost_breakdown[%d]", i), &s.CostItems[i]); err != nil {
+			return err
+		}
+		sum += s.CostItems[i].AmountHKD
+	}
+	if sum != s.TotalCostHKD {
+		return &ValidationError{Field: "total_cost_hkd", Message: fmt.Sprintf("is %d but the cost items add up to %d", s.TotalCostHKD, sum)}
+	}
+
+	insurers := make(map[string]bool)
+	recommended := 0
+	for i := range s.Payouts {
+		field := fmt.Sprintf("payouts[%d]", i)
+		if err := validatePayout(tx, field, &s.Payouts[i]); err != nil {
+			return err
+		}
+		if insurers[s.Payouts[i].InsurerID] {
+			return &ValidationError{Field: field + ".insurer_id", Message: fmt.Sprintf("insurer %s already has a payout", s.Payouts[i].InsurerID)}
+		}
+		insurers[s.Payouts[i].InsurerID] = true
+		if s.Payouts[i].IsRecommended {
+			recommended++
+		}
+	}
+	if len(s.Payouts) > 0 && recommended != 1 {
+		return &ValidationError{Field: "payouts", Message: fmt.Sprintf("exactly one payout must be recommended, got %d", recommended)}
+	}
+	return nil
+}
+
+// insertChildren writes the cost items and payouts of s with fresh ids.
+func insertChildren(tx *gorm.DB, s *Scenario) error {
+	for i := range s.CostItems {
+		s.CostItems[i].ID, s.CostItems[i].ScenarioID = "", s.ID
+	}
+	for i := range s.Payouts {
+		s.Payouts[i].ID, s.Payouts[i].ScenarioID = "", s.ID
+	}
+	if len(s.CostItems) > 0 {
+		if err := tx.Create(&s.CostItems).Error; err != nil {
+			return err
+		}
+	}
+	if len(s.Payouts) > 0 {
+		if err := tx.Omit("Insurer").Create(&s.Payouts).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func deleteChildren(tx *gorm.DB, scenarioID string) error {
+	if err := tx.Where("scenario_id = ?", scenarioID).Delete(&CostItem{}).Error; err != nil {
+		return err
+	}
+	return tx.Where("scenario_id = ?", scenarioID).Delete(&Payout{}).Error
+}
+
+// CreateScenario inserts a scenario with its cost items and payouts. An
+// empty ID is replaced by a UUID.
+func CreateScenario(db *gorm.DB, s *Scenario) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := validateScenario(tx, s); err != nil {
+			return err
+		}
+		if s.ID != "" {
+			var n int64
+			if err := tx.Model(&Scenario{}).Where("id = ?", s.ID).Count(&n).Error; err != nil {
+				return err
+			}
+			if n > 0 {
+				return fmt.Errorf("%w: scenario %s already exists", ErrConflict, s.ID)
+			}
+		}
+		if err := tx.Omit("CostItems", "Payouts").Create(s).Error; err != nil {
+			return err
+		}
+		return insertChildren(tx, s)
+	})
+}
+
+// UpdateScenario replaces scenario s.ID, including all of its cost items
+// and payouts.
+func UpdateScenario(db *gorm.DB, s *Scenario) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var existing Scenario
+		if err := found(tx.Limit(1).Find(&existing, "id = ?", s.ID)); err != nil {
+			return err
+		}
+		if err := validateScenario(tx, s); err != nil {
+			return err
+		}
+		s.CreatedAt = existing.CreatedAt
+		if err := tx.Model(&existing).Updates(map[string]interface{}{
+			"title": s.Title, "description": s.Description, "total_cost_hkd": s.TotalCostHKD,
+		}).Error; err != nil {
+			return err
+		}
+		if err := deleteChildren(tx, s.ID); err != nil {
+			return err
+		}
+		return insertChildren(tx, s)
+	})
+}
+
+// DeleteScenario removes a scenario with its cost items and payouts.
+func DeleteScenario(db *gorm.DB, id string) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := deleteChildren(tx, id); err != nil {
+			return err
+		}
+		return found(tx.Where("id = ?", id).Delete(&Scenario{}))
+	})
+}
+
+// --- Cost items ---
+
+// adjustTotal moves a scenario's total cost by delta, keeping it equal to
+// the sum of its cost items.
+func adjustTotal(tx *gorm.DB, scenarioID string, delta int) error {
+	return tx.Model(&Scenario{ID: scenarioID}).Update("total_cost_hkd", gorm.Expr("total_cost_hkd + ?", delta)).Error
+}
+
+// CreateCostItem adds a cost item to a scenario and raises its total cost
+// by the amount.
+func CreateCostItem(db *gorm.DB, item *CostItem) error {
+	if err := validateCostItem("cost_item", item); err != nil {
+		return err
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := found(tx.Limit(1).Find(&Scenario{}, "id = ?", item.ScenarioID)); err != nil {
+			return err
+		}
+		item.ID = ""
+		if err := tx.Create(item).Error; err != nil {
+			return err
+		}
+		return adjustTotal(tx, item.ScenarioID, item.AmountHKD)
+	})
+}
+
+// UpdateCostItem replaces the name and amount of a cost item and moves its
+// scenario's total cost by the difference.
+func UpdateCostItem(db *gorm.DB, item *CostItem) error {
+	if err := validateCostItem("cost_item", item); err != nil {
+		return err
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		var old CostItem
+		if err := found(tx.Limit(1).Find(&old, "id = ? AND scenario_id = ?", item.ID, item.ScenarioID)); err != nil {
+			return err
+		}
+		delta := item.AmountHKD - old.AmountHKD
+		if err := tx.Model(&old).Updates(map[string]interface{}{"item_name": item.ItemName, "amount_hkd": item.AmountHKD}).Error; err != nil {
+			return err
+		}
+		return adjustTotal(tx, item.ScenarioID, delta)
+	})
+}
+
+// DeleteCostItem removes a cost item and lowers its scenario's total cost.
+func DeleteCostItem(db *gorm.DB, scenarioID, id string) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var old CostItem
+		if err := found(tx.Limit(1).Find(&old, "id = ? AND scenario_id = ?", id, scenarioID)); err != nil {
+			return err
+		}
+		if err := tx.Delete(&old).Error; err != nil {
+			return err
+		}
+		return adjustTotal(tx, scenarioID, -old.AmountHKD)
+	})
+}
+
+// --- Payouts ---
+
+// savePayout validates p against its scenario and, when p is recommended,
+// takes the recommendation away from the scenario's other payouts.
+func savePayout(tx *gorm.DB, p *Payout, write func() error) error {
+	var s Scenario
+	if err := found(tx.Preload("Payouts").Limit(1).Find(&s, "id = ?", p.ScenarioID)); err != nil {
+		return err
+	}
+	if err := validatePayout(tx, "payout", p); err != nil {
+		return err
+	}
+	others := 0
+	for _, other := range s.Payouts {
+		if other.ID == p.ID {
+			continue
+		}
+		others++
+		if other.InsurerID == p.InsurerID {
+			return &ValidationError{Field: "insurer_id", Message: fmt.Sprintf("insurer %s already has a payout", p.InsurerID)}
+		}
+	}
+	if !p.IsRecommended {
+		var recommended int64
+		err := tx.Model(&Payout{}).Where("scenario_id = ? AND id != ? AND is_recommended", p.ScenarioID, p.ID).Count(&recommended).Error
+		if err != nil {
+			return err
+		}
+		if recommended == 0 {
+			return &ValidationError{Field: "is_recommended", Message: "one payout of the scenario must be recommended; recommend another one instead"}
+		}
+	} else if others > 0 {
+		if err := tx.Model(&Payout{}).Where("scenario_id = ? AND id != ?", p.ScenarioID, p.ID).Update("is_recommended", false).Error; err != nil {
+			return err
+		}
+	}
+	if err := write(); err != nil {
+		return err
+	}
+	return tx.Model(&Scenario{ID: p.ScenarioID}).Update("updated_at", time.Now()).Error
+}
+
+// CreatePayout adds an insurer's payout to a scenario.
+func CreatePayout(db *gorm.DB, p *Payout) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		p.ID = ""
+		return savePayout(tx, p, func() error {
+			return tx.Omit("Insurer").Create(p).Error
+		})
+	})
+}
+
+// UpdatePayout replaces every field of payout p.ID.
+func UpdatePayout(db *gorm.DB, p *Payout) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := found(tx.Limit(1).Find(&Payout{}, "id = ? AND scenario_id = ?", p.ID, p.ScenarioID)); err != nil {
+			return err
+		}
+		return savePayout(tx, p, func() error {
+			return tx.Model(&Payout{ID: p.ID}).Select("insurer_id", "estimated_payout_hkd", "coverage_percentage", "analysis", "is_recommended").
+				Updates(p).Error
+		})
+	})
+}
+
+// DeletePayout removes a payout. The recommended payout can only go once
+// another one is recommended, or when it's the last.
+func DeletePayout(db *gorm.DB, scenarioID, id string) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var p Payout
+		if err := found(tx.Limit(1).Find(&p, "id = ? AND scenario_id = ?", id, scenarioID)); err != nil {
+			return err
+		}
+		if p.IsRecommended {
+			var others int64
+			if err := tx.Model(&Payout{}).Where("scenario_id = ? AND id != ?", scenarioID, id).Count(&others).Error; err != nil {
+				return err
+			}
+			if others > 0 {
+				return fmt.Errorf("%w: payout %s is the recommended one; recommend another payout first", ErrConflict, id)
+			}
+		}
+		if err := tx.Delete(&p).Error; err != nil {
+			return err
+		}
+		return tx.Model(&Scenario{ID: scenarioID}).Update("updated_at", time.Now()).Error
+	})
+}
+
+// --- Insurers ---
+
+func validateInsurer(i *Insurer) error {
+	if i.ID == "" || strings.ContainsAny(i.ID, " /") || len(i.ID) > 50 {
+		return &ValidationError{Field: "id", Message: "is required, at most 50 characters and without spaces or slashes"}
+	}
+	if strings.TrimSpace(i.Name) == "" {
+		return &ValidationError{Field: "name", Message: "is required"}
+	}
+	if strings.TrimSpace(i.PlanName) == "" {
+		return &ValidationError{Field: "plan_name", Message: "is required"}
+	}
+	return nil
+}
+
+// CreateInsurer adds an insurer scenarios can pay out for, linked to the
+// insurance product i.ProductID when it is set.
+func CreateInsurer(db *gorm.DB, repo *InsuranceRepository, i *Insurer) error {
+	if err := validateInsurer(i); err != nil {
+		return err
+	}
+	if err := linkProduct(repo, i); err != nil {
+		return err
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		var n int64
+		if err := tx.Model(&Insurer{}).Where("id = ?", i.ID).Count(&n).Error; err != nil {
+			return err
+		}
+		if n > 0 {
+			return fmt.Errorf("%w: insurer %s already exists", ErrConflict, i.ID)
+		}
+		return tx.Create(i).Error
+	})
+}
+
+// UpdateInsurer replaces the name, plan and product of insurer i.ID.
+func UpdateInsurer(db *gorm.DB, repo *InsuranceRepository, i *Insurer) error {
+	if err := validateInsurer(i); err != nil {
+		return err
+	}
+	if err := linkProduct(repo, i); err != nil {
+		return err
+	}
+	return found(db.Model(&Insurer{ID: i.ID}).Updates(map[string]interface{}{
+		"name": i.Name, "plan_name": i.PlanName, "company_id": i.CompanyID, "product_id": i.ProductID,
+	}))
+}
+
+// DeleteInsurer removes an insurer no scenario pays out for any more.
+func DeleteInsurer(db *gorm.DB, id string) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var payouts int64
+		if err := tx.Model(&Payout{}).Where("insurer_id = ?", id).Count(&payouts).Error; err != nil {
+			return err
+		}
+		if payouts > 0 {
+			return fmt.Errorf("%w: insurer %s still has %d payouts", ErrConflict, id, payouts)
+		}
+		return found(tx.Where("id = ?", id).Delete(&Insurer{}))
+	})
+}
@@ -0,0 +1,139 @@
+package eligibility
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// breedAliases maps the spellings found in policy wordings to a canonical
+// breed name. Insurers mostly exclude the breeds restricted under Hong
+// Kong's Dangerous Dogs Regulation (Cap. 167D) plus a few large breeds.
+var breedAliases = map[string]string{
+	"antarctic husky":  "Antarctic Husky",
+	"bull terrier":     "Bull Terrier",
+	"dogo argentino":   "Dogo Argentino",
+	"fila brasileiro":  "Fila Brasileiro",
+	"fila braziliero":  "Fila Brasileiro",
+	"fila brazillier":  "Fila Brasileiro",
+	"fila brazilier":   "Fila Brasileiro",
+	"japanese tosa":    "Japanese Tosa",
+	"tosa":             "Japanese Tosa",
+	"pit bull terrier": "Pit Bull Terrier",
+	"pit bull":         "Pit Bull Terrier",
+	"pitbull":          "Pit Bull Terrier",
+	"tibetan mastiff":  "Tibetan Mastiff",
+	"tibetan masstiff": "Tibetan Mastiff",
+}
+
+// NormalizeBreed returns the canonical name for a known breed, or the
+// trimmed input in title case otherwise.
+func NormalizeBreed(breed string) string {
+	key := strings.Join(strings.Fields(strings.ToLower(breed)), " ")
+	if canonical, ok := breedAliases[key]; ok {
+		return canonical
+	}
+	words := strings.Fields(key)
+	for i, w := range words {
+		r, size := utf8.DecodeRuneInString(w)
+		words[i] = string(unicode.ToUpper(r)) + w[size:]
+	}
+	return strings.Join(words, " ")
+}
+
+// ParseExcludedBreeds finds the known breeds named in a breed remark and
+// whether their cross breeds are excluded too.
+func ParseExcludedBreeds(remark string) (breeds []string, crossBreeds bool) {
+	text := " " + strings.Join(strings.Fields(strings.ToLower(remark)), " ") + " "
+	found := make(map[string]bool)
+	for alias, canonical := range breedAliases {
+		// "pit bull terrier" also contains "bull terrier"; only count the
+		// shorter alias when it appears on its own.
+		idx := strings.Index(text, " "+alias)
+		for idx >= 0 {
+			if !(alias == "bull terrier" && strings.HasSuffix(text[:idx], " pit")) {
+				found[canonical] = true
+				break
+			}
+			next := strings.Index(text[idx+1:], " "+alias)
+			if next < 0 {
+				break
+			}
+			idx += next + 1
+		}
+	}
+	for b := range found {
+		breeds = append(breeds, b)
+	}
+	sort.Strings(breeds)
+	crossBreeds = strings.Contains(text, "cross breed") || strings.Contains(text, "crossbreed") || strings.Contains(text, "cross-breed")
+	return breeds, crossBreeds
+}
+
+// AcceptsBreed reports whether a breed isn't excluded by the product.
+func (r *Rules) AcceptsBreed(breed string) bool {
+	canonical := NormalizeBreed(breed)
+	lower := strings.ToLower(breed)
+	for _, excluded := range r.ExcludedBreeds {
+		if canonical == excluded {
+			return false
+		}
+		if r.CrossBreedsExcluded && strings.Contains(lower, strings.ToLower(excluded)) {
+			return false
+		}
+	}
+	return true
+}
+
+// BreedClass groups breeds the way premium tables price them.
+type BreedClass string
+
+const (
+	BreedAny      BreedClass = "any" // premium rows that apply to every class
+	BreedMixed    BreedClass = "mixed"
+	BreedPurebred BreedClass = "purebred"
+	BreedLarge    BreedClass = "large" // large and giant dog breeds
+)
+
+// ParseBreedClass accepts one of the BreedClass values, ignoring case.
+func ParseBreedClass(s string) (BreedClass, bool) {
+	switch c := BreedClass(strings.ToLower(strings.TrimSpace(s))); c {
+	case BreedAny, BreedMixed, BreedPurebred, BreedLarge:
+		return c, true
+	}
+	return "", false
+}
+
+// mixedBreedWords mark a breed as a mix, including the local names for
+// mixed-breed dogs and cats.
+var mixedBreedWords = []string{"mix", "cross", "mongrel", "domestic", "唐狗", "唐貓", "唐猫"}
+
+// largeDogBreeds are priced as large dogs. The breeds insurers exclude
+// outright are large too, for products that accept them.
+var largeDogBreeds = map[string]bool{
+	"Akita": true, "Alaskan Malamute": true, "Bernese Mountain Dog": true, "Boxer": true,
+	"Doberman": true, "Doberman Pinscher": true, "German Shepherd": true, "Golden Retriever": true,
+	"Great Dane": true, "Labrador": true, "Labrador Retriever": true, "Newfoundland": true,
+	"Rottweiler": true, "Saint Bernard": true, "Samoyed": true, "Siberian Husky": true,
+	"Antarctic Husky": true, "Dogo Argentino": true, "Fila Brasileiro": true, "Japanese Tosa": true,
+	"Tibetan Mastiff": true,
+}
+
+// ClassifyBreed returns the breed class of a pet. An empty breed counts
+// as mixed, the common case for rescued pets in Hong Kong.
+func ClassifyBreed(petType PetType, breed string) BreedClass {
+	lower := strings.ToLower(strings.TrimSpace(breed))
+	if lower == "" {
+		return BreedMixed
+	}
+	for _, w := range mixedBreedWords {
+		if strings.Contains(lower, w) {
+			return BreedMixed
+		}
+	}
+	if petType == PetDog && largeDogBreeds[NormalizeBreed(breed)] {
+		return BreedLarge
+	}
+	return BreedPurebred
+}
@@ -0,0 +1,167 @@
+package eligibility
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Clinic networks a coinsurance rate can be restricted to.
+const (
+	NetworkAny       = ""
+	NetworkMember    = "network"
+	NetworkNonMember = "non_network"
+)
+
+// Which age an age band is measured against.
+const (
+	AgeBasisAttained  = "attained"
+	AgeBasisEnrolment = "enrolment"
+)
+
+// CoinsuranceRate is the share of a claim the customer pays for one age
+// band and clinic network.
+type CoinsuranceRate struct {
+	Network          string  `json:"network,omitempty"`
+	AgeBasis         string  `json:"age_basis,omitempty"`
+	MinAgeWeeks      int     `json:"min_age_weeks"`
+	MaxAgeWeeks      *int    `json:"max_age_weeks,omitempty"` // inclusive
+	CustomerSharePct float64 `json:"customer_share_pct"`
+}
+
+// Schedule is a product's coinsurance rates in the order they were listed.
+type Schedule []CoinsuranceRate
+
+var (
+	ratePattern = regexp.MustCompile(`(?i)((?:non[\s-]*)?network[^%|:]*?|all[^%|:]*?)?(\d+(?:\.\d+)?)\s*%`)
+
+	// Age bands, with ages in their own units.
+	bandRange   = regexp.MustCompile(`(?i)(\d+)\s*(weeks?|months?|years?)?\s*(?:to|-|–)\s*(\d+)\s*(weeks?|months?|years?)?`)
+	bandAbove   = regexp.MustCompile(`(?i)(?:age\s*)?(\d+)\s*(weeks?|months?|years?)?\s*or\s*above`)
+	bandBefore  = regexp.MustCompile(`(?i)before\s*age\s*(\d+)`)
+	leadingRate = regexp.MustCompile(`^\s*\d+(?:\.\d+)?\s*%\s*-?`)
+)
+
+// ParseCoinsurance reads texts such as "20%",
+// "30% - pet's attained age from 0 - 8" or
+// "Insured age at Age 1 or above: Network Clinic 90% | Non-Network Clinic 70%".
+//
+// Most insurers quote the customer's share, but plans that distinguish
+// network clinics quote what they reimburse, so those are inverted.
+func ParseCoinsurance(text string) (Schedule, error) {
+	reimbursementQuoted := strings.Contains(strings.ToLower(text), "network")
+	lines := strings.Split(text, "\n")
+
+	var schedule Schedule
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		matches := ratePattern.FindAllStringSubmatch(line, -1)
+		if matches == nil {
+			return schedule, fmt.Errorf("no percentage in %q", strings.TrimSpace(line))
+		}
+
+		band, ok := parseAgeBand(leadingRate.ReplaceAllString(line, ""))
+		if !ok && len(lines) > 1 {
+			return schedule, fmt.Errorf("no age band in %q", strings.TrimSpace(line))
+		}
+
+		for _, m := range matches {
+			pct, err := strconv.ParseFloat(m[2], 64)
+			if err != nil {
+				return schedule, err
+			}
+			rate := band
+			qualifier := strings.ToLower(m[1])
+			switch {
+			case strings.HasPrefix(qualifier, "non"):
+				rate.Network = NetworkNonMember
+			case strings.HasPrefix(qualifier, "network"):
+				rate.Network = NetworkMember
+			}
+			if reimbursementQuoted {
+				pct = 100 - pct
+			}
+			rate.CustomerSharePct = pct
+			schedule = append(schedule, rate)
+		}
+	}
+	return schedule, nil
+}
+
+func parseAgeBand(line string) (CoinsuranceRate, bool) {
+	var band CoinsuranceRate
+	lower := strings.ToLower(line)
+	switch {
+	case strings.Contains(lower, "attained"):
+		band.AgeBasis = AgeBasisAttained
+	case strings.Contains(lower, "enrol") || strings.Contains(lower, "insured age"):
+		band.AgeBasis = AgeBasisEnrolment
+	}
+
+	if m := bandBefore.FindStringSubmatch(line); m != nil {
+		v, _ := strconv.ParseFloat(m[1], 64)
+		max := toWeeks(v, "years") - 1
+		band.MaxAgeWeeks = &max
+		return band, true
+	}
+	if m := bandAbove.FindStringSubmatch(line); m != nil {
+		v, _ := strconv.ParseFloat(m[1], 64)
+		band.MinAgeWeeks = toWeeks(v, unitOr(m[2], "years"))
+		return band, true
+	}
+	if m := bandRange.FindStringSubmatch(line); m != nil {
+		from, _ := strconv.ParseFloat(m[1], 64)
+		to, _ := strconv.ParseFloat(m[3], 64)
+		toUnit := unitOr(m[4], "years")
+		band.MinAgeWeeks = toWeeks(from, unitOr(m[2], toUnit))
+		max := endOfAge(fmt.Sprintf("%g %s", to, toUnit), toWeeks(to, toUnit))
+		band.MaxAgeWeeks = &max
+		return band, true
+	}
+	return band, false
+}
+
+func unitOr(unit, fallback string) string {
+	if unit == "" {
+		return fallback
+	}
+	return unit
+}
+
+// CustomerSharePct returns the customer's share for a pet of ageWeeks at a
+// network or non-network clinic. A negative age picks the first rate
+// listed, which is the one insurers headline; an age outside every band
+// picks the last, which covers the oldest pets. An empty schedule means
+// the insurer pays in full.
+func (s Schedule) CustomerSharePct(ageWeeks int, network bool) float64 {
+	if len(s) == 0 {
+		return 0
+	}
+	want := NetworkNonMember
+	if network {
+		want = NetworkMember
+	}
+	last := s[len(s)-1]
+	for _, rate := range s {
+		if rate.Network != NetworkAny && rate.Network != want {
+			continue
+		}
+		if ageWeeks < 0 {
+			return rate.CustomerSharePct
+		}
+		last = rate
+		if ageWeeks < rate.MinAgeWeeks || (rate.MaxAgeWeeks != nil && ageWeeks > *rate.MaxAgeWeeks) {
+			continue
+		}
+		return rate.CustomerSharePct
+	}
+	return last.CustomerSharePct
+}
+
+// ReimbursementRate is the share the insurer pays, between 0 and 1.
+func (s Schedule) ReimbursementRate(ageWeeks int, network bool) float64 {
+	return 1 - s.CustomerSharePct(ageWeeks, network)/100
+}
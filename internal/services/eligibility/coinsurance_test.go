@@ -0,0 +1,79 @@
+package eligibility
+
+import "testing"
+
+// The coinsurance texts below are quoted from assets/insurance/products.json.
+const (
+	networkText  = "Insured age at Age 1 or above: Network Clinic 90% | Non-Network Clinic 70%\n13 weeks to 11 months: All HK registered vets 50%"
+	attainedText = "30% - pet’s attained age from 0 - 8\n40% - pet’s attained age from 9 or above"
+	enrolledText = "20% - Pet enrolled before Age 4\n30% - Pet enrolled before Age 7\n40% - Pet enrolled before Age 9"
+)
+
+func TestParseCoinsurance(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		rates    int
+		ageWeeks int
+		network  bool
+		want     float64 // reimbursement rate
+	}{
+		{"flat", "20%", 1, 100, true, 0.8},
+		{"flat ignores network", "30%", 1, 100, false, 0.7},
+		{"empty pays in full", "", 0, 100, true, 1},
+		{"network headline", networkText, 3, -1, true, 0.9},
+		{"non-network headline", networkText, 3, -1, false, 0.7},
+		{"network adult", networkText, 3, 2 * WeeksPerYear, true, 0.9},
+		{"non-network adult", networkText, 3, 2 * WeeksPerYear, false, 0.7},
+		{"puppy at any vet", networkText, 3, 20, true, 0.5},
+		{"attained young", attainedText, 2, 3 * WeeksPerYear, true, 0.7},
+		{"attained end of band", attainedText, 2, 9*WeeksPerYear - 1, true, 0.7},
+		{"attained old", attainedText, 2, 9 * WeeksPerYear, true, 0.6},
+		{"enrolled young", enrolledText, 3, 1 * WeeksPerYear, true, 0.8},
+		{"enrolled at 4", enrolledText, 3, 4 * WeeksPerYear, true, 0.7},
+		{"enrolled at 8", enrolledText, 3, 8 * WeeksPerYear, true, 0.6},
+		{"older than every band", enrolledText, 3, 12 * WeeksPerYear, true, 0.6},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := ParseCoinsurance(tt.text)
+			if err != nil {
+				t.Fatalf("ParseCoinsurance: %v", err)
+			}
+			if len(s) != tt.rates {
+				t.Fatalf("got %d rates, want %d: %+v", len(s), tt.rates, s)
+			}
+			if got := s.ReimbursementRate(tt.ageWeeks, tt.network); got != tt.want {
+				t.Errorf("ReimbursementRate(%d, %v) = %g, want %g", tt.ageWeeks, tt.network, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseCoinsuranceErrors(t *testing.T) {
+	for _, text := range []string{
+		"N/A",
+		"20% - Pet enrolled before Age 4\nsee policy wording",
+		"20% for young pets\n30% for old pets",
+	} {
+		if _, err := ParseCoinsurance(text); err == nil {
+			t.Errorf("ParseCoinsurance(%q) succeeded, want an error", text)
+		}
+	}
+}
+
+func TestNormalizeBreed(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"pit bull", "Pit Bull Terrier"},
+		{"  PIT   Bull ", "Pit Bull Terrier"},
+		{"golden retriever", "Golden Retriever"},
+		{"épagneul breton", "Épagneul Breton"},
+		{"柴犬", "柴犬"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := NormalizeBreed(tt.in); got != tt.want {
+			t.Errorf("NormalizeBreed(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
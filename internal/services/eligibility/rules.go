@@ -0,0 +1,225 @@
+package eligibility
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/vf0429/Petwell_Backend/internal/models"
+)
+
+// PetType is a species a product can insure.
+type PetType string
+
+const (
+	PetCat PetType = "cat"
+	PetDog PetType = "dog"
+)
+
+// WeeksPerYear is used for every age conversion so bands line up exactly.
+const WeeksPerYear = 52
+
+// WaitingPeriod is the number of days before claims for a condition are paid.
+// An empty Condition applies to anything not listed separately.
+type WaitingPeriod struct {
+	Condition string `json:"condition,omitempty"`
+	Days      int    `json:"days"`
+}
+
+// Rules are the typed eligibility terms of one product.
+type Rules struct {
+	ProductID           int             `json:"product_id"`
+	MinAgeWeeks         *int            `json:"min_age_weeks,omitempty"`
+	MaxAgeWeeks         *int            `json:"max_age_weeks,omitempty"` // inclusive
+	PetTypes            []PetType       `json:"pet_types"`
+	Coinsurance         Schedule        `json:"coinsurance"`
+	WaitingPeriods      []WaitingPeriod `json:"waiting_periods"`
+	WaitingPeriodWaiver bool            `json:"waiting_period_waiver,omitempty"`
+	ExcludedBreeds      []string        `json:"excluded_breeds"`
+	CrossBreedsExcluded bool            `json:"cross_breeds_excluded,omitempty"`
+}
+
+// Issue describes a field that couldn't be parsed.
+type Issue struct {
+	ProductID int    `json:"product_id"`
+	Field     string `json:"field"`
+	Value     string `json:"value"`
+	Reason    string `json:"reason"`
+}
+
+// Report is the result of parsing a set of products.
+type Report struct {
+	Rules  []Rules `json:"rules"`
+	Issues []Issue `json:"issues"`
+}
+
+// ParseAll parses every product and collects the rows it couldn't read.
+func ParseAll(products []models.InsuranceProduct) Report {
+	report := Report{Rules: make([]Rules, 0, len(products)), Issues: []Issue{}}
+	for _, p := range products {
+		rules, issues := Parse(p)
+		report.Rules = append(report.Rules, rules)
+		report.Issues = append(report.Issues, issues...)
+	}
+	return report
+}
+
+// Parse turns the free-text eligibility columns of a product into Rules.
+// Only the English columns are read; the _zh columns are translations.
+func Parse(p models.InsuranceProduct) (Rules, []Issue) {
+	r := Rules{ProductID: p.InsuranceId, PetTypes: []PetType{}, WaitingPeriods: []WaitingPeriod{}, ExcludedBreeds: []string{}}
+	var issues []Issue
+	fail := func(field, value, reason string) {
+		issues = append(issues, Issue{ProductID: p.InsuranceId, Field: field, Value: value, Reason: reason})
+	}
+
+	if text := strings.TrimSpace(p.MinAge.String); text != "" {
+		if weeks, ok := ParseAgeWeeks(text); ok {
+			r.MinAgeWeeks = &weeks
+		} else {
+			fail("min_age", text, "no age found")
+		}
+	}
+	if text := strings.TrimSpace(p.MaxAge.String); text != "" {
+		if weeks, ok := ParseAgeWeeks(text); ok {
+			// "8 years" covers pets until their 9th birthday.
+			weeks = endOfAge(text, weeks)
+			r.MaxAgeWeeks = &weeks
+		} else {
+			fail("max_age", text, "no age found")
+		}
+	}
+
+	if text := strings.TrimSpace(p.SuitablePetType.String); text != "" {
+		for _, part := range strings.FieldsFunc(strings.ToLower(text), func(c rune) bool { return c == ',' || c == '/' || c == '&' || c == ' ' }) {
+			switch strings.TrimSuffix(part, "s") {
+			case "cat":
+				r.PetTypes = append(r.PetTypes, PetCat)
+			case "dog":
+				r.PetTypes = append(r.PetTypes, PetDog)
+			case "and", "or":
+			default:
+				fail("suitable_pet_type", text, "unknown pet type "+strconv.Quote(part))
+			}
+		}
+	}
+
+	if text := strings.TrimSpace(p.Coinsurance.String); text != "" {
+		schedule, err := ParseCoinsurance(text)
+		if err != nil {
+			fail("coinsurance", text, err.Error())
+		}
+		r.Coinsurance = schedule
+	}
+
+	if text := strings.TrimSpace(p.WaitingPeriod.String); text != "" {
+		periods, waiver := parseWaitingPeriods(text)
+		r.WaitingPeriods = append(r.WaitingPeriods, periods...)
+		r.WaitingPeriodWaiver = waiver
+		if len(periods) == 0 && !waiver {
+			fail("waiting_period", text, "no waiting period length found")
+		}
+	}
+
+	if text := strings.TrimSpace(p.BreedTypeRemark.String); text != "" {
+		breeds, cross := ParseExcludedBreeds(text)
+		r.ExcludedBreeds = append(r.ExcludedBreeds, breeds...)
+		r.CrossBreedsExcluded = cross
+		if len(breeds) == 0 && strings.Contains(strings.ToLower(text), "except") {
+			fail("breed_type_remark", text, "exclusions mentioned but no known breed found")
+		}
+	}
+
+	return r, issues
+}
+
+var ageUnitPattern = regexp.MustCompile(`(?i)(\d+(?:\.\d+)?)\s*(weeks?|months?|years?|yrs?)`)
+
+// ParseAgeWeeks reads an age such as "13 weeks", "6 months" or "11 years".
+func ParseAgeWeeks(text string) (int, bool) {
+	m := ageUnitPattern.FindStringSubmatch(text)
+	if m == nil {
+		return 0, false
+	}
+	v, err := strconv.ParseFloat(m[1], 64)
+	if err != nil {
+		return 0, false
+	}
+	return toWeeks(v, m[2]), true
+}
+
+func toWeeks(v float64, unit string) int {
+	switch strings.ToLower(unit)[0] {
+	case 'w':
+		return int(v)
+	case 'm':
+		return int(v * WeeksPerYear / 12)
+	}
+	return int(v * WeeksPerYear)
+}
+
+// endOfAge extends an age in whole months or years to the last week before
+// the next one, since insurers count age at last birthday.
+func endOfAge(text string, weeks int) int {
+	m := ageUnitPattern.FindStringSubmatch(text)
+	if m == nil || strings.ToLower(m[2])[0] == 'w' {
+		return weeks
+	}
+	v, err := strconv.ParseFloat(m[1], 64)
+	if err != nil {
+		return weeks
+	}
+	return toWeeks(v+1, m[2]) - 1
+}
+
+// AcceptsAge reports whether a pet aged ageWeeks can enrol.
+func (r *Rules) AcceptsAge(ageWeeks int) bool {
+	if r.MinAgeWeeks != nil && ageWeeks < *r.MinAgeWeeks {
+		return false
+	}
+	if r.MaxAgeWeeks != nil && ageWeeks > *r.MaxAgeWeeks {
+		return false
+	}
+	return true
+}
+
+// AcceptsPetType reports whether the product insures the given species.
+// Products without a parsed pet type accept anything.
+func (r *Rules) AcceptsPetType(t PetType) bool {
+	if len(r.PetTypes) == 0 {
+		return true
+	}
+	for _, pt := range r.PetTypes {
+		if pt == t {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	daysPattern   = regexp.MustCompile(`(?i)(\d+)[\s-]*days?`)
+	bulletPattern = regexp.MustCompile(`^\s*(?:[a-z0-9]{1,2}[.)]\s*)`)
+)
+
+// parseWaitingPeriods reads lines such as "a. Cancer or chronic renal
+// disease: 90 days" or "A 30-day waiting period ... resulting from illness".
+func parseWaitingPeriods(text string) (periods []WaitingPeriod, waiver bool) {
+	waiver = strings.Contains(strings.ToLower(text), "waive")
+	for _, line := range strings.Split(text, "\n") {
+		m := daysPattern.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		days, _ := strconv.Atoi(m[1])
+		condition := ""
+		if i := strings.Index(line, ":"); i >= 0 && i < strings.Index(line, m[0]) {
+			condition = strings.TrimSpace(bulletPattern.ReplaceAllString(line[:i], ""))
+		}
+		if strings.HasPrefix(strings.ToLower(condition), "other") || strings.HasPrefix(strings.ToLower(condition), "for medical") {
+			condition = ""
+		}
+		periods = append(periods, WaitingPeriod{Condition: condition, Days: days})
+	}
+	return periods, waiver
+}
@@ -0,0 +1,118 @@
+package eligibility
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/vf0429/Petwell_Backend/internal/models"
+)
+
+// Query holds the optional filters of a product search. Zero values match
+// anything.
+type Query struct {
+	PetType           PetType
+	AgeWeeks          int // negative for any age
+	Breed             string
+	ProviderID        int
+	Tags              []string // tag slugs, with or without a leading '#'
+	AnyTag            bool     // match products with any of Tags instead of all
+	MaxCoinsurancePct *float64
+	MinLimitHKD       int
+	// CoverageLimits holds each product's limit for the coverage type the
+	// search is about, as parsed by payout.ParseAmount. Products missing
+	// from it don't offer that coverage. Nil skips the limit filter.
+	CoverageLimits map[int]int
+}
+
+// Match is a product that passed every filter.
+type Match struct {
+	Product   models.InsuranceProduct
+	Rules     Rules
+	Relevance float64
+}
+
+// Search filters products by q and sorts them by relevance, best first.
+//
+// Relevance rewards what the filters asked for: plans dedicated to the
+// requested species, a lower customer share, a higher limit for the
+// requested coverage and matching tags.
+func Search(products []models.InsuranceProduct, q Query) []Match {
+	var matches []Match
+	for _, p := range products {
+		if q.ProviderID != 0 && p.ProviderId != q.ProviderID {
+			continue
+		}
+		rules, _ := Parse(p)
+		if q.PetType != "" && !rules.AcceptsPetType(q.PetType) {
+			continue
+		}
+		if q.AgeWeeks >= 0 && !rules.AcceptsAge(q.AgeWeeks) {
+			continue
+		}
+		if q.Breed != "" && !rules.AcceptsBreed(q.Breed) {
+			continue
+		}
+		tagged := matchingTags(p.Tags, q.Tags)
+		if len(q.Tags) > 0 && (tagged == 0 || !q.AnyTag && tagged < len(q.Tags)) {
+			continue
+		}
+
+		relevance := 0.0
+		share := rules.Coinsurance.CustomerSharePct(q.AgeWeeks, true)
+		if q.MaxCoinsurancePct != nil && share > *q.MaxCoinsurancePct {
+			continue
+		}
+		relevance += (100 - share) / 100
+
+		if q.CoverageLimits != nil {
+			limit, ok := q.CoverageLimits[p.InsuranceId]
+			if !ok || limit == 0 {
+				continue
+			}
+			if limit > 0 && limit < q.MinLimitHKD {
+				continue
+			}
+			// An uncapped coverage counts like twice the requested minimum.
+			if q.MinLimitHKD > 0 {
+				if limit < 0 {
+					relevance += 2
+				} else {
+					relevance += min(float64(limit)/float64(q.MinLimitHKD), 2)
+				}
+			}
+		}
+
+		if q.PetType != "" && len(rules.PetTypes) == 1 {
+			relevance += 0.5
+		}
+		relevance += float64(tagged)
+
+		matches = append(matches, Match{Product: p, Rules: rules, Relevance: relevance})
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Relevance > matches[j].Relevance
+	})
+	return matches
+}
+
+// SplitTags splits a tag string such as "#BudgetStarter #FixedPremium".
+func SplitTags(s string) []string {
+	return strings.FieldsFunc(s, func(c rune) bool { return c == '#' || c == ',' || c == ' ' || c == '\n' })
+}
+
+// matchingTags counts the wanted slugs the product is tagged with,
+// ignoring case.
+func matchingTags(tags []models.Tag, want []string) int {
+	have := make(map[string]bool, len(tags))
+	for _, t := range tags {
+		have[strings.ToLower(t.Slug)] = true
+	}
+	n := 0
+	for _, t := range want {
+		if have[strings.ToLower(strings.TrimPrefix(t, "#"))] {
+			n++
+		}
+	}
+	return n
+}
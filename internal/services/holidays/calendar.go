@@ -0,0 +1,149 @@
+// Package holidays is the Hong Kong public holiday calendar clinic opening
+// hours are read against. It is loaded from a versioned data file that
+// lists each gazetted general holiday by date, lunar festivals included, so
+// no lunar calendar has to be computed.
+package holidays
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/vf0429/Petwell_Backend/internal/models"
+)
+
+// DefaultPath is the holiday data file relative to the repo root.
+const DefaultPath = "assets/hk_public_holidays.json"
+
+const dateLayout = "2006-01-02"
+
+// Holiday is one general holiday. Lunar is the lunar month-day ("08-16")
+// of holidays set by the lunar calendar.
+type Holiday struct {
+	Date   string `json:"date"`
+	Name   string `json:"name"`
+	NameZh string `json:"name_zh"`
+	Lunar  string `json:"lunar,omitempty"`
+}
+
+// Calendar is the contents of the data file. Version changes whenever the
+// file does; Years are the years it lists completely.
+type Calendar struct {
+	Version  string    `json:"version"`
+	Notes    string    `json:"notes,omitempty"`
+	Years    []int     `json:"years"`
+	Holidays []Holiday `json:"holidays"`
+
+	byDate map[string]Holiday
+}
+
+var lunarDate = regexp.MustCompile(`^(0[1-9]|1[0-2])-(0[1-9]|[12]\d|30)$`)
+
+// Load reads and checks the calendar at path: every date must be valid,
+// unique and in one of the listed years. Holidays are sorted by date.
+func Load(path string) (*Calendar, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read holidays file: %w", err)
+	}
+	var c Calendar
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("failed to parse holidays file %s: %w", path, err)
+	}
+	if c.Version == "" {
+		return nil, fmt.Errorf("%s: version is required", path)
+	}
+	years := make(map[int]bool, len(c.Years))
+	for _, y := range c.Years {
+		years[y] = true
+	}
+
+	c.byDate = make(map[string]Holiday, len(c.Holidays))
+	for _, h := range c.Holidays {
+		day, err := time.Parse(dateLayout, h.Date)
+		switch {
+		case err != nil:
+			return nil, fmt.Errorf("%s: holiday %q: date must be 2006-01-02", path, h.Date)
+		case !years[day.Year()]:
+			return nil, fmt.Errorf("%s: holiday %s is outside the listed years", path, h.Date)
+		case h.Name == "":
+			return nil, fmt.Errorf("%s: holiday %s: name is required", path, h.Date)
+		case h.Lunar != "" && !lunarDate.MatchString(h.Lunar):
+			return nil, fmt.Errorf("%s: holiday %s: lunar must be a month-day like 08-15", path, h.Date)
+		}
+		if _, ok := c.byDate[h.Date]; ok {
+			return nil, fmt.Errorf("%s: holiday %s is listed twice", path, h.Date)
+		}
+		c.byDate[h.Date] = h
+	}
+	sort.Slice(c.Holidays, func(i, j int) bool { return c.Holidays[i].Date < c.Holidays[j].Date })
+	sort.Ints(c.Years)
+	return &c, nil
+}
+
+// date is t's date in Hong Kong.
+func date(t time.Time) string {
+	return t.In(models.HongKong).Format(dateLayout)
+}
+
+// On returns the holiday on t's Hong Kong date, if any.
+func (c *Calendar) On(t time.Time) (Holiday, bool) {
+	if c == nil {
+		return Holiday{}, false
+	}
+	h, ok := c.byDate[date(t)]
+	return h, ok
+}
+
+// IsHoliday reports whether t falls on a public holiday in Hong Kong. A nil
+// calendar has no holidays.
+func (c *Calendar) IsHoliday(t time.Time) bool {
+	_, ok := c.On(t)
+	return ok
+}
+
+// Covers reports whether the calendar lists the holidays of year.
+func (c *Calendar) Covers(year int) bool {
+	if c == nil {
+		return false
+	}
+	i := sort.SearchInts(c.Years, year)
+	return i < len(c.Years) && c.Years[i] == year
+}
+
+// InYear returns the holidays of year in date order.
+func (c *Calendar) InYear(year int) []Holiday {
+	prefix := fmt.Sprintf("%04d-", year)
+	out := []Holiday{}
+	if c == nil {
+		return out
+	}
+	for _, h := range c.Holidays {
+		if h.Date[:5] == prefix {
+			out = append(out, h)
+		}
+	}
+	return out
+}
+
+// Next returns the first holiday on or after t's Hong Kong date.
+func (c *Calendar) Next(t time.Time) (Holiday, bool) {
+	if c == nil {
+		return Holiday{}, false
+	}
+	from := date(t)
+	i := sort.Search(len(c.Holidays), func(i int) bool { return c.Holidays[i].Date >= from })
+	if i == len(c.Holidays) {
+		return Holiday{}, false
+	}
+	return c.Holidays[i], true
+}
+
+// Day is the holiday's date at midnight Hong Kong time.
+func (h Holiday) Day() time.Time {
+	day, _ := time.ParseInLocation(dateLayout, h.Date, models.HongKong)
+	return day
+}
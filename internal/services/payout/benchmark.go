@@ -0,0 +1,139 @@
+package payout
+
+import (
+	"errors"
+	"math"
+	"strings"
+
+	"github.com/vf0429/Petwell_Backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// Location is where the user takes their pet: a district or a clinic.
+type Location struct {
+	District string `json:"district,omitempty"`
+	ClinicID string `json:"clinic_id,omitempty"`
+}
+
+// LocalCosts describes how a scenario was rescaled to a location.
+type LocalCosts struct {
+	Location
+	BaseTotalCostHKD int              `json:"base_total_cost_hkd"`
+	Items            []ItemAdjustment `json:"items"`
+}
+
+// ItemAdjustment is one cost item's scaling. Factor is the local benchmark
+// over the Hong Kong-wide one; 1 when either is missing.
+type ItemAdjustment struct {
+	ItemName      string  `json:"item_name"`
+	BaseAmountHKD int     `json:"base_amount_hkd"`
+	AmountHKD     int     `json:"amount_hkd"`
+	Factor        float64 `json:"factor"`
+	Source        string  `json:"source"` // "clinic", "district" or "baseline"
+}
+
+// LocalizeCosts rescales the cost items of s in place by the benchmarks of
+// loc, and its payouts with them. A payout whose insurer is linked to a
+// product moves with what that product would pay for the old and new
+// bills, so limits are respected; other payouts keep their share of the
+// total. Items without a local benchmark, or every item when loc has none,
+// keep the baseline price. s must have its cost items, payouts and
+// insurers loaded.
+func LocalizeCosts(db *gorm.DB, repo *models.InsuranceRepository, s *models.Scenario, loc Location) (*LocalCosts, error) {
+	var rows []models.CostBenchmark
+	query := db.Where("district = '' AND clinic_id = ''")
+	if loc.ClinicID != "" {
+		query = query.Or("clinic_id = ?", loc.ClinicID)
+	} else {
+		query = query.Or("district = ? AND clinic_id = ''", loc.District)
+	}
+	if err := query.Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	baseline, local := make(map[string]int), make(map[string]int)
+	district := loc.District
+	for _, b := range rows {
+		switch {
+		case b.ClinicID == "" && b.District == "":
+			baseline[itemKey(b.ItemName)] = b.AmountHKD
+		default:
+			local[itemKey(b.ItemName)] = b.AmountHKD
+			if b.ClinicID != "" && b.District != "" {
+				district = b.District
+			}
+		}
+	}
+	// Items a clinic has no price for fall back to its district.
+	var fallback map[string]int
+	if loc.ClinicID != "" && district != "" {
+		var districtRows []models.CostBenchmark
+		if err := db.Where("district = ? AND clinic_id = ''", district).Find(&districtRows).Error; err != nil {
+			return nil, err
+		}
+		fallback = make(map[string]int, len(districtRows))
+		for _, b := range districtRows {
+			fallback[itemKey(b.ItemName)] = b.AmountHKD
+		}
+	}
+
+	out := &LocalCosts{Location: loc, BaseTotalCostHKD: s.TotalCostHKD, Items: make([]ItemAdjustment, len(s.CostItems))}
+	before := append([]models.CostItem(nil), s.CostItems...)
+	total := 0
+	for i := range s.CostItems {
+		item := &s.CostItems[i]
+		adj := ItemAdjustment{ItemName: item.ItemName, BaseAmountHKD: item.AmountHKD, Factor: 1, Source: "baseline"}
+		key := itemKey(item.ItemName)
+		if base := baseline[key]; base > 0 {
+			if amount, ok := local[key]; ok {
+				adj.Factor, adj.Source = float64(amount)/float64(base), "district"
+				if loc.ClinicID != "" {
+					adj.Source = "clinic"
+				}
+			} else if amount, ok := fallback[key]; ok {
+				adj.Factor, adj.Source = float64(amount)/float64(base), "district"
+			}
+		}
+		adj.Factor = math.Round(adj.Factor*1000) / 1000
+		item.AmountHKD = int(math.Round(float64(item.AmountHKD) * adj.Factor))
+		adj.AmountHKD = item.AmountHKD
+		total += item.AmountHKD
+		out.Items[i] = adj
+	}
+	s.TotalCostHKD = total
+
+	plans := make(map[int]*Plan)
+	for i := range s.Payouts {
+		p := &s.Payouts[i]
+		ratio := 1.0
+		if out.BaseTotalCostHKD > 0 {
+			ratio = float64(total) / float64(out.BaseTotalCostHKD)
+		}
+		if id := p.Insurer.ProductID; id != nil {
+			plan, ok := plans[*id]
+			if !ok {
+				loaded, err := LoadPlan(repo, *id)
+				if err != nil && !errors.Is(err, models.ErrNotFound) && !errors.Is(err, ErrNoCoverage) {
+					return nil, err
+				}
+				plan, plans[*id] = loaded, loaded
+			}
+			if plan != nil {
+				if old := Calculate(plan, before).EstimatedPayoutHKD; old > 0 {
+					ratio = float64(Calculate(plan, s.CostItems).EstimatedPayoutHKD) / float64(old)
+				}
+			}
+		}
+		p.EstimatedPayoutHKD = int(math.Round(float64(p.EstimatedPayoutHKD) * ratio))
+		if total > 0 && total != out.BaseTotalCostHKD {
+			p.CoveragePercentage = math.Round(float64(p.EstimatedPayoutHKD)/float64(total)*1000) / 10
+		}
+	}
+	return out, nil
+}
+
+// itemKey matches cost item names the way districts are matched: ignoring
+// case and surrounding space.
+func itemKey(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
@@ -0,0 +1,65 @@
+package payout
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/vf0429/Petwell_Backend/internal/migrations"
+	"github.com/vf0429/Petwell_Backend/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestLocalizeCosts(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "scenarios.db")), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrations.Scenarios.Up(sqlDB); err != nil {
+		t.Fatal(err)
+	}
+	benchmarks := []models.CostBenchmark{
+		{ItemName: "consultation", AmountHKD: 500},
+		{ItemName: "surgery_fee", AmountHKD: 20000},
+		{ItemName: "Consultation", District: "Wan Chai", AmountHKD: 600},
+		{ItemName: "surgery_fee ", District: "wan chai", AmountHKD: 25000},
+	}
+	if err := db.Create(&benchmarks).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		loc     Location
+		amounts []int
+		sources []string
+	}{
+		{"district ignores case", Location{District: "WAN CHAI"}, []int{600, 25000}, []string{"district", "district"}},
+		{"district without benchmarks", Location{District: "Sai Kung"}, []int{500, 20000}, []string{"baseline", "baseline"}},
+		{"unknown clinic", Location{ClinicID: "no-such-clinic"}, []int{500, 20000}, []string{"baseline", "baseline"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := models.Scenario{TotalCostHKD: 20500, CostItems: []models.CostItem{
+				{ItemName: "consultation", AmountHKD: 500},
+				{ItemName: "Surgery_Fee", AmountHKD: 20000},
+			}}
+			local, err := LocalizeCosts(db, nil, &s, tt.loc)
+			if err != nil {
+				t.Fatalf("LocalizeCosts: %v", err)
+			}
+			for i, adj := range local.Items {
+				if adj.AmountHKD != tt.amounts[i] || adj.Source != tt.sources[i] {
+					t.Errorf("items[%d] = $%d from %s, want $%d from %s", i, adj.AmountHKD, adj.Source, tt.amounts[i], tt.sources[i])
+				}
+			}
+			if want := tt.amounts[0] + tt.amounts[1]; s.TotalCostHKD != want {
+				t.Errorf("total = %d, want %d", s.TotalCostHKD, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,210 @@
+package payout
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/vf0429/Petwell_Backend/internal/models"
+)
+
+// LineItem is the calculated reimbursement for one CostItem.
+type LineItem struct {
+	ItemName        string `json:"item_name"`
+	AmountHKD       int    `json:"amount_hkd"`
+	CoverageType    string `json:"coverage_type,omitempty"`
+	SubCoverageName string `json:"sub_coverage_name,omitempty"`
+	PayoutHKD       int    `json:"payout_hkd"`
+	Explanation     string `json:"explanation"`
+}
+
+// Result is the estimated reimbursement of a bill under one plan.
+type Result struct {
+	ProductID          int        `json:"product_id"`
+	ProductName        string     `json:"product_name"`
+	ProductNameZh      string     `json:"product_name_zh,omitempty"`
+	TotalCostHKD       int        `json:"total_cost_hkd"`
+	EstimatedPayoutHKD int        `json:"estimated_payout_hkd"`
+	OutOfPocketHKD     int        `json:"out_of_pocket_hkd"`
+	CoveragePercentage float64    `json:"coverage_percentage"`
+	Analysis           string     `json:"analysis"`
+	Items              []LineItem `json:"items"`
+}
+
+// itemKeywords maps the cost item keys used by the scenarios to the words
+// insurers use for the matching coverage or sub-coverage.
+var itemKeywords = map[string][]string{
+	"consultation":          {"consultation", "診金", "诊金"},
+	"medication":            {"medication", "medicine", "drug", "藥物", "药物"},
+	"treatment":             {"medical", "醫療", "医疗"},
+	"surgery_fee":           {"surgery", "surgical", "手術", "手术"},
+	"anesthetist":           {"anaesthe", "anesthe", "麻醉"},
+	"operating_theatre":     {"operating theatre", "operating room", "手術室"},
+	"hospitalization":       {"hospital", "住院"},
+	"xray_lab":              {"x-ray", "xray", "lab", "ultrasound", "化驗", "x光"},
+	"misc":                  {"miscellaneous", "雜項"},
+	"chemotherapy":          {"chemotherapy", "cancer", "化療", "癌症"},
+	"third_party_liability": {"third party", "liability", "第三者"},
+}
+
+// keywordsFor returns the search terms for a cost item name. Unknown
+// names fall back to the name itself with underscores as spaces.
+func keywordsFor(itemName string) []string {
+	key := strings.ToLower(strings.TrimSpace(itemName))
+	if kw, ok := itemKeywords[key]; ok {
+		return kw
+	}
+	return []string{strings.ReplaceAll(key, "_", " ")}
+}
+
+func containsAny(text string, keywords []string) bool {
+	text = strings.ToLower(text)
+	if text == "" {
+		return false
+	}
+	for _, kw := range keywords {
+		if strings.Contains(text, kw) {
+			return true
+		}
+	}
+	return false
+}
+
+// match finds the coverage (and sub-limit, if any) that pays for an item.
+// Sub-limits are checked first because they are the most specific; items
+// that match nothing fall back to a general "medical" coverage.
+func (p *Plan) match(itemName string) (cov *Coverage, sub *SubLimit) {
+	keywords := keywordsFor(itemName)
+	for i := range p.Coverages {
+		for j := range p.Coverages[i].SubLimits {
+			s := &p.Coverages[i].SubLimits[j]
+			if containsAny(s.Name, keywords) || containsAny(s.NameZh, keywords) {
+				return &p.Coverages[i], s
+			}
+		}
+	}
+	for i := range p.Coverages {
+		c := &p.Coverages[i]
+		if containsAny(c.CoverageType, keywords) || containsAny(c.CoverageTypeZh, keywords) {
+			return c, nil
+		}
+	}
+	for i := range p.Coverages {
+		if containsAny(p.Coverages[i].CoverageType, []string{"medical"}) {
+			return &p.Coverages[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// remaining tracks how much of each cap is left while a bill is processed.
+type remaining map[string]int
+
+func (r remaining) take(key string, limit, want int) (int, bool) {
+	if limit == NoLimit {
+		return want, false
+	}
+	left, ok := r[key]
+	if !ok {
+		left = limit
+	}
+	if want > left {
+		r[key] = 0
+		return left, true
+	}
+	r[key] = left - want
+	return want, false
+}
+
+// Calculate estimates what plan reimburses for items. Each item is paid at
+// the plan's reimbursement rate, then capped by its sub-limit, its coverage
+// limit and finally the plan's annual limit. Caps are shared by every item
+// that falls under them.
+func Calculate(plan *Plan, items []models.CostItem) Result {
+	res := Result{
+		ProductID:     plan.ProductID,
+		ProductName:   plan.ProductName,
+		ProductNameZh: plan.ProductNameZh,
+		Items:         make([]LineItem, 0, len(items)),
+	}
+	caps := make(remaining)
+	var notes []string
+
+	for _, item := range items {
+		line := LineItem{ItemName: item.ItemName, AmountHKD: item.AmountHKD}
+		res.TotalCostHKD += item.AmountHKD
+
+		cov, sub := plan.match(item.ItemName)
+		if cov == nil || cov.LimitHKD == 0 || (sub != nil && sub.LimitHKD == 0) {
+			line.Explanation = "不在保障范围内"
+			notes = appendOnce(notes, fmt.Sprintf("%s不在保障范围内", item.ItemName))
+			res.Items = append(res.Items, line)
+			continue
+		}
+		line.CoverageType = cov.CoverageType
+		if sub != nil {
+			line.SubCoverageName = sub.Name
+		}
+
+		pay := int(math.Round(float64(item.AmountHKD) * plan.ReimbursementRate))
+		reasons := []string{fmt.Sprintf("按%s赔付", formatPercent(plan.ReimbursementRate*100))}
+
+		if sub != nil {
+			var capped bool
+			pay, capped = caps.take(fmt.Sprintf("sub:%d", sub.SubCoverageID), sub.LimitHKD, pay)
+			if capped {
+				reason := fmt.Sprintf("受%s细项上限 $%d 限制", sub.Name, sub.LimitHKD)
+				reasons = append(reasons, reason)
+				notes = appendOnce(notes, reason)
+			}
+		}
+
+		var capped bool
+		pay, capped = caps.take(fmt.Sprintf("cov:%d", cov.CoverageID), cov.LimitHKD, pay)
+		if capped {
+			reason := fmt.Sprintf("受%s保障上限 $%d 限制", cov.CoverageType, cov.LimitHKD)
+			reasons = append(reasons, reason)
+			notes = appendOnce(notes, reason)
+		}
+
+		pay, capped = caps.take("annual", plan.AnnualLimitHKD, pay)
+		if capped {
+			reason := fmt.Sprintf("已达年度上限 $%d", plan.AnnualLimitHKD)
+			reasons = append(reasons, reason)
+			notes = appendOnce(notes, reason)
+		}
+
+		line.PayoutHKD = pay
+		line.Explanation = strings.Join(reasons, "，")
+		res.EstimatedPayoutHKD += pay
+		res.Items = append(res.Items, line)
+	}
+
+	res.OutOfPocketHKD = res.TotalCostHKD - res.EstimatedPayoutHKD
+	if res.TotalCostHKD > 0 {
+		res.CoveragePercentage = math.Round(float64(res.EstimatedPayoutHKD)/float64(res.TotalCostHKD)*1000) / 10
+	}
+
+	summary := []string{fmt.Sprintf("按%s赔付", formatPercent(plan.ReimbursementRate*100))}
+	if res.EstimatedPayoutHKD == 0 {
+		summary = []string{"完全不赔"}
+	}
+	summary = append(summary, notes...)
+	summary = append(summary, fmt.Sprintf("预计自付 $%d", res.OutOfPocketHKD))
+	res.Analysis = strings.Join(summary, "；") + "。"
+	return res
+}
+
+func appendOnce(list []string, s string) []string {
+	for _, existing := range list {
+		if existing == s {
+			return list
+		}
+	}
+	return append(list, s)
+}
+
+func formatPercent(v float64) string {
+	return strconv.FormatFloat(math.Round(v*10)/10, 'f', -1, 64) + "%"
+}
@@ -0,0 +1,136 @@
+package payout
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+
+	"github.com/vf0429/Petwell_Backend/internal/models"
+)
+
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		name string
+		in   models.NullJsonString
+		want int
+	}{
+		{"null", models.NullJsonString{}, 0},
+		{"blank", text("  "), 0},
+		{"plain", text("30000"), 30000},
+		{"currency and commas", text("HK$30,000"), 30000},
+		{"decimals are dropped", text("$12,500.50 per year"), 12500},
+		{"no number", text("Unlimited"), NoLimit},
+		{"chinese without number", text("不設上限"), NoLimit},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseAmount(tt.in); got != tt.want {
+				t.Errorf("ParseAmount(%q) = %d, want %d", tt.in.String, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCalculate(t *testing.T) {
+	medical := Coverage{CoverageID: 1, CoverageType: "Medical Expenses", LimitHKD: NoLimit}
+	tests := []struct {
+		name       string
+		plan       Plan
+		items      []models.CostItem
+		wantPayout int
+		wantItems  []int
+		wantPct    float64
+		analysis   string // substring of the analysis
+	}{
+		{
+			name:       "reimbursement rate",
+			plan:       Plan{ReimbursementRate: 0.8, AnnualLimitHKD: NoLimit, Coverages: []Coverage{medical}},
+			items:      []models.CostItem{{ItemName: "consultation", AmountHKD: 500}, {ItemName: "medication", AmountHKD: 250}},
+			wantPayout: 600,
+			wantItems:  []int{400, 200},
+			wantPct:    80,
+			analysis:   "按80%赔付",
+		},
+		{
+			name: "sub-limit shared by items",
+			plan: Plan{ReimbursementRate: 1, AnnualLimitHKD: NoLimit, Coverages: []Coverage{{
+				CoverageID: 1, CoverageType: "Medical Expenses", LimitHKD: 10000,
+				SubLimits: []SubLimit{{SubCoverageID: 7, Name: "Consultation", LimitHKD: 300}},
+			}}},
+			items:      []models.CostItem{{ItemName: "consultation", AmountHKD: 200}, {ItemName: "consultation", AmountHKD: 200}, {ItemName: "treatment", AmountHKD: 100}},
+			wantPayout: 400,
+			wantItems:  []int{200, 100, 100},
+			wantPct:    80,
+			analysis:   "受Consultation细项上限 $300 限制",
+		},
+		{
+			name: "coverage limit",
+			plan: Plan{ReimbursementRate: 1, AnnualLimitHKD: NoLimit, Coverages: []Coverage{
+				medical, {CoverageID: 2, CoverageType: "Surgical Expenses", LimitHKD: 10000},
+			}},
+			items:      []models.CostItem{{ItemName: "surgery_fee", AmountHKD: 8000}, {ItemName: "surgery_fee", AmountHKD: 8000}},
+			wantPayout: 10000,
+			wantItems:  []int{8000, 2000},
+			wantPct:    62.5,
+			analysis:   "受Surgical Expenses保障上限 $10000 限制",
+		},
+		{
+			name:       "annual limit",
+			plan:       Plan{ReimbursementRate: 1, AnnualLimitHKD: 5000, Coverages: []Coverage{medical}},
+			items:      []models.CostItem{{ItemName: "treatment", AmountHKD: 4000}, {ItemName: "hospitalization", AmountHKD: 4000}},
+			wantPayout: 5000,
+			wantItems:  []int{4000, 1000},
+			wantPct:    62.5,
+			analysis:   "已达年度上限 $5000",
+		},
+		{
+			name: "not covered",
+			plan: Plan{ReimbursementRate: 0.8, AnnualLimitHKD: NoLimit, Coverages: []Coverage{
+				{CoverageID: 3, CoverageType: "Third Party Liability", LimitHKD: 0},
+			}},
+			items:      []models.CostItem{{ItemName: "third_party_liability", AmountHKD: 50000}},
+			wantPayout: 0,
+			wantItems:  []int{0},
+			wantPct:    0,
+			analysis:   "完全不赔",
+		},
+		{
+			name:       "no coverage matches",
+			plan:       Plan{ReimbursementRate: 0.8, AnnualLimitHKD: NoLimit, Coverages: []Coverage{{CoverageID: 4, CoverageType: "Cancer Treatment", LimitHKD: 20000}}},
+			items:      []models.CostItem{{ItemName: "consultation", AmountHKD: 500}},
+			wantPayout: 0,
+			wantItems:  []int{0},
+			wantPct:    0,
+			analysis:   "consultation不在保障范围内",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := Calculate(&tt.plan, tt.items)
+			if res.EstimatedPayoutHKD != tt.wantPayout {
+				t.Errorf("payout = %d, want %d", res.EstimatedPayoutHKD, tt.wantPayout)
+			}
+			if res.OutOfPocketHKD != res.TotalCostHKD-tt.wantPayout {
+				t.Errorf("out of pocket = %d, want %d", res.OutOfPocketHKD, res.TotalCostHKD-tt.wantPayout)
+			}
+			if res.CoveragePercentage != tt.wantPct {
+				t.Errorf("coverage = %g%%, want %g%%", res.CoveragePercentage, tt.wantPct)
+			}
+			if len(res.Items) != len(tt.wantItems) {
+				t.Fatalf("got %d line items, want %d", len(res.Items), len(tt.wantItems))
+			}
+			for i, want := range tt.wantItems {
+				if got := res.Items[i].PayoutHKD; got != want {
+					t.Errorf("items[%d] (%s) pays %d, want %d", i, res.Items[i].ItemName, got, want)
+				}
+			}
+			if !strings.Contains(res.Analysis, tt.analysis) {
+				t.Errorf("analysis %q does not mention %q", res.Analysis, tt.analysis)
+			}
+		})
+	}
+}
+
+func text(s string) models.NullJsonString {
+	return models.NullJsonString{NullString: sql.NullString{String: s, Valid: true}}
+}
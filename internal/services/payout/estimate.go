@@ -0,0 +1,53 @@
+package payout
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/vf0429/Petwell_Backend/internal/models"
+	"github.com/vf0429/Petwell_Backend/internal/services/eligibility"
+)
+
+// EstimateBill calculates items against every product a pet of petType
+// and ageWeeks can enrol in and returns the results ranked by estimated
+// payout, highest first. An empty petType or a negative age skips that check.
+// Eligible products without coverage data are returned in uncovered.
+func EstimateBill(repo *models.InsuranceRepository, items []models.CostItem, petType eligibility.PetType, ageWeeks int) (results []Result, uncovered []int, err error) {
+	products, err := repo.Products()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var ids []int
+	for _, p := range products {
+		rules, _ := eligibility.Parse(p)
+		if petType != "" && !rules.AcceptsPetType(petType) {
+			continue
+		}
+		if ageWeeks >= 0 && !rules.AcceptsAge(ageWeeks) {
+			continue
+		}
+		ids = append(ids, p.InsuranceId)
+	}
+
+	results, uncovered = make([]Result, 0, len(ids)), []int{}
+	for _, id := range ids {
+		plan, err := LoadPlan(repo, id)
+		if errors.Is(err, ErrNoCoverage) {
+			uncovered = append(uncovered, id)
+			continue
+		} else if err != nil {
+			return nil, nil, err
+		}
+		plan.SetPetAge(ageWeeks)
+		results = append(results, Calculate(plan, items))
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].EstimatedPayoutHKD != results[j].EstimatedPayoutHKD {
+			return results[i].EstimatedPayoutHKD > results[j].EstimatedPayoutHKD
+		}
+		return results[i].ProductID < results[j].ProductID
+	})
+	return results, uncovered, nil
+}
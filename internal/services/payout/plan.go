@@ -0,0 +1,162 @@
+package payout
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/vf0429/Petwell_Backend/internal/models"
+	"github.com/vf0429/Petwell_Backend/internal/services/eligibility"
+)
+
+// NoLimit marks a coverage or sub-limit without a monetary cap.
+const NoLimit = -1
+
+// ErrNoCoverage is returned for a product without any coverage_limit rows,
+// which means the coverage sources haven't been imported rather than that
+// the product pays nothing.
+var ErrNoCoverage = errors.New("no coverage limits imported")
+
+// Plan is the subset of a product's terms the calculator needs.
+type Plan struct {
+	ProductID         int
+	ProductName       string
+	ProductNameZh     string
+	Coinsurance       eligibility.Schedule
+	ReimbursementRate float64 // share of eligible costs the insurer pays, 0..1
+	AnnualLimitHKD    int     // NoLimit when the product has no overall cap
+	Coverages         []Coverage
+}
+
+// Coverage is one coverage_limit row joined with its coverage_list type.
+type Coverage struct {
+	CoverageID     int
+	CoverageType   string
+	CoverageTypeZh string
+	LimitHKD       int // NoLimit, 0 for not covered, or the cap in HKD
+	SubLimits      []SubLimit
+}
+
+// SubLimit is one sub_coverage_limit row under a coverage.
+type SubLimit struct {
+	SubCoverageID int
+	Name          string
+	NameZh        string
+	LimitHKD      int
+}
+
+var amountPattern = regexp.MustCompile(`\d[\d,]*(\.\d+)?`)
+
+// ParseAmount turns a free-text limit such as "HK$30,000" or "Unlimited"
+// into HKD. Empty values mean the item isn't covered; text without any
+// number is treated as uncapped.
+func ParseAmount(s models.NullJsonString) int {
+	if !s.Valid || strings.TrimSpace(s.String) == "" {
+		return 0
+	}
+	m := amountPattern.FindString(s.String)
+	if m == "" {
+		return NoLimit
+	}
+	v, err := strconv.ParseFloat(strings.ReplaceAll(m, ",", ""), 64)
+	if err != nil {
+		return NoLimit
+	}
+	return int(v)
+}
+
+// SetPetAge picks the reimbursement rate for a pet of ageWeeks, or the
+// headline rate when the age is negative. Where a plan pays differently
+// at network clinics, the network rate is assumed.
+func (p *Plan) SetPetAge(ageWeeks int) {
+	p.ReimbursementRate = p.Coinsurance.ReimbursementRate(ageWeeks, true)
+}
+
+// LoadPlan reads a product and its coverage tree from the insurance DB.
+func LoadPlan(repo *models.InsuranceRepository, productID int) (*Plan, error) {
+	product, err := repo.Product(productID)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return nil, fmt.Errorf("product %d: %w", productID, err)
+		}
+		return nil, err
+	}
+
+	// Unparseable coinsurance text leaves a partial schedule; the rules
+	// report at /insurance-rules lists those products.
+	schedule, _ := eligibility.ParseCoinsurance(product.Coinsurance.String)
+	plan := &Plan{
+		ProductID:      productID,
+		ProductName:    strings.TrimSpace(product.InsuranceName),
+		ProductNameZh:  product.InsuranceNameZh.String,
+		Coinsurance:    schedule,
+		AnnualLimitHKD: NoLimit,
+	}
+	plan.SetPetAge(-1)
+
+	coverageList, err := repo.CoverageList()
+	if err != nil {
+		return nil, err
+	}
+	types := make(map[int]models.CoverageItem, len(coverageList))
+	for _, item := range coverageList {
+		types[item.CoverageId] = item
+	}
+
+	limits, err := repo.ProductCoverageLimits(productID)
+	if err != nil {
+		return nil, err
+	}
+	if len(limits) == 0 {
+		return nil, fmt.Errorf("product %d: %w", productID, ErrNoCoverage)
+	}
+	index := make(map[int]int)
+	for _, l := range limits {
+		item, ok := types[l.CoverageId]
+		if !ok {
+			continue
+		}
+		cov := Coverage{
+			CoverageID:     l.CoverageId,
+			CoverageType:   item.CoverageType,
+			CoverageTypeZh: item.CoverageTypeZh.String,
+			LimitHKD:       ParseAmount(l.CoverageLimit),
+		}
+
+		// An "annual" coverage row caps the whole policy year rather than a
+		// single kind of treatment. A blank or zero one sets no cap.
+		if strings.Contains(strings.ToLower(cov.CoverageType), "annual") {
+			if cov.LimitHKD != 0 {
+				plan.AnnualLimitHKD = cov.LimitHKD
+			}
+			continue
+		}
+		index[cov.CoverageID] = len(plan.Coverages)
+		plan.Coverages = append(plan.Coverages, cov)
+	}
+
+	subLimits, err := repo.ProductSubCoverageLimits(productID)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range subLimits {
+		i, ok := index[s.ParentCoverageId]
+		if !ok {
+			continue
+		}
+		sub := SubLimit{
+			SubCoverageID: s.SubCoverageId,
+			Name:          s.SubCoverageName.String,
+			NameZh:        s.SubCoverageNameZh.String,
+			LimitHKD:      ParseAmount(s.SubLimit),
+		}
+		if sub.LimitHKD == 0 && strings.TrimSpace(s.SubLimit.String) == "" {
+			// A blank sub-limit only names the item; the parent limit applies.
+			sub.LimitHKD = NoLimit
+		}
+		plan.Coverages[i].SubLimits = append(plan.Coverages[i].SubLimits, sub)
+	}
+	return plan, nil
+}
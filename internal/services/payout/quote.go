@@ -0,0 +1,162 @@
+package payout
+
+import (
+	"math"
+	"sort"
+
+	"github.com/vf0429/Petwell_Backend/internal/models"
+	"github.com/vf0429/Petwell_Backend/internal/services/eligibility"
+)
+
+// Payment modes of the premium tables.
+const (
+	PaymentMonthly = "monthly"
+	PaymentAnnual  = "annual"
+)
+
+// Profile is the pet a premium quote is for.
+type Profile struct {
+	PetType  eligibility.PetType
+	AgeWeeks int
+	Breed    string
+	// BreedClass picks the premium rows; ClassifyBreed(Breed) when empty.
+	BreedClass eligibility.BreedClass
+}
+
+// Quote is the premium of one eligible product for a profile and what it
+// would have paid towards each scenario.
+type Quote struct {
+	ProductID         int      `json:"product_id"`
+	ProductName       string   `json:"product_name"`
+	ProductNameZh     string   `json:"product_name_zh,omitempty"`
+	BreedClass        string   `json:"breed_class"` // class of the premium rows used
+	MonthlyPremiumHKD *float64 `json:"monthly_premium_hkd"`
+	AnnualPremiumHKD  *float64 `json:"annual_premium_hkd"`
+	// AnnualCostHKD is the cheaper of paying annually or twelve monthly
+	// premiums.
+	AnnualCostHKD float64   `json:"annual_cost_hkd"`
+	Scenarios     []Benefit `json:"scenarios"`
+}
+
+// Benefit weighs a scenario's estimated payout against a year of premiums.
+type Benefit struct {
+	ScenarioID         string  `json:"scenario_id"`
+	Title              string  `json:"title"`
+	TotalCostHKD       int     `json:"total_cost_hkd"`
+	EstimatedPayoutHKD int     `json:"estimated_payout_hkd"`
+	OutOfPocketHKD     int     `json:"out_of_pocket_hkd"`
+	NetBenefitHKD      float64 `json:"net_benefit_hkd"`    // payout minus the annual cost
+	PayoutPerPremium   float64 `json:"payout_per_premium"` // HKD paid out per HKD of annual cost
+}
+
+// QuoteProducts prices every product the profile is eligible for and
+// calculates each scenario's bill under it. Quotes are sorted by annual
+// cost, cheapest first; eligible products without a premium for the
+// profile are returned in unpriced.
+func QuoteProducts(repo *models.InsuranceRepository, profile Profile, scenarios []models.Scenario) (quotes []Quote, unpriced []int, err error) {
+	products, err := repo.Products()
+	if err != nil {
+		return nil, nil, err
+	}
+	premiums, err := repo.Premiums()
+	if err != nil {
+		return nil, nil, err
+	}
+	byProduct := make(map[int][]models.Premium)
+	for _, p := range premiums {
+		byProduct[p.ProductId] = append(byProduct[p.ProductId], p)
+	}
+	class := profile.BreedClass
+	if class == "" {
+		class = eligibility.ClassifyBreed(profile.PetType, profile.Breed)
+	}
+
+	quotes, unpriced = []Quote{}, []int{}
+	matches := eligibility.Search(products, eligibility.Query{PetType: profile.PetType, AgeWeeks: profile.AgeWeeks, Breed: profile.Breed})
+	for _, m := range matches {
+		id := m.Product.InsuranceId
+		monthly, monthlyClass := premiumFor(byProduct[id], profile, class, PaymentMonthly)
+		annual, annualClass := premiumFor(byProduct[id], profile, class, PaymentAnnual)
+		if monthly == nil && annual == nil {
+			unpriced = append(unpriced, id)
+			continue
+		}
+
+		plan, err := LoadPlan(repo, id)
+		if err != nil {
+			return nil, nil, err
+		}
+		plan.SetPetAge(profile.AgeWeeks)
+		q := Quote{
+			ProductID:         id,
+			ProductName:       plan.ProductName,
+			ProductNameZh:     plan.ProductNameZh,
+			BreedClass:        string(annualClass),
+			MonthlyPremiumHKD: monthly,
+			AnnualPremiumHKD:  annual,
+			AnnualCostHKD:     math.Inf(1),
+			Scenarios:         make([]Benefit, 0, len(scenarios)),
+		}
+		if monthly != nil {
+			q.BreedClass = string(monthlyClass)
+			q.AnnualCostHKD = roundCents(*monthly * 12)
+		}
+		if annual != nil && *annual < q.AnnualCostHKD {
+			q.AnnualCostHKD = *annual
+		}
+
+		for _, s := range scenarios {
+			res := Calculate(plan, s.CostItems)
+			b := Benefit{
+				ScenarioID:         s.ID,
+				Title:              s.Title,
+				TotalCostHKD:       res.TotalCostHKD,
+				EstimatedPayoutHKD: res.EstimatedPayoutHKD,
+				OutOfPocketHKD:     res.OutOfPocketHKD,
+				NetBenefitHKD:      roundCents(float64(res.EstimatedPayoutHKD) - q.AnnualCostHKD),
+			}
+			if q.AnnualCostHKD > 0 {
+				b.PayoutPerPremium = math.Round(float64(res.EstimatedPayoutHKD)/q.AnnualCostHKD*100) / 100
+			}
+			q.Scenarios = append(q.Scenarios, b)
+		}
+		quotes = append(quotes, q)
+	}
+
+	sort.Ints(unpriced)
+	sort.SliceStable(quotes, func(i, j int) bool {
+		if quotes[i].AnnualCostHKD != quotes[j].AnnualCostHKD {
+			return quotes[i].AnnualCostHKD < quotes[j].AnnualCostHKD
+		}
+		return quotes[i].ProductID < quotes[j].ProductID
+	})
+	return quotes, unpriced, nil
+}
+
+// premiumFor finds the premium row for the profile's pet type and age
+// under mode, preferring rows for class over the product's "any" rows.
+func premiumFor(rows []models.Premium, profile Profile, class eligibility.BreedClass, mode string) (*float64, eligibility.BreedClass) {
+	var fallback *models.Premium
+	for i, p := range rows {
+		if p.PaymentMode != mode || eligibility.PetType(p.PetType) != profile.PetType {
+			continue
+		}
+		if profile.AgeWeeks < p.MinAgeWeeks || p.MaxAgeWeeks != nil && profile.AgeWeeks > *p.MaxAgeWeeks {
+			continue
+		}
+		switch eligibility.BreedClass(p.BreedClass) {
+		case class:
+			return &rows[i].PremiumHKD, class
+		case eligibility.BreedAny:
+			fallback = &rows[i]
+		}
+	}
+	if fallback == nil {
+		return nil, ""
+	}
+	return &fallback.PremiumHKD, eligibility.BreedAny
+}
+
+func roundCents(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,136 @@
+package payout
+
+import (
+	"database/sql"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/vf0429/Petwell_Backend/internal/config"
+	"github.com/vf0429/Petwell_Backend/internal/migrations"
+	"github.com/vf0429/Petwell_Backend/internal/models"
+	"github.com/vf0429/Petwell_Backend/internal/services/eligibility"
+)
+
+// openQuoteTestRepo builds an insurance DB from the published products in
+// assets/insurance/products.json. Only product 1 (OneDegree Essential Plan)
+// gets coverage and premiums, as the sources don't have them yet.
+func openQuoteTestRepo(t *testing.T) *models.InsuranceRepository {
+	t.Helper()
+	data, err := os.ReadFile("../../../assets/insurance/products.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var products []map[string]interface{}
+	if err := json.Unmarshal(data, &products); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "insurance.db")
+	db, err := sql.Open("sqlite3", "file:"+path+"?_foreign_keys=on")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := migrations.Insurance.Up(db); err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range products {
+		if _, err := db.Exec("INSERT OR IGNORE INTO insurance_provider (company_id, company_name) VALUES (?, 'Provider')", p["provider_id"]); err != nil {
+			t.Fatal(err)
+		}
+		var columns []string
+		var args []interface{}
+		for col, v := range p {
+			columns = append(columns, col)
+			args = append(args, v)
+		}
+		stmt := "INSERT INTO product (" + strings.Join(columns, ", ") + ") VALUES (?" + strings.Repeat(", ?", len(args)-1) + ")"
+		if _, err := db.Exec(stmt, args...); err != nil {
+			t.Fatalf("product %v: %v", p["insurance_id"], err)
+		}
+	}
+	for _, stmt := range []string{
+		"INSERT INTO coverage_list (coverage_id, coverage_type) VALUES (1, 'Medical Expenses')",
+		"INSERT INTO coverage_limit (coverage_id, product_id, coverage_limit) VALUES (1, 1, '30000')",
+		`INSERT INTO premium (product_id, pet_type, min_age_weeks, max_age_weeks, breed_class, payment_mode, premium_hkd) VALUES
+			(1, 'dog', 13, 155, 'any', 'monthly', 180),
+			(1, 'dog', 13, 155, 'any', 'annual', 2000),
+			(1, 'dog', 13, 155, 'large', 'monthly', 240),
+			(1, 'dog', 156, NULL, 'any', 'monthly', 300)`,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+
+	repo, err := models.OpenInsuranceRepository(&config.Config{InsuranceDBPath: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { repo.Close() })
+	return repo
+}
+
+func TestQuoteProducts(t *testing.T) {
+	repo := openQuoteTestRepo(t)
+	products, err := repo.Products()
+	if err != nil {
+		t.Fatal(err)
+	}
+	scenarios := []models.Scenario{{ID: "case_001", Title: "Vet visit", CostItems: []models.CostItem{{ItemName: "consultation", AmountHKD: 1000}}}}
+
+	tests := []struct {
+		name       string
+		profile    Profile
+		monthly    float64
+		annual     float64 // 0 when there is no annual rate
+		annualCost float64
+		class      string
+		payout     int
+	}{
+		{"adult dog", Profile{PetType: eligibility.PetDog, AgeWeeks: 2 * eligibility.WeeksPerYear, Breed: "mongrel"}, 180, 2000, 2000, "any", 900},
+		{"large breed", Profile{PetType: eligibility.PetDog, AgeWeeks: 2 * eligibility.WeeksPerYear, BreedClass: eligibility.BreedLarge}, 240, 2000, 2000, "large", 900},
+		{"puppy pays half", Profile{PetType: eligibility.PetDog, AgeWeeks: 20}, 180, 2000, 2000, "any", 500},
+		{"older dog, monthly only", Profile{PetType: eligibility.PetDog, AgeWeeks: 5 * eligibility.WeeksPerYear}, 300, 0, 3600, "any", 900},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			quotes, unpriced, err := QuoteProducts(repo, tt.profile, scenarios)
+			if err != nil {
+				t.Fatalf("QuoteProducts: %v", err)
+			}
+			if len(quotes) != 1 {
+				t.Fatalf("got %d quotes, want 1: %+v", len(quotes), quotes)
+			}
+			q := quotes[0]
+			if q.ProductID != 1 || q.ProductName != "Essential Plan" {
+				t.Errorf("quoted product %d %q, want 1 Essential Plan", q.ProductID, q.ProductName)
+			}
+			if q.MonthlyPremiumHKD == nil || *q.MonthlyPremiumHKD != tt.monthly {
+				t.Errorf("monthly premium = %v, want %g", q.MonthlyPremiumHKD, tt.monthly)
+			}
+			if tt.annual == 0 && q.AnnualPremiumHKD != nil || tt.annual != 0 && (q.AnnualPremiumHKD == nil || *q.AnnualPremiumHKD != tt.annual) {
+				t.Errorf("annual premium = %v, want %g", q.AnnualPremiumHKD, tt.annual)
+			}
+			if q.AnnualCostHKD != tt.annualCost || q.BreedClass != tt.class {
+				t.Errorf("annual cost = %g (%s rows), want %g (%s)", q.AnnualCostHKD, q.BreedClass, tt.annualCost, tt.class)
+			}
+			b := q.Scenarios[0]
+			if b.EstimatedPayoutHKD != tt.payout || b.NetBenefitHKD != float64(tt.payout)-tt.annualCost {
+				t.Errorf("scenario pays %d with net benefit %g, want %d and %g", b.EstimatedPayoutHKD, b.NetBenefitHKD, tt.payout, float64(tt.payout)-tt.annualCost)
+			}
+			// Every other eligible product is listed as unpriced.
+			for _, id := range unpriced {
+				if id == 1 {
+					t.Errorf("product 1 is both quoted and unpriced")
+				}
+			}
+			if len(unpriced) == 0 || len(unpriced) >= len(products) {
+				t.Errorf("%d of %d products unpriced", len(unpriced), len(products))
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package payout
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/vf0429/Petwell_Backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// ErrScenarioNotFound is returned when the scenario to recompute doesn't exist.
+var ErrScenarioNotFound = errors.New("scenario not found")
+
+// ScenarioResult pairs a scenario payout with its recalculated estimate.
+type ScenarioResult struct {
+	InsurerID string `json:"insurer_id"`
+	Result
+}
+
+// RecomputeScenario recalculates the payouts of a scenario against each
+// insurer's product and, unless dryRun is set, stores the new figures.
+// products (insurer ID → product.insurance_id) overrides the products the
+// insurers are linked to. Payouts whose insurer has no product are left
+// untouched and reported in skipped.
+func RecomputeScenario(db *gorm.DB, repo *models.InsuranceRepository, scenarioID string, products map[string]int, dryRun bool) (results []ScenarioResult, skipped []string, err error) {
+	var s models.Scenario
+	res := db.Preload("CostItems").Preload("Payouts").Preload("Payouts.Insurer").Limit(1).Find(&s, "id = ?", scenarioID)
+	if res.Error != nil {
+		return nil, nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, nil, ErrScenarioNotFound
+	}
+
+	plans := make(map[int]*Plan)
+	err = db.Transaction(func(tx *gorm.DB) error {
+		for _, p := range s.Payouts {
+			productID, ok := products[p.InsurerID]
+			if !ok && p.Insurer.ProductID != nil {
+				productID, ok = *p.Insurer.ProductID, true
+			}
+			if !ok {
+				skipped = append(skipped, p.InsurerID)
+				continue
+			}
+
+			plan, ok := plans[productID]
+			if !ok {
+				loaded, err := LoadPlan(repo, productID)
+				if err != nil {
+					return fmt.Errorf("insurer %s: %w", p.InsurerID, err)
+				}
+				plan = loaded
+				plans[productID] = plan
+			}
+
+			result := Calculate(plan, s.CostItems)
+			results = append(results, ScenarioResult{InsurerID: p.InsurerID, Result: result})
+			if dryRun {
+				continue
+			}
+
+			err := tx.Model(&models.Payout{}).Where("id = ?", p.ID).Updates(map[string]interface{}{
+				"estimated_payout_hkd": result.EstimatedPayoutHKD,
+				"coverage_percentage":  result.CoveragePercentage,
+				"analysis":             result.Analysis,
+			}).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return results, skipped, nil
+}
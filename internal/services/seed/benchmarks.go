@@ -0,0 +1,111 @@
+package seed
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/vf0429/Petwell_Backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// DefaultBenchmarksPath is where the cost benchmarks live relative to the repo root.
+const DefaultBenchmarksPath = "assets/cost_benchmarks.csv"
+
+var benchmarkColumns = []string{"item_name", "district", "clinic_id", "amount_hkd"}
+
+// Benchmarks replaces the cost benchmarks with the rows of the CSV at path
+// and returns how many were loaded. The file is the source of truth, so
+// rows missing from it are removed.
+func Benchmarks(db *gorm.DB, path string) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read benchmarks file: %w", err)
+	}
+	defer f.Close()
+
+	rows, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse benchmarks file: %w", err)
+	}
+	if len(rows) == 0 || strings.Join(rows[0], ",") != strings.Join(benchmarkColumns, ",") {
+		return 0, fmt.Errorf("%s: header must be %s", path, strings.Join(benchmarkColumns, ","))
+	}
+
+	seen := make(map[string]int)
+	clinicLines := make(map[string]int)
+	benchmarks := make([]models.CostBenchmark, 0, len(rows)-1)
+	for i, row := range rows[1:] {
+		line := i + 2
+		b := models.CostBenchmark{
+			ItemName: strings.TrimSpace(row[0]),
+			District: strings.TrimSpace(row[1]),
+			ClinicID: strings.TrimSpace(row[2]),
+		}
+		if b.ItemName == "" {
+			return 0, fmt.Errorf("%s:%d: item_name is required", path, line)
+		}
+		amount, err := strconv.Atoi(strings.TrimSpace(row[3]))
+		if err != nil || amount <= 0 {
+			return 0, fmt.Errorf("%s:%d: amount_hkd must be a positive whole number", path, line)
+		}
+		b.AmountHKD = amount
+		key := strings.ToLower(b.ItemName + "|" + b.District + "|" + b.ClinicID)
+		if prev, ok := seen[key]; ok {
+			return 0, fmt.Errorf("%s:%d: duplicates line %d", path, line, prev)
+		}
+		seen[key] = line
+		if _, ok := clinicLines[b.ClinicID]; b.ClinicID != "" && !ok {
+			clinicLines[b.ClinicID] = line
+		}
+		benchmarks = append(benchmarks, b)
+	}
+	if err := checkBenchmarkClinics(db, path, clinicLines); err != nil {
+		return 0, err
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("1 = 1").Delete(&models.CostBenchmark{}).Error; err != nil {
+			return err
+		}
+		if len(benchmarks) == 0 {
+			return nil
+		}
+		return tx.Create(&benchmarks).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(benchmarks), nil
+}
+
+// checkBenchmarkClinics reports the first clinic_id, by line, that isn't in
+// the clinics table; lines maps each clinic_id to where it first appears.
+func checkBenchmarkClinics(db *gorm.DB, path string, lines map[string]int) error {
+	if len(lines) == 0 {
+		return nil
+	}
+	ids := make([]string, 0, len(lines))
+	for id := range lines {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return lines[ids[i]] < lines[ids[j]] })
+
+	var known []string
+	if err := db.Model(&models.Clinic{}).Where("clinic_id IN ?", ids).Pluck("clinic_id", &known).Error; err != nil {
+		return err
+	}
+	found := make(map[string]bool, len(known))
+	for _, id := range known {
+		found[id] = true
+	}
+	for _, id := range ids {
+		if !found[id] {
+			return fmt.Errorf("%s:%d: clinic_id %s is not in the clinics table; import the clinics first", path, lines[id], id)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,189 @@
+package seed
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/vf0429/Petwell_Backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// DefaultClinicsPath is the clinic directory CSV relative to the repo root.
+const DefaultClinicsPath = "assets/clinics.csv"
+
+// clinicColumns is the header of clinics.csv.
+var clinicColumns = []string{
+	"clinic_id", "name", "address", "phone_regular", "phone_emergency", "whatsapp",
+	"opening_hours", "emergency_24h", "website_url", "applemap_url", "latitude", "longitude",
+	"rating", "google_place_id", "photo_reference",
+}
+
+func clinicRecord(c models.Clinic) []string {
+	return []string{
+		c.ClinicID, c.Name, c.Address, c.PhoneRegular, c.PhoneEmergency, c.Whatsapp,
+		c.OpeningHours, c.Emergency24h, c.WebsiteURL, c.ApplemapURL, c.Latitude, c.Longitude,
+		c.Rating, c.GooglePlaceID, c.PhotoReference,
+	}
+}
+
+// parseClinic reads a clinics.csv row. Older files stop before
+// google_place_id and photo_reference.
+func parseClinic(r []string) models.Clinic {
+	field := func(i int) string {
+		if i < len(r) {
+			return strings.TrimSpace(r[i])
+		}
+		return ""
+	}
+	return models.Clinic{
+		ClinicID: field(0), Name: field(1), Address: field(2), PhoneRegular: field(3), PhoneEmergency: field(4),
+		Whatsapp: field(5), OpeningHours: field(6), Emergency24h: field(7), WebsiteURL: field(8),
+		ApplemapURL: field(9), Latitude: field(10), Longitude: field(11), Rating: field(12),
+		GooglePlaceID: field(13), PhotoReference: field(14),
+	}
+}
+
+// ClinicSummary reports what a clinics.csv import changed.
+type ClinicSummary struct {
+	Created  int
+	Updated  int
+	Skipped  int
+	Warnings []string
+}
+
+func (s *ClinicSummary) String() string {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "Clinics: %d created, %d updated, %d skipped", s.Created, s.Updated, s.Skipped)
+	for _, w := range s.Warnings {
+		fmt.Fprintf(&buf, "\n  warning: %s", w)
+	}
+	return buf.String()
+}
+
+// ImportClinics upserts the clinics of the CSV at path into db by
+// clinic_id, in one transaction. Rows without an id are matched by
+// google_place_id, then by name and address, and get a UUID when they are
+// new; clinics missing from the file are left alone. emergency_24h is
+// derived from opening_hours wherever those can be read.
+func ImportClinics(db *gorm.DB, path string) (*ClinicSummary, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read clinics file: %w", err)
+	}
+	defer f.Close()
+
+	reader := csv.NewReader(f)
+	reader.FieldsPerRecord = -1
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse clinics file: %w", err)
+	}
+	if len(rows) > 0 {
+		rows = rows[1:] // header
+	}
+
+	summary := &ClinicSummary{}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		seen := make(map[string]int)
+		for i, row := range rows {
+			line := i + 2
+			want := parseClinic(row)
+			if want.Name == "" {
+				summary.Skipped++
+				summary.Warnings = append(summary.Warnings, fmt.Sprintf("line %d: skipped a clinic without a name", line))
+				continue
+			}
+			if err := want.DeriveEmergency24h(); err != nil {
+				summary.Warnings = append(summary.Warnings, fmt.Sprintf("line %d: opening_hours: %v; keeping emergency_24h %q", line, err, want.Emergency24h))
+			}
+			if want.ClinicID != "" {
+				if prev, ok := seen[want.ClinicID]; ok {
+					return fmt.Errorf("line %d: clinic_id %s duplicates line %d", line, want.ClinicID, prev)
+				}
+				seen[want.ClinicID] = line
+			}
+
+			var existing models.Clinic
+			var res *gorm.DB
+			switch {
+			case want.ClinicID != "":
+				res = tx.Limit(1).Find(&existing, "clinic_id = ?", want.ClinicID)
+			case want.GooglePlaceID != "":
+				res = tx.Limit(1).Find(&existing, "google_place_id = ?", want.GooglePlaceID)
+			default:
+				res = tx.Limit(1).Find(&existing, "name = ? AND address = ?", want.Name, want.Address)
+			}
+			if res.RowsAffected > 0 {
+				want.ClinicID = existing.ClinicID
+			}
+			switch {
+			case res.Error != nil:
+				return res.Error
+			case res.RowsAffected == 0:
+				if err := tx.Create(&want).Error; err != nil {
+					return fmt.Errorf("line %d: %w", line, err)
+				}
+				summary.Created++
+			case sameClinic(existing, want):
+				summary.Skipped++
+			default:
+				want.CreatedAt = existing.CreatedAt
+				if err := tx.Save(&want).Error; err != nil {
+					return fmt.Errorf("line %d: %w", line, err)
+				}
+				summary.Updated++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return summary, nil
+}
+
+func sameClinic(a, b models.Clinic) bool {
+	return strings.Join(clinicRecord(a), "\x00") == strings.Join(clinicRecord(b), "\x00")
+}
+
+// ExportClinics writes every clinic to the CSV at path. The file is written
+// next to path and renamed over it, so a failed export leaves the old file
+// intact.
+func ExportClinics(db *gorm.DB, path string) (int, error) {
+	var clinics []models.Clinic
+	if err := db.Order(models.ClinicOrder).Find(&clinics).Error; err != nil {
+		return 0, err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".clinics-*.csv")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmp.Name())
+
+	w := csv.NewWriter(tmp)
+	w.Write(clinicColumns)
+	for _, c := range clinics {
+		w.Write(clinicRecord(c))
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		tmp.Close()
+		return 0, err
+	}
+	if err := tmp.Chmod(0o644); err != nil {
+		tmp.Close()
+		return 0, err
+	}
+	if err := tmp.Close(); err != nil {
+		return 0, err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return 0, err
+	}
+	return len(clinics), nil
+}
@@ -0,0 +1,615 @@
+package seed
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/vf0429/Petwell_Backend/internal/migrations"
+	"github.com/vf0429/Petwell_Backend/internal/models"
+)
+
+// DefaultInsuranceSourceDir holds the versioned source files the insurance
+// DB is built from, relative to the repo root.
+const DefaultInsuranceSourceDir = "assets/insurance"
+
+// insuranceTable describes one table and the source file it's loaded from.
+type insuranceTable struct {
+	name     string
+	file     string
+	key      []string
+	columns  []string // key columns first
+	required []string // must not be empty in the source
+	ints     []string // written as numbers in JSON sources
+	nonEmpty bool     // the source must have data rows
+}
+
+// insuranceTables are listed parents first; deletes run in reverse.
+var insuranceTables = []insuranceTable{
+	{
+		name: "insurance_provider", file: "providers.csv",
+		key:      []string{"company_id"},
+		columns:  []string{"company_id", "company_name", "company_name_zh", "company_logo"},
+		required: []string{"company_name"},
+		nonEmpty: true,
+	},
+	{
+		name: "coverage_list", file: "coverage_list.csv",
+		key:      []string{"coverage_id"},
+		columns:  []string{"coverage_id", "coverage_type", "coverage_type_zh"},
+		required: []string{"coverage_type"},
+		nonEmpty: true,
+	},
+	{
+		name: "tag", file: "tags.csv",
+		key:      []string{"tag_id"},
+		columns:  []string{"tag_id", "slug", "label", "label_zh", "display_order"},
+		required: []string{"slug", "label", "display_order"},
+	},
+	{
+		name: "product", file: "products.json",
+		key: []string{"insurance_id"},
+		columns: []string{"insurance_id", "provider_id", "insurance_name", "insurance_name_zh", "remark", "remark_zh",
+			"min_age", "min_age_zh", "max_age", "max_age_zh", "coinsurance", "coinsurance_zh", "suitable_pet_type", "suitable_pet_type_zh",
+			"cat_breed_type", "cat_breed_type_zh", "dog_breed_type", "dog_breed_type_zh", "breed_type_remark", "breed_type_remark_zh",
+			"payment_mode", "payment_mode_zh", "waiting_period", "waiting_period_zh", "information_link", "information_link_zh", "update_time"},
+		required: []string{"provider_id", "insurance_name"},
+		ints:     []string{"insurance_id", "provider_id"},
+		nonEmpty: true,
+	},
+	{
+		name: "coverage_limit", file: "coverage_limits.csv",
+		key:      []string{"coverage_id", "product_id"},
+		columns:  []string{"coverage_id", "product_id", "coverage_limit", "remark", "remark_zh"},
+		nonEmpty: true,
+	},
+	{
+		name: "sub_coverage_limit", file: "sub_coverage_limits.csv",
+		key: []string{"sub_coverage_id"},
+		columns: []string{"sub_coverage_id", "parent_coverage_id", "product_id", "sub_coverage_name", "sub_coverage_name_zh",
+			"sub_limit", "sub_coverage_remark", "sub_coverage_remark_zh"},
+		required: []string{"parent_coverage_id", "product_id"},
+	},
+	{
+		name: "premium", file: "premiums.csv",
+		key: []string{"premium_id"},
+		columns: []string{"premium_id", "product_id", "pet_type", "min_age_weeks", "max_age_weeks", "breed_class",
+			"payment_mode", "premium_hkd"},
+		required: []string{"product_id", "pet_type", "min_age_weeks", "breed_class", "payment_mode", "premium_hkd"},
+	},
+	{
+		name: "product_tag", file: "product_tags.csv",
+		key:     []string{"product_id", "tag_id"},
+		columns: []string{"product_id", "tag_id"},
+	},
+}
+
+// foreignKeys are checked against the source files before anything is
+// written, so a bad reference is reported by file and row rather than as
+// a bare constraint failure.
+var foreignKeys = []struct{ table, column, parent string }{
+	{"product", "provider_id", "insurance_provider"},
+	{"coverage_limit", "coverage_id", "coverage_list"},
+	{"coverage_limit", "product_id", "product"},
+	{"sub_coverage_limit", "parent_coverage_id", "coverage_list"},
+	{"sub_coverage_limit", "product_id", "product"},
+	{"premium", "product_id", "product"},
+	{"product_tag", "product_id", "product"},
+	{"product_tag", "tag_id", "tag"},
+}
+
+// Manifest is the manifest.json of a source directory.
+type Manifest struct {
+	Version string `json:"version"`
+	Notes   string `json:"notes,omitempty"`
+}
+
+type record []sql.NullString
+
+// TableDiff counts how one table changed.
+type TableDiff struct {
+	Table     string
+	Added     int
+	Updated   int
+	Removed   int
+	Unchanged int
+	Changes   []string // "+ key", "~ key: columns", "- key"
+}
+
+// InsuranceSummary reports what an insurance import changed.
+type InsuranceSummary struct {
+	FromVersion string
+	ToVersion   string
+	DryRun      bool
+	Tables      []TableDiff
+	Warnings    []string
+}
+
+// maxListedChanges caps the per-table change lines in String.
+const maxListedChanges = 20
+
+func (s *InsuranceSummary) String() string {
+	var b strings.Builder
+	from := s.FromVersion
+	if from == "" {
+		from = "(none)"
+	}
+	fmt.Fprintf(&b, "Insurance data %s -> %s", from, s.ToVersion)
+	if s.DryRun {
+		b.WriteString(" (dry run, nothing written)")
+	}
+	b.WriteString("\n")
+	for _, w := range s.Warnings {
+		fmt.Fprintf(&b, "  warning: %s\n", w)
+	}
+	for _, t := range s.Tables {
+		fmt.Fprintf(&b, "  %-20s %d added, %d updated, %d removed, %d unchanged\n", t.Table, t.Added, t.Updated, t.Removed, t.Unchanged)
+		for i, c := range t.Changes {
+			if i == maxListedChanges {
+				fmt.Fprintf(&b, "      ... %d more\n", len(t.Changes)-maxListedChanges)
+				break
+			}
+			fmt.Fprintf(&b, "      %s\n", c)
+		}
+	}
+	return b.String()
+}
+
+// Insurance rebuilds the insurance tables from the source files in dir in
+// a single transaction: rows missing from the sources are deleted, the rest
+// are inserted or updated. Nothing is written when dryRun is set or when
+// any check fails. db must have foreign keys enabled and be migrated to
+// the latest insurance schema.
+func Insurance(db *sql.DB, dir string, dryRun bool) (*InsuranceSummary, error) {
+	if err := migrations.Insurance.Check(db); err != nil {
+		return nil, err
+	}
+	manifest, sources, err := loadInsuranceSources(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	summary := &InsuranceSummary{ToVersion: manifest.Version, DryRun: dryRun}
+	if len(sources["premium"]) == 0 && len(sources["product"]) > 0 {
+		summary.Warnings = append(summary.Warnings, "premiums.csv is empty: every product is listed as unpriced by /insurance-quotes until the premiums are exported from the production DB")
+	}
+	if err := tx.QueryRow("SELECT value FROM import_meta WHERE key = 'source_version'").Scan(&summary.FromVersion); err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	// Product and limit changes go into change_history like admin edits,
+	// once per row however many of its files changed.
+	changes := models.NewChangeLog(tx, "import_insurance "+manifest.Version)
+
+	existing := make(map[string]map[string]record)
+	for _, t := range insuranceTables {
+		if existing[t.name], err = readTable(tx, t); err != nil {
+			return nil, err
+		}
+	}
+
+	// An empty source next to a populated table is almost always a file
+	// that hasn't been exported yet rather than an intent to wipe it.
+	for _, t := range insuranceTables {
+		if len(sources[t.name]) == 0 && len(existing[t.name]) > 0 {
+			return nil, fmt.Errorf("%s is empty but %s has %d rows; run with -export first or delete them explicitly", t.file, t.name, len(existing[t.name]))
+		}
+	}
+
+	// Children go first when deleting and last when inserting, so the
+	// foreign keys hold after every statement.
+	diffs := make(map[string]*TableDiff)
+	for i := len(insuranceTables) - 1; i >= 0; i-- {
+		t := insuranceTables[i]
+		diff := &TableDiff{Table: t.name}
+		diffs[t.name] = diff
+		for _, key := range sortedKeys(existing[t.name]) {
+			if _, ok := sources[t.name][key]; ok {
+				continue
+			}
+			if err := trackRow(changes, t, existing[t.name][key], func() error { return deleteRow(tx, t, existing[t.name][key]) }); err != nil {
+				return nil, fmt.Errorf("delete %s %s: %w", t.name, key, err)
+			}
+			diff.Removed++
+			diff.Changes = append(diff.Changes, "- "+key)
+		}
+	}
+	for _, t := range insuranceTables {
+		diff := diffs[t.name]
+		for _, key := range sortedKeys(sources[t.name]) {
+			rec := sources[t.name][key]
+			old, ok := existing[t.name][key]
+			if !ok {
+				if err := trackRow(changes, t, rec, func() error { return insertRow(tx, t, rec) }); err != nil {
+					return nil, fmt.Errorf("insert %s %s: %w", t.name, key, err)
+				}
+				diff.Added++
+				diff.Changes = append(diff.Changes, "+ "+key)
+				continue
+			}
+			changed := changedColumns(t, old, rec)
+			if len(changed) == 0 {
+				diff.Unchanged++
+				continue
+			}
+			if err := trackRow(changes, t, rec, func() error { return updateRow(tx, t, rec) }); err != nil {
+				return nil, fmt.Errorf("update %s %s: %w", t.name, key, err)
+			}
+			diff.Updated++
+			diff.Changes = append(diff.Changes, fmt.Sprintf("~ %s: %s", key, strings.Join(changed, ", ")))
+		}
+		summary.Tables = append(summary.Tables, *diff)
+	}
+
+	if err := checkForeignKeys(tx); err != nil {
+		return nil, err
+	}
+	if err := changes.Flush(); err != nil {
+		return nil, err
+	}
+	if err := models.RebuildSearchIndex(tx); err != nil {
+		return nil, err
+	}
+	for k, v := range map[string]string{"source_version": manifest.Version, "imported_at": time.Now().UTC().Format(time.RFC3339)} {
+		if _, err := tx.Exec("INSERT INTO import_meta (key, value) VALUES (?, ?) ON CONFLICT(key) DO UPDATE SET value = excluded.value", k, v); err != nil {
+			return nil, err
+		}
+	}
+
+	if dryRun {
+		return summary, nil
+	}
+	return summary, tx.Commit()
+}
+
+// loadInsuranceSources reads and cross-checks every source file in dir.
+// The result maps table name to rows by key.
+func loadInsuranceSources(dir string) (*Manifest, map[string]map[string]record, error) {
+	var manifest Manifest
+	data, err := os.ReadFile(filepath.Join(dir, "manifest.json"))
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, nil, fmt.Errorf("manifest.json: %w", err)
+	}
+	if manifest.Version == "" {
+		return nil, nil, fmt.Errorf("manifest.json: version is required")
+	}
+
+	sources := make(map[string]map[string]record)
+	for _, t := range insuranceTables {
+		path := filepath.Join(dir, t.file)
+		var rows []record
+		if strings.HasSuffix(t.file, ".json") {
+			rows, err = readJSONRows(path, t.columns)
+		} else {
+			rows, err = readCSVRows(path, t.columns)
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if t.nonEmpty && len(rows) == 0 {
+			return nil, nil, fmt.Errorf("%s has no data rows; export it from a complete DB with -export", t.file)
+		}
+
+		byKey := make(map[string]record, len(rows))
+		for i, rec := range rows {
+			for _, col := range append(append([]string{}, t.key...), t.required...) {
+				if !rec[indexOf(t.columns, col)].Valid {
+					return nil, nil, fmt.Errorf("%s row %d: %s is required", t.file, i+1, col)
+				}
+			}
+			key := rowKey(t, rec)
+			if _, dup := byKey[key]; dup {
+				return nil, nil, fmt.Errorf("%s row %d: duplicate key %s", t.file, i+1, key)
+			}
+			byKey[key] = rec
+		}
+		sources[t.name] = byKey
+	}
+
+	if err := checkCoverage(sources); err != nil {
+		return nil, nil, err
+	}
+	for _, fk := range foreignKeys {
+		t := tableByName(fk.table)
+		col := indexOf(t.columns, fk.column)
+		for _, key := range sortedKeys(sources[fk.table]) {
+			ref := sources[fk.table][key][col]
+			if !ref.Valid {
+				continue
+			}
+			if _, ok := sources[fk.parent][ref.String]; !ok {
+				return nil, nil, fmt.Errorf("%s %s: %s %s not found in %s", t.file, key, fk.column, ref.String, tableByName(fk.parent).file)
+			}
+		}
+	}
+	return &manifest, sources, nil
+}
+
+// checkCoverage requires every product to have coverage limits: a product
+// left out of coverage_limits.csv would otherwise be estimated to pay
+// nothing.
+func checkCoverage(sources map[string]map[string]record) error {
+	limits := sources["coverage_limit"]
+	t := tableByName("coverage_limit")
+	col := indexOf(t.columns, "product_id")
+	covered := make(map[string]bool)
+	for _, rec := range limits {
+		covered[rec[col].String] = true
+	}
+	for _, key := range sortedKeys(sources["product"]) {
+		if !covered[key] {
+			return fmt.Errorf("products.json %s: product has no rows in coverage_limits.csv", key)
+		}
+	}
+	return nil
+}
+
+// readCSVRows reads a CSV file whose header names a subset of columns.
+// Empty cells are NULL.
+func readCSVRows(path string, columns []string) ([]record, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	header, err := r.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+	index := make([]int, len(header))
+	for i, name := range header {
+		name = strings.TrimPrefix(strings.TrimSpace(name), "\ufeff")
+		if index[i] = indexOf(columns, name); index[i] < 0 {
+			return nil, fmt.Errorf("%s: unknown column %q", filepath.Base(path), name)
+		}
+	}
+
+	var rows []record
+	for {
+		fields, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+		}
+		rec := make(record, len(columns))
+		for i, v := range fields {
+			if v != "" {
+				rec[index[i]] = sql.NullString{String: v, Valid: true}
+			}
+		}
+		rows = append(rows, rec)
+	}
+	return rows, nil
+}
+
+// readJSONRows reads a JSON array of objects keyed by column name.
+func readJSONRows(path string, columns []string) ([]record, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var objects []map[string]interface{}
+	if err := dec.Decode(&objects); err != nil {
+		return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+
+	rows := make([]record, len(objects))
+	for i, obj := range objects {
+		rec := make(record, len(columns))
+		for name, v := range obj {
+			col := indexOf(columns, name)
+			if col < 0 {
+				return nil, fmt.Errorf("%s row %d: unknown field %q", filepath.Base(path), i+1, name)
+			}
+			switch v := v.(type) {
+			case nil:
+			case string:
+				// Unlike CSV, JSON can tell "" from null, so both are kept.
+				rec[col] = sql.NullString{String: v, Valid: true}
+			case json.Number:
+				rec[col] = sql.NullString{String: v.String(), Valid: true}
+			default:
+				return nil, fmt.Errorf("%s row %d: %s must be a string or number", filepath.Base(path), i+1, name)
+			}
+		}
+		rows[i] = rec
+	}
+	return rows, nil
+}
+
+func readTable(tx *sql.Tx, t insuranceTable) (map[string]record, error) {
+	rows, err := tx.Query(fmt.Sprintf("SELECT %s FROM %s", strings.Join(t.columns, ", "), t.name))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make(map[string]record)
+	for rows.Next() {
+		rec := make(record, len(t.columns))
+		dest := make([]interface{}, len(rec))
+		for i := range rec {
+			dest[i] = &rec[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, fmt.Errorf("scan %s: %w", t.name, err)
+		}
+		out[rowKey(t, rec)] = rec
+	}
+	return out, rows.Err()
+}
+
+func insertRow(tx *sql.Tx, t insuranceTable, rec record) error {
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(t.columns)), ", ")
+	_, err := tx.Exec(fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", t.name, strings.Join(t.columns, ", "), placeholders), values(rec)...)
+	return err
+}
+
+func updateRow(tx *sql.Tx, t insuranceTable, rec record) error {
+	var sets []string
+	var args []interface{}
+	for i, col := range t.columns[len(t.key):] {
+		sets = append(sets, col+" = ?")
+		args = append(args, value(rec[len(t.key)+i]))
+	}
+	where, keyArgs := keyClause(t, rec)
+	_, err := tx.Exec(fmt.Sprintf("UPDATE %s SET %s WHERE %s", t.name, strings.Join(sets, ", "), where), append(args, keyArgs...)...)
+	return err
+}
+
+func deleteRow(tx *sql.Tx, t insuranceTable, rec record) error {
+	where, args := keyClause(t, rec)
+	_, err := tx.Exec(fmt.Sprintf("DELETE FROM %s WHERE %s", t.name, where), args...)
+	return err
+}
+
+// trackRow runs write, a change to the row of rec, through changes.
+func trackRow(changes *models.ChangeLog, t insuranceTable, rec record, write func() error) error {
+	_, key := keyClause(t, rec)
+	return changes.Track(t.name, key, write)
+}
+
+func keyClause(t insuranceTable, rec record) (string, []interface{}) {
+	var conds []string
+	var args []interface{}
+	for i, col := range t.key {
+		conds = append(conds, col+" = ?")
+		args = append(args, value(rec[i]))
+	}
+	return strings.Join(conds, " AND "), args
+}
+
+// checkForeignKeys catches references the in-memory checks can't see, such
+// as rows of tables created before their constraints existed.
+func checkForeignKeys(tx *sql.Tx) error {
+	rows, err := tx.Query("PRAGMA foreign_key_check")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var problems []string
+	for rows.Next() {
+		var table, parent string
+		var rowid sql.NullInt64
+		var fkid int
+		if err := rows.Scan(&table, &rowid, &parent, &fkid); err != nil {
+			return err
+		}
+		problems = append(problems, fmt.Sprintf("%s row %d references a missing %s", table, rowid.Int64, parent))
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("foreign key check failed:\n  %s", strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+// changedColumns lists the columns whose values differ.
+func changedColumns(t insuranceTable, old, rec record) []string {
+	var changed []string
+	for i, col := range t.columns {
+		if old[i] != rec[i] {
+			changed = append(changed, col)
+		}
+	}
+	return changed
+}
+
+func rowKey(t insuranceTable, rec record) string {
+	parts := make([]string, len(t.key))
+	for i := range t.key {
+		parts[i] = rec[i].String
+	}
+	return strings.Join(parts, "/")
+}
+
+func values(rec record) []interface{} {
+	args := make([]interface{}, len(rec))
+	for i, v := range rec {
+		args[i] = value(v)
+	}
+	return args
+}
+
+func value(v sql.NullString) interface{} {
+	if !v.Valid {
+		return nil
+	}
+	return v.String
+}
+
+func tableByName(name string) insuranceTable {
+	for _, t := range insuranceTables {
+		if t.name == name {
+			return t
+		}
+	}
+	panic("unknown insurance table " + name)
+}
+
+func indexOf(list []string, s string) int {
+	for i, v := range list {
+		if v == s {
+			return i
+		}
+	}
+	return -1
+}
+
+// sortedKeys orders row keys numerically where possible, so summaries list
+// product 2 before product 10.
+func sortedKeys(m map[string]record) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := strings.Split(keys[i], "/"), strings.Split(keys[j], "/")
+		for n := 0; n < len(a) && n < len(b); n++ {
+			if a[n] == b[n] {
+				continue
+			}
+			if len(a[n]) != len(b[n]) && isDigits(a[n]) && isDigits(b[n]) {
+				return len(a[n]) < len(b[n])
+			}
+			return a[n] < b[n]
+		}
+		return len(a) < len(b)
+	})
+	return keys
+}
+
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
@@ -0,0 +1,122 @@
+package seed
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/vf0429/Petwell_Backend/internal/migrations"
+)
+
+// ExportInsurance writes the insurance tables of db to dir in the format
+// Insurance reads, so the sources of an existing database can be put
+// under version control.
+func ExportInsurance(db *sql.DB, dir, version string) error {
+	if err := migrations.Insurance.Check(db); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, t := range insuranceTables {
+		rows, err := readTable(tx, t)
+		if err != nil {
+			return err
+		}
+		ordered := make([]record, 0, len(rows))
+		for _, key := range sortedKeys(rows) {
+			ordered = append(ordered, rows[key])
+		}
+
+		if strings.HasSuffix(t.file, ".json") {
+			err = writeJSONRows(filepath.Join(dir, t.file), t, ordered)
+		} else {
+			err = writeCSVRows(filepath.Join(dir, t.file), t.columns, ordered)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	data, err := json.MarshalIndent(Manifest{Version: version}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, "manifest.json"), append(data, '\n'), 0o644)
+}
+
+func writeCSVRows(path string, columns []string, rows []record) error {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write(columns)
+	for _, rec := range rows {
+		fields := make([]string, len(rec))
+		for i, v := range rec {
+			fields[i] = v.String
+		}
+		w.Write(fields)
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), 0o644)
+}
+
+// writeJSONRows writes rows as objects with keys in column order.
+func writeJSONRows(path string, t insuranceTable, rows []record) error {
+	var buf bytes.Buffer
+	buf.WriteString("[")
+	for i, rec := range rows {
+		if i > 0 {
+			buf.WriteString(",")
+		}
+		buf.WriteString("{")
+		for j, col := range t.columns {
+			if j > 0 {
+				buf.WriteString(",")
+			}
+			buf.Write(jsonString(col))
+			buf.WriteString(":")
+			switch {
+			case !rec[j].Valid:
+				buf.WriteString("null")
+			case indexOf(t.ints, col) >= 0:
+				buf.WriteString(strings.TrimSpace(rec[j].String))
+			default:
+				buf.Write(jsonString(rec[j].String))
+			}
+		}
+		buf.WriteString("}")
+	}
+	buf.WriteString("]")
+
+	var out bytes.Buffer
+	if err := json.Indent(&out, buf.Bytes(), "", "  "); err != nil {
+		return fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+	out.WriteString("\n")
+	return os.WriteFile(path, out.Bytes(), 0o644)
+}
+
+// jsonString quotes s without json.Marshal's HTML escaping, which would
+// turn every "&" in the information links into \u0026.
+func jsonString(s string) []byte {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	return bytes.TrimSuffix(b.Bytes(), []byte("\n"))
+}
@@ -0,0 +1,315 @@
+package seed
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/vf0429/Petwell_Backend/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DefaultScenariosPath is where the scenario fixtures live relative to the repo root.
+const DefaultScenariosPath = "assets/pet_insurance_scenarios.json"
+
+// scenarioFile mirrors the top-level structure of pet_insurance_scenarios.json.
+type scenarioFile struct {
+	Scenarios []scenarioRecord `json:"scenarios"`
+}
+
+type scenarioRecord struct {
+	ID            string         `json:"id"`
+	Title         string         `json:"title"`
+	Description   string         `json:"description"`
+	TotalCostHKD  int            `json:"total_cost_hkd"`
+	CostBreakdown costBreakdown  `json:"cost_breakdown"`
+	Payouts       []payoutRecord `json:"payouts"`
+}
+
+type payoutRecord struct {
+	InsurerID          string  `json:"insurer_id"`
+	InsurerName        string  `json:"insurer_name"`
+	PlanName           string  `json:"plan_name"`
+	CompanyID          *int    `json:"company_id"`
+	ProductID          *int    `json:"product_id"`
+	EstimatedPayoutHKD int     `json:"estimated_payout_hkd"`
+	CoveragePercentage float64 `json:"coverage_percentage"`
+	Analysis           string  `json:"analysis"`
+	IsRecommended      bool    `json:"is_recommended"`
+}
+
+// costBreakdown decodes the {"item": amount} object while keeping the
+// order the items were written in, which a plain map would lose.
+type costBreakdown []models.CostItem
+
+func (c *costBreakdown) UnmarshalJSON(data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '{' {
+		return fmt.Errorf("cost_breakdown must be an object")
+	}
+
+	var items []models.CostItem
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		name, _ := tok.(string)
+		var amount int
+		if err := dec.Decode(&amount); err != nil {
+			return fmt.Errorf("cost_breakdown.%s: %w", name, err)
+		}
+		items = append(items, models.CostItem{ItemName: name, AmountHKD: amount})
+	}
+	*c = items
+	return nil
+}
+
+// Summary reports what a seeding run changed.
+type Summary struct {
+	ScenariosCreated int
+	ScenariosUpdated int
+	ScenariosSkipped int
+	InsurersCreated  int
+	InsurersUpdated  int
+	InsurersSkipped  int
+	Warnings         []string
+}
+
+func (s *Summary) String() string {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "Scenarios: %d created, %d updated, %d skipped\n", s.ScenariosCreated, s.ScenariosUpdated, s.ScenariosSkipped)
+	fmt.Fprintf(&buf, "Insurers:  %d created, %d updated, %d skipped", s.InsurersCreated, s.InsurersUpdated, s.InsurersSkipped)
+	for _, w := range s.Warnings {
+		fmt.Fprintf(&buf, "\n  warning: %s", w)
+	}
+	return buf.String()
+}
+
+// ResolvePath finds the scenarios file either relative to the working
+// directory or next to the running executable.
+func ResolvePath(path string) string {
+	if _, err := os.Stat(path); err == nil {
+		return path
+	}
+	if ex, err := os.Executable(); err == nil {
+		candidate := filepath.Join(filepath.Dir(ex), path)
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate
+		}
+	}
+	return path
+}
+
+// Scenarios loads the scenario fixtures at path and upserts them into db.
+// Scenarios are matched by their ID (e.g. "case_001"); unchanged scenarios
+// are skipped, so running it repeatedly is safe. Everything happens in a
+// single transaction.
+func Scenarios(db *gorm.DB, path string) (*Summary, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read scenarios file: %w", err)
+	}
+
+	var file scenarioFile
+	if err := json.Unmarshal(raw, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse scenarios file: %w", err)
+	}
+
+	summary := &Summary{}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := upsertInsurers(tx, file.Scenarios, summary); err != nil {
+			return err
+		}
+		for _, rec := range file.Scenarios {
+			if err := upsertScenario(tx, rec, summary); err != nil {
+				return fmt.Errorf("scenario %s: %w", rec.ID, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return summary, nil
+}
+
+func upsertInsurers(tx *gorm.DB, records []scenarioRecord, summary *Summary) error {
+	seen := make(map[string]bool)
+	for _, rec := range records {
+		for _, p := range rec.Payouts {
+			if p.InsurerID == "" || seen[p.InsurerID] {
+				continue
+			}
+			seen[p.InsurerID] = true
+
+			want := models.Insurer{ID: p.InsurerID, Name: p.InsurerName, PlanName: p.PlanName, CompanyID: p.CompanyID, ProductID: p.ProductID}
+			var existing models.Insurer
+			res := tx.Limit(1).Find(&existing, "id = ?", want.ID)
+			switch {
+			case res.Error != nil:
+				return res.Error
+			case res.RowsAffected == 0:
+				if err := tx.Create(&want).Error; err != nil {
+					return fmt.Errorf("failed to create insurer %s: %w", want.ID, err)
+				}
+				summary.InsurersCreated++
+			case sameInsurer(existing, want):
+				summary.InsurersSkipped++
+			default:
+				if err := tx.Save(&want).Error; err != nil {
+					return fmt.Errorf("failed to update insurer %s: %w", want.ID, err)
+				}
+				summary.InsurersUpdated++
+			}
+		}
+	}
+	return nil
+}
+
+func upsertScenario(tx *gorm.DB, rec scenarioRecord, summary *Summary) error {
+	if rec.ID == "" {
+		summary.ScenariosSkipped++
+		summary.Warnings = append(summary.Warnings, fmt.Sprintf("skipped scenario %q without an id", rec.Title))
+		return nil
+	}
+
+	want := buildScenario(rec)
+	sum := 0
+	for _, item := range want.CostItems {
+		sum += item.AmountHKD
+	}
+	if sum != want.TotalCostHKD {
+		summary.Warnings = append(summary.Warnings,
+			fmt.Sprintf("%s: total_cost_hkd %d does not match cost_breakdown sum %d", rec.ID, want.TotalCostHKD, sum))
+	}
+
+	var existing models.Scenario
+	res := tx.Preload("CostItems").Preload("Payouts").Limit(1).Find(&existing, "id = ?", rec.ID)
+	created := false
+	switch {
+	case res.Error != nil:
+		return res.Error
+	case res.RowsAffected == 0:
+		created = true
+	case sameScenario(existing, want):
+		summary.ScenariosSkipped++
+		return nil
+	}
+
+	if err := tx.Omit(clause.Associations).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"title", "description", "total_cost_hkd", "updated_at"}),
+	}).Create(&want).Error; err != nil {
+		return err
+	}
+
+	// Children are replaced wholesale; their IDs are derived from the
+	// scenario ID so the rows stay stable between runs.
+	if err := tx.Where("scenario_id = ?", want.ID).Delete(&models.CostItem{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("scenario_id = ?", want.ID).Delete(&models.Payout{}).Error; err != nil {
+		return err
+	}
+	if len(want.CostItems) > 0 {
+		if err := tx.Create(&want.CostItems).Error; err != nil {
+			return err
+		}
+	}
+	if len(want.Payouts) > 0 {
+		if err := tx.Omit("Insurer").Create(&want.Payouts).Error; err != nil {
+			return err
+		}
+	}
+
+	if created {
+		summary.ScenariosCreated++
+	} else {
+		summary.ScenariosUpdated++
+	}
+	return nil
+}
+
+func buildScenario(rec scenarioRecord) models.Scenario {
+	s := models.Scenario{
+		ID:           rec.ID,
+		Title:        rec.Title,
+		Description:  rec.Description,
+		TotalCostHKD: rec.TotalCostHKD,
+	}
+	for _, item := range rec.CostBreakdown {
+		item.ID = fmt.Sprintf("%s:%s", rec.ID, item.ItemName)
+		item.ScenarioID = rec.ID
+		s.CostItems = append(s.CostItems, item)
+	}
+	for _, p := range rec.Payouts {
+		s.Payouts = append(s.Payouts, models.Payout{
+			ID:                 fmt.Sprintf("%s:%s", rec.ID, p.InsurerID),
+			ScenarioID:         rec.ID,
+			InsurerID:          p.InsurerID,
+			EstimatedPayoutHKD: p.EstimatedPayoutHKD,
+			CoveragePercentage: p.CoveragePercentage,
+			Analysis:           p.Analysis,
+			IsRecommended:      p.IsRecommended,
+		})
+	}
+	return s
+}
+
+func sameInsurer(a, b models.Insurer) bool {
+	return a.ID == b.ID && a.Name == b.Name && a.PlanName == b.PlanName &&
+		equalID(a.CompanyID, b.CompanyID) && equalID(a.ProductID, b.ProductID)
+}
+
+func equalID(a, b *int) bool {
+	return a == nil && b == nil || a != nil && b != nil && *a == *b
+}
+
+// sameScenario compares the seeded content of two scenarios, ignoring
+// timestamps and the order child rows come back from the database in.
+func sameScenario(a, b models.Scenario) bool {
+	if a.Title != b.Title || a.Description != b.Description || a.TotalCostHKD != b.TotalCostHKD {
+		return false
+	}
+	return equalKeys(costItemKeys(a.CostItems), costItemKeys(b.CostItems)) &&
+		equalKeys(payoutKeys(a.Payouts), payoutKeys(b.Payouts))
+}
+
+func costItemKeys(items []models.CostItem) []string {
+	keys := make([]string, len(items))
+	for i, item := range items {
+		keys[i] = fmt.Sprintf("%s|%s|%d", item.ID, item.ItemName, item.AmountHKD)
+	}
+	return keys
+}
+
+func payoutKeys(payouts []models.Payout) []string {
+	keys := make([]string, len(payouts))
+	for i, p := range payouts {
+		keys[i] = fmt.Sprintf("%s|%s|%d|%g|%s|%t", p.ID, p.InsurerID, p.EstimatedPayoutHKD, p.CoveragePercentage, p.Analysis, p.IsRecommended)
+	}
+	return keys
+}
+
+func equalKeys(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	sort.Strings(a)
+	sort.Strings(b)
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}